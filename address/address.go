@@ -0,0 +1,316 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/script"
+)
+
+var (
+	// ErrInvalidFormat is returned when an address is neither valid
+	// Base58Check nor valid bech32.
+	ErrInvalidFormat = errors.New("invalid address format")
+
+	// ErrInvalidChecksum is returned when the checksum of an address does
+	// not match its contents.
+	ErrInvalidChecksum = errors.New("invalid address checksum")
+
+	// ErrUnknownNetwork is returned when the version byte or human
+	// readable part of an address doesn't belong to a known network.
+	ErrUnknownNetwork = errors.New("unknown network")
+
+	// ErrInvalidWitnessProgram is returned when a segwit address holds a
+	// witness version or program that isn't allowed by BIP173 and BIP350.
+	ErrInvalidWitnessProgram = errors.New("invalid witness program")
+
+	// ErrUnsupportedScript is returned when a script has no address form.
+	ErrUnsupportedScript = errors.New("script has no address form")
+)
+
+// Network holds the parameters that make an address specific to a network.
+type Network struct {
+	Name string
+
+	// PubKeyHashAddrID and ScriptHashAddrID are the Base58Check version
+	// bytes of P2PKH and P2SH addresses.
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+
+	// Bech32HRP is the human readable part of segwit addresses.
+	Bech32HRP string
+
+	// PrivateKeyID is the Base58Check version byte of private keys in
+	// wallet import format.
+	PrivateKeyID byte
+}
+
+var (
+	MainNet = &Network{
+		Name:             "mainnet",
+		PubKeyHashAddrID: 0x00,
+		ScriptHashAddrID: 0x05,
+		Bech32HRP:        "bc",
+		PrivateKeyID:     0x80,
+	}
+
+	TestNet = &Network{
+		Name:             "testnet",
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0xc4,
+		Bech32HRP:        "tb",
+		PrivateKeyID:     0xef,
+	}
+
+	SigNet = &Network{
+		Name:             "signet",
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0xc4,
+		Bech32HRP:        "tb",
+		PrivateKeyID:     0xef,
+	}
+
+	RegTest = &Network{
+		Name:             "regtest",
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0xc4,
+		Bech32HRP:        "bcrt",
+		PrivateKeyID:     0xef,
+	}
+
+	// networks is the order in which networks are matched when decoding.
+	// Signet shares all of its parameters with testnet and regtest shares
+	// its Base58Check versions, so those addresses decode as testnet.
+	networks = []*Network{MainNet, TestNet, RegTest}
+)
+
+// Address is a decoded bitcoin address.
+type Address struct {
+	Network *Network
+
+	// Type is one of script.TypeP2PKH, script.TypeP2SH, script.TypeP2WPKH,
+	// script.TypeP2WSH, script.TypeP2TR or script.TypeWitnessUnknown.
+	Type script.Type
+
+	// WitnessVersion is only meaningful for segwit addresses.
+	WitnessVersion int
+
+	// Program is the hash160 of P2PKH and P2SH addresses and the witness
+	// program of segwit addresses.
+	Program []byte
+}
+
+// FromScript returns the address of a scriptPubKey on the given network.
+func FromScript(s script.Script, net *Network) (*Address, error) {
+	c := script.Classify(s)
+
+	switch c.Type {
+	case script.TypeP2PKH, script.TypeP2SH:
+		return &Address{Network: net, Type: c.Type, Program: c.Hash}, nil
+
+	case script.TypeP2WPKH, script.TypeP2WSH, script.TypeP2TR,
+		script.TypeWitnessUnknown:
+
+		return &Address{
+			Network:        net,
+			Type:           c.Type,
+			WitnessVersion: c.WitnessVersion,
+			Program:        c.WitnessProgram,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrUnsupportedScript, c.Type)
+}
+
+// Decode parses a Base58Check or bech32 address and reports the network it is
+// for. Signet addresses decode as testnet, as do regtest Base58Check
+// addresses, since they can't be told apart. Use IsForNet to check an
+// address against a specific network.
+func Decode(addr string) (*Address, error) {
+	hrp, data, enc, err := bech32Decode(addr)
+	if err != nil {
+		// Only report bech32 errors for strings that claim to be a
+		// segwit address of a known network.
+		if sep := strings.LastIndexByte(addr, '1'); sep > 0 &&
+			networkForHRP(strings.ToLower(addr[:sep])) != nil {
+
+			return nil, err
+		}
+
+		return decodeBase58(addr)
+	}
+
+	net := networkForHRP(hrp)
+	if net == nil {
+		return nil, fmt.Errorf("%w: human readable part %q",
+			ErrUnknownNetwork, hrp)
+	}
+
+	return decodeSegwit(data, enc, net)
+}
+
+func networkForHRP(hrp string) *Network {
+	for _, net := range networks {
+		if hrp == net.Bech32HRP {
+			return net
+		}
+	}
+
+	return nil
+}
+
+func decodeBase58(addr string) (*Address, error) {
+	payload, version, err := base58.CheckDecode(addr)
+	switch err {
+	case nil:
+	case base58.ErrChecksum:
+		return nil, ErrInvalidChecksum
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormat, err)
+	}
+
+	if len(payload) != 20 {
+		return nil, fmt.Errorf("%w: payload must be 20 bytes, got %d",
+			ErrInvalidFormat, len(payload))
+	}
+
+	for _, net := range networks {
+		switch version {
+		case net.PubKeyHashAddrID:
+			return &Address{
+				Network: net,
+				Type:    script.TypeP2PKH,
+				Program: payload,
+			}, nil
+
+		case net.ScriptHashAddrID:
+			return &Address{
+				Network: net,
+				Type:    script.TypeP2SH,
+				Program: payload,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: version byte 0x%02x", ErrUnknownNetwork,
+		version)
+}
+
+func decodeSegwit(data []byte, enc bech32Encoding, net *Network) (*Address,
+	error) {
+
+	if len(data) < 1 {
+		return nil, fmt.Errorf("%w: missing witness version",
+			ErrInvalidWitnessProgram)
+	}
+
+	version := int(data[0])
+	if version > 16 {
+		return nil, fmt.Errorf("%w: invalid witness version %d",
+			ErrInvalidWitnessProgram, version)
+	}
+
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Address{
+		Network:        net,
+		Type:           script.TypeWitnessUnknown,
+		WitnessVersion: version,
+		Program:        program,
+	}
+	if err := a.validateWitnessProgram(); err != nil {
+		return nil, err
+	}
+
+	if (version == 0 && enc != bech32) || (version != 0 && enc != bech32m) {
+		return nil, fmt.Errorf("%w: wrong checksum variant for witness "+
+			"version %d", ErrInvalidChecksum, version)
+	}
+
+	switch {
+	case version == 0 && len(program) == 20:
+		a.Type = script.TypeP2WPKH
+	case version == 0:
+		a.Type = script.TypeP2WSH
+	case version == 1 && len(program) == 32:
+		a.Type = script.TypeP2TR
+	}
+
+	return a, nil
+}
+
+func (a *Address) validateWitnessProgram() error {
+	if len(a.Program) < 2 || len(a.Program) > 40 {
+		return fmt.Errorf("%w: program must be 2 to 40 bytes, got %d",
+			ErrInvalidWitnessProgram, len(a.Program))
+	}
+
+	if a.WitnessVersion == 0 && len(a.Program) != 20 &&
+		len(a.Program) != 32 {
+
+		return fmt.Errorf("%w: version 0 program must be 20 or 32 "+
+			"bytes, got %d", ErrInvalidWitnessProgram, len(a.Program))
+	}
+
+	return nil
+}
+
+// IsForNet returns true if the address is valid on the given network.
+func (a *Address) IsForNet(net *Network) bool {
+	switch a.Type {
+	case script.TypeP2PKH:
+		return a.Network.PubKeyHashAddrID == net.PubKeyHashAddrID
+	case script.TypeP2SH:
+		return a.Network.ScriptHashAddrID == net.ScriptHashAddrID
+	}
+
+	return a.Network.Bech32HRP == net.Bech32HRP
+}
+
+// IsSegwit returns true if the address pays to a witness program.
+func (a *Address) IsSegwit() bool {
+	return a.Type != script.TypeP2PKH && a.Type != script.TypeP2SH
+}
+
+// String returns the encoded address. Segwit v0 addresses use bech32 and later
+// witness versions use bech32m.
+func (a *Address) String() string {
+	switch a.Type {
+	case script.TypeP2PKH:
+		return base58.CheckEncode(a.Program, a.Network.PubKeyHashAddrID)
+	case script.TypeP2SH:
+		return base58.CheckEncode(a.Program, a.Network.ScriptHashAddrID)
+	}
+
+	enc := bech32
+	if a.WitnessVersion != 0 {
+		enc = bech32m
+	}
+
+	// Converting from 8 to 5 bits with padding can't fail.
+	data, _ := convertBits(a.Program, 8, 5, true)
+	data = append([]byte{byte(a.WitnessVersion)}, data...)
+
+	return bech32Encode(a.Network.Bech32HRP, data, enc)
+}
+
+// Script returns the scriptPubKey that the address pays to.
+func (a *Address) Script() (script.Script, error) {
+	switch a.Type {
+	case script.TypeP2PKH:
+		return script.P2PKH(a.Program)
+	case script.TypeP2SH:
+		return script.P2SHFromHash(a.Program)
+	}
+
+	if err := a.validateWitnessProgram(); err != nil {
+		return nil, err
+	}
+
+	return script.WitnessProgram(a.WitnessVersion, a.Program)
+}
@@ -0,0 +1,195 @@
+package address
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ellemouton/btc/script"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		addr         string
+		scriptPubKey string
+		net          *Network
+		typ          script.Type
+		version      int
+	}{
+		{
+			addr:         "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2",
+			scriptPubKey: "76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac",
+			net:          MainNet,
+			typ:          script.TypeP2PKH,
+		},
+		{
+			addr:         "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+			scriptPubKey: "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87",
+			net:          MainNet,
+			typ:          script.TypeP2SH,
+		},
+		{
+			addr:         "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+			scriptPubKey: "0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			net:          MainNet,
+			typ:          script.TypeP2WPKH,
+		},
+		{
+			addr:         "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7",
+			scriptPubKey: "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262",
+			net:          TestNet,
+			typ:          script.TypeP2WSH,
+		},
+		{
+			addr:         "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+			scriptPubKey: "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6",
+			net:          MainNet,
+			typ:          script.TypeWitnessUnknown,
+			version:      1,
+		},
+		{
+			addr:         "BC1SW50QGDZ25J",
+			scriptPubKey: "6002751e",
+			net:          MainNet,
+			typ:          script.TypeWitnessUnknown,
+			version:      16,
+		},
+		{
+			addr:         "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+			scriptPubKey: "5210751e76e8199196d454941c45d1b3a323",
+			net:          MainNet,
+			typ:          script.TypeWitnessUnknown,
+			version:      2,
+		},
+		{
+			addr:         "tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy",
+			scriptPubKey: "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+			net:          TestNet,
+			typ:          script.TypeP2WSH,
+		},
+		{
+			addr:         "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c",
+			scriptPubKey: "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433",
+			net:          TestNet,
+			typ:          script.TypeP2TR,
+			version:      1,
+		},
+		{
+			addr:         "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+			scriptPubKey: "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			net:          MainNet,
+			typ:          script.TypeP2TR,
+			version:      1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.addr, func(t *testing.T) {
+			a, err := Decode(test.addr)
+			require.NoError(t, err)
+			require.Equal(t, test.net, a.Network)
+			require.Equal(t, test.typ, a.Type)
+			require.Equal(t, test.version, a.WitnessVersion)
+
+			s, err := a.Script()
+			require.NoError(t, err)
+			b, err := s.SerializeRaw()
+			require.NoError(t, err)
+			require.Equal(t, test.scriptPubKey, hex.EncodeToString(b))
+
+			// Encoding always produces lower case.
+			require.Equal(t, strings.ToLower(test.addr), strings.ToLower(a.String()))
+			if a.IsSegwit() {
+				require.Equal(t, strings.ToLower(test.addr), a.String())
+			} else {
+				require.Equal(t, test.addr, a.String())
+			}
+
+			fromScript, err := FromScript(s, test.net)
+			require.NoError(t, err)
+			require.Equal(t, a, fromScript)
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		addr string
+		err  error
+	}{
+		// Invalid human readable part.
+		{"tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut", ErrUnknownNetwork},
+		// Bech32 checksum used for a v1+ program.
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", ErrInvalidChecksum},
+		{"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf", ErrInvalidChecksum},
+		{"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL", ErrInvalidChecksum},
+		// Bech32m checksum used for a v0 program.
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", ErrInvalidChecksum},
+		{"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47", ErrInvalidChecksum},
+		// Invalid character in the checksum.
+		{"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4", ErrInvalidFormat},
+		// Witness version 17.
+		{"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", ErrInvalidWitnessProgram},
+		// Programs that are too short or too long.
+		{"bc1pw5dgrnzv", ErrInvalidWitnessProgram},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav", ErrInvalidWitnessProgram},
+		// Version 0 program of invalid length.
+		{"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P", ErrInvalidWitnessProgram},
+		// Mixed case.
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq", ErrInvalidFormat},
+		// Invalid padding.
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf", ErrInvalidFormat},
+		{"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j", ErrInvalidFormat},
+		// Empty data section.
+		{"bc1gmk9yu", ErrInvalidWitnessProgram},
+		// Base58 with a bad checksum and an empty string.
+		{"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", ErrInvalidChecksum},
+		{"", ErrInvalidFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.addr, func(t *testing.T) {
+			_, err := Decode(test.addr)
+			require.True(t, errors.Is(err, test.err), "got %v", err)
+		})
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	hash, err := hex.DecodeString("751e76e8199196d454941c45d1b3a323f1433bd6")
+	require.NoError(t, err)
+
+	p2wpkh, err := script.P2WPKH(hash)
+	require.NoError(t, err)
+
+	a, err := FromScript(p2wpkh, RegTest)
+	require.NoError(t, err)
+	require.Equal(t, "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", a.String())
+
+	decoded, err := Decode(a.String())
+	require.NoError(t, err)
+	require.Equal(t, RegTest, decoded.Network)
+	require.True(t, decoded.IsForNet(RegTest))
+	require.False(t, decoded.IsForNet(TestNet))
+
+	// Signet addresses can't be told apart from testnet ones.
+	a, err = FromScript(p2wpkh, SigNet)
+	require.NoError(t, err)
+	decoded, err = Decode(a.String())
+	require.NoError(t, err)
+	require.True(t, decoded.IsForNet(SigNet))
+	require.True(t, decoded.IsForNet(TestNet))
+	require.False(t, decoded.IsForNet(MainNet))
+
+	p2pkh, err := script.P2PKH(hash)
+	require.NoError(t, err)
+	a, err = FromScript(p2pkh, TestNet)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(a.String(), "m") ||
+		strings.HasPrefix(a.String(), "n"))
+
+	_, err = FromScript(script.NullData([]byte{1}), MainNet)
+	require.True(t, errors.Is(err, ErrUnsupportedScript))
+}
@@ -0,0 +1,183 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bech32Encoding is the checksum variant used by a bech32 string. BIP173
+// defines the original encoding used by segwit v0 and BIP350 defines bech32m
+// which is used by every later witness version.
+type bech32Encoding int
+
+const (
+	bech32 bech32Encoding = iota
+	bech32m
+)
+
+const (
+	bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+
+	// bech32MaxLen is the maximum length of a bech32 string.
+	bech32MaxLen = 90
+
+	bech32ChecksumLen = 6
+)
+
+var bech32Gen = [5]uint32{
+	0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3,
+}
+
+func (e bech32Encoding) constant() uint32 {
+	if e == bech32m {
+		return bech32mConst
+	}
+
+	return bech32Const
+}
+
+func bech32Polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= bech32Gen[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+// hrpExpand returns the human readable part in the form that is fed into
+// the checksum: the high bits of each character, a zero and then the low bits.
+func hrpExpand(hrp string) []byte {
+	res := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		res = append(res, hrp[i]>>5)
+	}
+	res = append(res, 0)
+	for i := 0; i < len(hrp); i++ {
+		res = append(res, hrp[i]&31)
+	}
+
+	return res
+}
+
+func bech32Checksum(hrp string, data []byte, enc bech32Encoding) []byte {
+	values := append(hrpExpand(hrp), data...)
+	values = append(values, make([]byte, bech32ChecksumLen)...)
+	mod := bech32Polymod(values) ^ enc.constant()
+
+	res := make([]byte, bech32ChecksumLen)
+	for i := range res {
+		res[i] = byte((mod >> uint(5*(5-i))) & 31)
+	}
+
+	return res
+}
+
+// bech32Encode encodes the 5 bit groups in data with the given human readable
+// part.
+func bech32Encode(hrp string, data []byte, enc bech32Encoding) string {
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range append(data, bech32Checksum(hrp, data, enc)...) {
+		sb.WriteByte(bech32Charset[v])
+	}
+
+	return sb.String()
+}
+
+// bech32Decode decodes a bech32 or bech32m string into its lower case human
+// readable part and the 5 bit groups of its data part, without the checksum.
+func bech32Decode(s string) (string, []byte, bech32Encoding, error) {
+	if len(s) > bech32MaxLen {
+		return "", nil, 0, fmt.Errorf("%w: length %d exceeds %d",
+			ErrInvalidFormat, len(s), bech32MaxLen)
+	}
+
+	lower, upper := strings.ToLower(s), strings.ToUpper(s)
+	if s != lower && s != upper {
+		return "", nil, 0, fmt.Errorf("%w: mixed case", ErrInvalidFormat)
+	}
+	s = lower
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+bech32ChecksumLen+1 > len(s) {
+		return "", nil, 0, fmt.Errorf("%w: invalid separator position",
+			ErrInvalidFormat)
+	}
+
+	hrp := s[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, fmt.Errorf("%w: invalid human "+
+				"readable part character", ErrInvalidFormat)
+		}
+	}
+
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, fmt.Errorf("%w: invalid character %q",
+				ErrInvalidFormat, s[i])
+		}
+		data = append(data, byte(v))
+	}
+
+	var enc bech32Encoding
+	switch bech32Polymod(append(hrpExpand(hrp), data...)) {
+	case bech32Const:
+		enc = bech32
+	case bech32mConst:
+		enc = bech32m
+	default:
+		return "", nil, 0, ErrInvalidChecksum
+	}
+
+	return hrp, data[:len(data)-bech32ChecksumLen], enc, nil
+}
+
+// convertBits regroups data from groups of fromBits into groups of toBits.
+// When pad is false the input must not have more than fromBits-1 bits left
+// over and those bits must be zero.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte,
+	error) {
+
+	var (
+		acc  uint32
+		bits uint
+		res  []byte
+		max  = uint32(1)<<toBits - 1
+	)
+	for _, v := range data {
+		if uint32(v)>>fromBits != 0 {
+			return nil, fmt.Errorf("%w: invalid data range",
+				ErrInvalidFormat)
+		}
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			res = append(res, byte(acc>>bits&max))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			res = append(res, byte(acc<<(toBits-bits)&max))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&max != 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidFormat)
+	}
+
+	return res, nil
+}
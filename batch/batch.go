@@ -0,0 +1,243 @@
+package batch
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/signature"
+)
+
+// Error is returned by Verifier.Verify when a signature in the batch is
+// invalid. Index is the position of the first invalid signature in the order
+// that signatures were added.
+type Error struct {
+	Index int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("signature %d is invalid", e.Index)
+}
+
+type entry struct {
+	pubKey *s256point.S256Point
+	msg    []byte
+
+	// Exactly one of the following is set.
+	schnorrSig *schnorr.Signature
+	ecdsaSig   *signature.Signature
+}
+
+// Verifier collects signatures so that they can be checked together.
+//
+// Schnorr signatures are checked with a single multi-scalar multiplication
+// over a random linear combination of the BIP340 verification equations. The
+// random weights make it infeasible for an invalid signature to cancel out
+// another. ECDSA signatures only commit to the x coordinate of their nonce
+// point so they can't be combined in the same way and are each checked with
+// one two-term multi-scalar multiplication instead.
+type Verifier struct {
+	entries []entry
+}
+
+func New() *Verifier {
+	return &Verifier{}
+}
+
+// AddSchnorr adds a BIP340 signature over msg to the batch.
+func (v *Verifier) AddSchnorr(pubKey *s256point.S256Point, msg []byte,
+	sig *schnorr.Signature) {
+
+	v.entries = append(v.entries, entry{
+		pubKey:     pubKey,
+		msg:        msg,
+		schnorrSig: sig,
+	})
+}
+
+// AddECDSA adds an ECDSA signature over the message hash to the batch.
+func (v *Verifier) AddECDSA(pubKey *s256point.S256Point, hash []byte,
+	sig *signature.Signature) {
+
+	v.entries = append(v.entries, entry{
+		pubKey:   pubKey,
+		msg:      hash,
+		ecdsaSig: sig,
+	})
+}
+
+// Len returns the number of signatures in the batch.
+func (v *Verifier) Len() int {
+	return len(v.entries)
+}
+
+// Verify checks every signature in the batch. If any are invalid an *Error
+// identifying the first one is returned. Other errors are only returned if
+// point arithmetic fails.
+func (v *Verifier) Verify() error {
+	var (
+		schnorrIdxs []int
+		failed      = -1
+	)
+	for i, e := range v.entries {
+		if e.schnorrSig != nil {
+			schnorrIdxs = append(schnorrIdxs, i)
+			continue
+		}
+
+		ok, err := e.pubKey.Verify(e.msg, e.ecdsaSig)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			failed = i
+			break
+		}
+	}
+
+	// Only Schnorr signatures before the first invalid ECDSA signature can
+	// change which index is reported.
+	for len(schnorrIdxs) > 0 && failed >= 0 &&
+		schnorrIdxs[len(schnorrIdxs)-1] > failed {
+
+		schnorrIdxs = schnorrIdxs[:len(schnorrIdxs)-1]
+	}
+
+	ok, err := v.verifySchnorr(schnorrIdxs)
+	if err != nil {
+		return err
+	}
+
+	// Find the culprit one signature at a time if the batch failed.
+	if !ok {
+		for _, i := range schnorrIdxs {
+			e := v.entries[i]
+			pk, ok := liftKey(e.pubKey)
+			if !ok {
+				return &Error{Index: i}
+			}
+
+			ok, err := pk.VerifySchnorr(e.msg, e.schnorrSig)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &Error{Index: i}
+			}
+		}
+	}
+
+	if failed >= 0 {
+		return &Error{Index: failed}
+	}
+
+	return nil
+}
+
+// verifySchnorr checks that (sum a_i*s_i)*G equals sum a_i*R_i + a_i*e_i*P_i
+// over the given entries, where a_1 is 1 and the remaining a_i are random. It
+// does so by checking that moving everything to the left hand side gives the
+// point at infinity.
+func (v *Verifier) verifySchnorr(idxs []int) (bool, error) {
+	if len(idxs) == 0 {
+		return true, nil
+	}
+
+	n := s256point.N
+	var (
+		points  = []*s256point.S256Point{s256point.G}
+		scalars = []*big.Int{new(big.Int)}
+		sumS    = scalars[0]
+	)
+	for j, i := range idxs {
+		e := v.entries[i]
+		sig := e.schnorrSig
+
+		if sig.R.Cmp(s256field.P) >= 0 || sig.S.Cmp(n) >= 0 {
+			return false, nil
+		}
+
+		// A key that can't be used fails the batch so that the
+		// caller reports its index.
+		pk, ok := liftKey(e.pubKey)
+		if !ok {
+			return false, nil
+		}
+		pubKey := pk.XOnly()
+
+		rBytes := make([]byte, 32)
+		rb := sig.R.Bytes()
+		copy(rBytes[32-len(rb):], rb)
+
+		// The nonce point is the lift of R which fails if R is not
+		// the x coordinate of a point on the curve.
+		R, err := s256point.ParseXOnly(rBytes)
+		if err != nil {
+			return false, nil
+		}
+
+		ch := schnorr.ChallengeHash(rBytes, pubKey, e.msg)
+		c := new(big.Int).SetBytes(ch)
+		c.Mod(c, n)
+
+		a := big.NewInt(1)
+		if j > 0 {
+			a, err = randScalar()
+			if err != nil {
+				return false, err
+			}
+		}
+
+		as := new(big.Int).Mul(a, sig.S)
+		sumS.Add(sumS, as)
+		sumS.Mod(sumS, n)
+
+		ac := new(big.Int).Mul(a, c)
+		ac.Mod(ac, n)
+
+		points = append(points, R, pk)
+		scalars = append(scalars,
+			new(big.Int).Sub(n, a), new(big.Int).Sub(n, ac))
+	}
+
+	res, err := s256point.MultiScalarMul(points, scalars)
+	if err != nil {
+		return false, err
+	}
+
+	return res.IsInfinity(), nil
+}
+
+// liftKey returns the point with the x coordinate of the public key and an
+// even y, which is the key that BIP340 signatures are checked against. False
+// is returned for the point at infinity, which has no x-only encoding.
+func liftKey(pubKey *s256point.S256Point) (*s256point.S256Point, bool) {
+	if pubKey == nil || pubKey.IsInfinity() {
+		return nil, false
+	}
+
+	if pubKey.HasEvenY() {
+		return pubKey, true
+	}
+
+	pk, err := s256point.ParseXOnly(pubKey.XOnly())
+	if err != nil {
+		return nil, false
+	}
+
+	return pk, true
+}
+
+// randScalar returns a uniformly random integer in [1, n-1].
+func randScalar() (*big.Int, error) {
+	max := new(big.Int).Sub(s256point.N, big.NewInt(1))
+	a, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Add(a, big.NewInt(1)), nil
+}
@@ -0,0 +1,148 @@
+package batch
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/signature"
+	"github.com/stretchr/testify/require"
+)
+
+type testSig struct {
+	priv       *privatekey.PrivateKey
+	msg        []byte
+	schnorrSig *schnorr.Signature
+	ecdsaSig   *signature.Signature
+}
+
+func newTestSigs(t *testing.T) []testSig {
+	var sigs []testSig
+	for i := int64(1); i <= 6; i++ {
+		priv, err := privatekey.New(big.NewInt(i * 123456789))
+		require.NoError(t, err)
+
+		msg := sha256.Sum256([]byte{byte(i)})
+		s := testSig{priv: priv, msg: msg[:]}
+
+		// Alternate between Schnorr and ECDSA signatures.
+		if i%3 != 0 {
+			s.schnorrSig, err = priv.SignSchnorr(msg[:], make([]byte, 32))
+		} else {
+			s.ecdsaSig, err = priv.Sign(msg[:])
+		}
+		require.NoError(t, err)
+
+		sigs = append(sigs, s)
+	}
+
+	return sigs
+}
+
+func newVerifier(sigs []testSig) *Verifier {
+	v := New()
+	for _, s := range sigs {
+		if s.schnorrSig != nil {
+			v.AddSchnorr(s.priv.PubKey, s.msg, s.schnorrSig)
+		} else {
+			v.AddECDSA(s.priv.PubKey, s.msg, s.ecdsaSig)
+		}
+	}
+
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	sigs := newTestSigs(t)
+
+	v := newVerifier(sigs)
+	require.Equal(t, len(sigs), v.Len())
+	require.NoError(t, v.Verify())
+
+	require.NoError(t, New().Verify())
+
+	tests := []struct {
+		name    string
+		corrupt func(sigs []testSig)
+		index   int
+	}{
+		{
+			name: "schnorr s",
+			corrupt: func(sigs []testSig) {
+				s := sigs[1].schnorrSig
+				sigs[1].schnorrSig = schnorr.New(
+					s.R, new(big.Int).Add(s.S, big.NewInt(1)),
+				)
+			},
+			index: 1,
+		},
+		{
+			name: "schnorr message",
+			corrupt: func(sigs []testSig) {
+				sigs[4].msg = sigs[3].msg
+			},
+			index: 4,
+		},
+		{
+			name: "schnorr r not on curve",
+			corrupt: func(sigs []testSig) {
+				s := sigs[0].schnorrSig
+				sigs[0].schnorrSig = schnorr.New(big.NewInt(5), s.S)
+			},
+			index: 0,
+		},
+		{
+			name: "schnorr key at infinity",
+			corrupt: func(sigs []testSig) {
+				inf, err := s256point.New(nil, nil)
+				require.NoError(t, err)
+
+				sigs[4].priv = &privatekey.PrivateKey{PubKey: inf}
+			},
+			index: 4,
+		},
+		{
+			name: "ecdsa",
+			corrupt: func(sigs []testSig) {
+				s := sigs[2].ecdsaSig
+				sigs[2].ecdsaSig = signature.New(
+					s.Rx, new(big.Int).Add(s.S, big.NewInt(1)),
+				)
+			},
+			index: 2,
+		},
+		{
+			name: "first failure is reported",
+			corrupt: func(sigs []testSig) {
+				sigs[5].msg = sigs[0].msg
+				sigs[4].msg = sigs[0].msg
+			},
+			index: 4,
+		},
+		{
+			name: "ecdsa before schnorr",
+			corrupt: func(sigs []testSig) {
+				sigs[2].msg = sigs[0].msg
+				sigs[4].msg = sigs[0].msg
+			},
+			index: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			corrupted := append([]testSig{}, sigs...)
+			test.corrupt(corrupted)
+
+			err := newVerifier(corrupted).Verify()
+
+			var batchErr *Error
+			require.True(t, errors.As(err, &batchErr), err)
+			require.Equal(t, test.index, batchErr.Index)
+		})
+	}
+}
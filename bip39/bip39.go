@@ -0,0 +1,249 @@
+package bip39
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// SeedSize is the size of the seed derived from a mnemonic.
+	SeedSize = 64
+
+	// seedIterations is the number of PBKDF2 rounds used to derive the
+	// seed.
+	seedIterations = 2048
+
+	// wordlistSize is the number of words in every wordlist, so that each
+	// word encodes 11 bits.
+	wordlistSize = 2048
+	bitsPerWord  = 11
+)
+
+var (
+	// ErrInvalidEntropySize is returned when entropy is not 128, 160,
+	// 192, 224 or 256 bits long.
+	ErrInvalidEntropySize = errors.New("entropy must be 128 to 256 bits " +
+		"in steps of 32")
+
+	// ErrInvalidWordCount is returned when a mnemonic doesn't have 12,
+	// 15, 18, 21 or 24 words.
+	ErrInvalidWordCount = errors.New("mnemonic must have 12, 15, 18, 21 " +
+		"or 24 words")
+
+	// ErrUnknownWord is returned when a mnemonic contains a word that is
+	// not in the wordlist.
+	ErrUnknownWord = errors.New("word is not in the wordlist")
+
+	// ErrInvalidChecksum is returned when the checksum bits of a mnemonic
+	// don't match its entropy.
+	ErrInvalidChecksum = errors.New("invalid mnemonic checksum")
+)
+
+// Wordlist is one of the lists of 2048 words that mnemonics are made of.
+type Wordlist struct {
+	// Name is the name of the list in the BIP39 repository.
+	Name string
+
+	// Separator is put between the words of a mnemonic. It is the
+	// ideographic space for Japanese and a space for every other list.
+	Separator string
+
+	words []string
+	index map[string]int
+}
+
+func newWordlist(name, sep, words string) *Wordlist {
+	w := &Wordlist{
+		Name:      name,
+		Separator: sep,
+		words:     strings.Split(strings.TrimSpace(words), "\n"),
+		index:     make(map[string]int, wordlistSize),
+	}
+
+	if len(w.words) != wordlistSize {
+		panic(fmt.Sprintf("bip39: %s wordlist has %d words", name,
+			len(w.words)))
+	}
+
+	for i, word := range w.words {
+		w.index[word] = i
+	}
+
+	return w
+}
+
+// Word returns the i'th word of the list.
+func (w *Wordlist) Word(i int) string {
+	return w.words[i]
+}
+
+// Index returns the position of word in the list and whether it was found.
+func (w *Wordlist) Index(word string) (int, bool) {
+	i, ok := w.index[nfkd(word)]
+	return i, ok
+}
+
+// Wordlists are all of the supported wordlists.
+var Wordlists = []*Wordlist{
+	English, Japanese, Korean, Spanish, ChineseSimplified,
+	ChineseTraditional, French, Italian, Czech, Portuguese,
+}
+
+// NewEntropy returns bits of entropy read from crypto/rand. The size must be
+// 128, 160, 192, 224 or 256 bits.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropySize(bits); err != nil {
+		return nil, err
+	}
+
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+
+	return entropy, nil
+}
+
+func checkEntropySize(bits int) error {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return fmt.Errorf("%w: got %d", ErrInvalidEntropySize, bits)
+	}
+
+	return nil
+}
+
+// NewMnemonic encodes entropy as a mnemonic using the given wordlist. The
+// first byte of the SHA256 hash of the entropy is used as a checksum, one bit
+// for every 32 bits of entropy, and every 11 bits of entropy and checksum are
+// encoded as one word.
+func NewMnemonic(entropy []byte, w *Wordlist) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropySize(bits); err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), hash[0])
+
+	words := make([]string, (bits+bits/32)/bitsPerWord)
+	for i := range words {
+		words[i] = w.words[readBits(data, i*bitsPerWord)]
+	}
+
+	return strings.Join(words, w.Separator), nil
+}
+
+// EntropyFromMnemonic decodes a mnemonic back into its entropy after checking
+// its word count, words and checksum.
+func EntropyFromMnemonic(mnemonic string, w *Wordlist) ([]byte, error) {
+	words := splitMnemonic(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, fmt.Errorf("%w: got %d", ErrInvalidWordCount,
+			len(words))
+	}
+
+	totalBits := len(words) * bitsPerWord
+	checksumBits := totalBits / 33
+	entropyBits := totalBits - checksumBits
+
+	data := make([]byte, (totalBits+7)/8)
+	for i, word := range words {
+		idx, ok := w.Index(word)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownWord, word)
+		}
+
+		writeBits(data, i*bitsPerWord, idx)
+	}
+
+	entropy := data[:entropyBits/8]
+	hash := sha256.Sum256(entropy)
+
+	mask := byte(0xff) << uint(8-checksumBits)
+	if data[len(entropy)]&mask != hash[0]&mask {
+		return nil, ErrInvalidChecksum
+	}
+
+	return entropy, nil
+}
+
+// Validate returns an error if the mnemonic is not a valid mnemonic in the
+// given wordlist.
+func Validate(mnemonic string, w *Wordlist) error {
+	_, err := EntropyFromMnemonic(mnemonic, w)
+	return err
+}
+
+// DetectWordlist returns the wordlist that contains every word of the
+// mnemonic. Some words appear in more than one list, in which case the first
+// matching list in Wordlists is returned.
+func DetectWordlist(mnemonic string) (*Wordlist, error) {
+	words := splitMnemonic(mnemonic)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("%w: got 0", ErrInvalidWordCount)
+	}
+
+	for _, w := range Wordlists {
+		found := true
+		for _, word := range words {
+			if _, ok := w.Index(word); !ok {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return w, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no wordlist contains every word",
+		ErrUnknownWord)
+}
+
+// NewSeed derives the 64 byte seed from a mnemonic and an optional
+// passphrase with PBKDF2-HMAC-SHA512. The mnemonic is not validated, as
+// BIP39 allows seeds to be derived from any sentence. Use Validate first to
+// reject typos.
+func NewSeed(mnemonic, passphrase string) []byte {
+	password := nfkd(strings.Join(splitMnemonic(mnemonic), " "))
+	salt := nfkd("mnemonic" + passphrase)
+
+	return pbkdf2.Key(
+		[]byte(password), []byte(salt), seedIterations, SeedSize,
+		sha512.New,
+	)
+}
+
+// splitMnemonic splits a mnemonic on any white space, including the
+// ideographic space used by Japanese mnemonics.
+func splitMnemonic(mnemonic string) []string {
+	return strings.Fields(nfkd(mnemonic))
+}
+
+// readBits returns the 11 bits of data starting at bit offset.
+func readBits(data []byte, offset int) int {
+	var v int
+	for i := 0; i < bitsPerWord; i++ {
+		bit := offset + i
+		v = v<<1 | int(data[bit/8]>>uint(7-bit%8)&1)
+	}
+
+	return v
+}
+
+// writeBits writes the 11 bits of v to data starting at bit offset.
+func writeBits(data []byte, offset, v int) {
+	for i := 0; i < bitsPerWord; i++ {
+		if v>>uint(bitsPerWord-1-i)&1 == 1 {
+			bit := offset + i
+			data[bit/8] |= 1 << uint(7-bit%8)
+		}
+	}
+}
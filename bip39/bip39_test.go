@@ -0,0 +1,243 @@
+package bip39
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestVectors(t *testing.T) {
+	// Test vectors from the BIP39 reference implementation, which all use
+	// the passphrase "TREZOR".
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+			seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+		{
+			entropy:  "80808080808080808080808080808080",
+			mnemonic: "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+			seed:     "d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+			seed:     "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+		},
+		{
+			entropy:  "0000000000000000000000000000000000000000000000000000000000000000",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+			seed:     "bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+		},
+		{
+			entropy:  "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+			mnemonic: "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote",
+			seed:     "dd48c104698c30cfe2b6142103248622fb7bb0ff692eebb00089b32d22484e1613912f0a5b694407be899ffd31ed3992c456cdf60f5d4564b8ba3f05a69890ad",
+		},
+		{
+			entropy:  "9e885d952ad362caeb4efe34a8e91bd2",
+			mnemonic: "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+			seed:     "274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+		},
+	}
+
+	for _, test := range tests {
+		entropy, err := hex.DecodeString(test.entropy)
+		require.NoError(t, err)
+
+		mnemonic, err := NewMnemonic(entropy, English)
+		require.NoError(t, err)
+		require.Equal(t, test.mnemonic, mnemonic)
+
+		decoded, err := EntropyFromMnemonic(mnemonic, English)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed := NewSeed(mnemonic, "TREZOR")
+		require.Equal(t, test.seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestJapaneseVectors(t *testing.T) {
+	// Test vectors for the Japanese wordlist from the BIP39 repository. The
+	// passphrase only derives the right seed once it is NFKD normalized.
+	const passphrase = "㍍ガバヴァぱばぐゞちぢ十人十色"
+
+	tests := []struct {
+		entropy  string
+		mnemonic string
+		seed     string
+	}{
+		{
+			entropy:  "00000000000000000000000000000000",
+			mnemonic: "あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あいこくしん　あおぞら",
+			seed:     "a262d6fb6122ecf45be09c50492b31f92e9beb7d9a845987a02cefda57a15f9c467a17872029a9e92299b5cbdf306e3a0ee620245cbd508959b6cb7ca637bd55",
+		},
+		{
+			entropy:  "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			mnemonic: "そつう　れきだい　ほんやく　わかす　りくつ　ばいか　ろせん　やちん　そつう　れきだい　ほんやく　わかめ",
+			seed:     "aee025cbe6ca256862f889e48110a6a382365142f7d16f2b9545285b3af64e542143a577e9c144e101a6bdca18f8d97ec3366ebf5b088b1c1af9bc31346e60d9",
+		},
+	}
+
+	for _, test := range tests {
+		entropy, err := hex.DecodeString(test.entropy)
+		require.NoError(t, err)
+
+		mnemonic, err := NewMnemonic(entropy, Japanese)
+		require.NoError(t, err)
+		require.Equal(t, test.mnemonic, norm.NFC.String(mnemonic))
+
+		decoded, err := EntropyFromMnemonic(test.mnemonic, Japanese)
+		require.NoError(t, err)
+		require.Equal(t, entropy, decoded)
+
+		seed := NewSeed(test.mnemonic, passphrase)
+		require.Equal(t, test.seed, hex.EncodeToString(seed))
+	}
+}
+
+func TestWordlists(t *testing.T) {
+	// The CRC32 checksums of the files in the BIP39 repository.
+	checksums := map[*Wordlist]uint32{
+		English:            0xc1dbd296,
+		Japanese:           0x0acc1419,
+		Korean:             0x4ef461eb,
+		Spanish:            0x266e4f3d,
+		ChineseSimplified:  0xe3721bbf,
+		ChineseTraditional: 0x3c20b443,
+		French:             0x3e56b216,
+		Italian:            0x2fc7d07e,
+		Czech:              0xd1b5fda0,
+		Portuguese:         0xe627a546,
+	}
+	require.Len(t, Wordlists, len(checksums))
+
+	for _, w := range Wordlists {
+		file := strings.Join(w.words, "\n") + "\n"
+		require.Equal(t, checksums[w], crc32.ChecksumIEEE([]byte(file)),
+			w.Name)
+
+		// The lists are stored normalized.
+		require.Equal(t, file, nfkd(file), w.Name)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, w := range Wordlists {
+		for _, bits := range []int{128, 160, 192, 224, 256} {
+			entropy, err := NewEntropy(bits)
+			require.NoError(t, err)
+			require.Len(t, entropy, bits/8)
+
+			mnemonic, err := NewMnemonic(entropy, w)
+			require.NoError(t, err)
+			require.Len(t, splitMnemonic(mnemonic), bits*33/32/11)
+
+			decoded, err := EntropyFromMnemonic(mnemonic, w)
+			require.NoError(t, err)
+			require.Equal(t, entropy, decoded)
+
+			detected, err := DetectWordlist(mnemonic)
+			require.NoError(t, err)
+			require.Equal(t, w.Name, detected.Name)
+		}
+	}
+
+	_, err := NewEntropy(129)
+	require.True(t, errors.Is(err, ErrInvalidEntropySize))
+
+	_, err = NewMnemonic(make([]byte, 15), English)
+	require.True(t, errors.Is(err, ErrInvalidEntropySize))
+}
+
+func TestInvalidMnemonic(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		err      error
+	}{
+		{
+			name:     "bad checksum",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			err:      ErrInvalidChecksum,
+		},
+		{
+			name:     "too few words",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			err:      ErrInvalidWordCount,
+		},
+		{
+			name:     "unknown word",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bitcoin",
+			err:      ErrUnknownWord,
+		},
+		{
+			name:     "empty",
+			mnemonic: "",
+			err:      ErrInvalidWordCount,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.mnemonic, English)
+			require.True(t, errors.Is(err, test.err), err)
+		})
+	}
+
+	// Extra white space is ignored.
+	err := Validate(" abandon abandon abandon abandon abandon abandon "+
+		"abandon abandon abandon  abandon abandon about\n", English)
+	require.NoError(t, err)
+}
+
+func TestNormalization(t *testing.T) {
+	// Hangul syllables decompose into jamo.
+	require.Equal(t, "\u1100\u1161", nfkd("\uac00"))
+	require.Equal(t, "\u1112\u1161\u11ab", nfkd("\ud55c"))
+
+	// Precomposed letters and kana are accepted and give the same seed.
+	for _, w := range []*Wordlist{Spanish, French, Japanese} {
+		// Find a word with a combining accent or voiced sound mark.
+		var word string
+		for i := 0; i < wordlistSize && word == ""; i++ {
+			if strings.ContainsAny(w.Word(i), "\u0301\u3099") {
+				word = w.Word(i)
+			}
+		}
+
+		composed := norm.NFC.String(word)
+		require.NotEqual(t, word, composed, w.Name)
+
+		idx, ok := w.Index(composed)
+		require.True(t, ok, w.Name)
+		require.Equal(t, word, w.Word(idx))
+
+		require.Equal(t, NewSeed(word, ""), NewSeed(composed, ""))
+	}
+
+	// Japanese mnemonics are joined with ideographic spaces, which are
+	// replaced by plain spaces when deriving the seed.
+	mnemonic, err := NewMnemonic(make([]byte, 16), Japanese)
+	require.NoError(t, err)
+	require.Contains(t, mnemonic, "\u3000")
+	require.Equal(t, NewSeed(mnemonic, ""), NewSeed(
+		strings.Replace(mnemonic, "\u3000", " ", -1), "",
+	))
+}
@@ -0,0 +1,9 @@
+package bip39
+
+import "golang.org/x/text/unicode/norm"
+
+// nfkd returns s in Unicode normalization form KD as required by BIP39 for
+// both mnemonics and passphrases. The wordlists are stored in this form.
+func nfkd(s string) string {
+	return norm.NFKD.String(s)
+}
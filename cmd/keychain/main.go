@@ -4,8 +4,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/bip39"
 	"github.com/ellemouton/btc/hdkeys"
-	"github.com/tyler-smith/go-bip39"
 	"github.com/urfave/cli/v2"
 	"log"
 	"os"
@@ -65,7 +65,7 @@ func main() {
 			{
 				Name:    "new",
 				Aliases: []string{"n"},
-				Usage:   "generate a new seed and extended key pair",
+				Usage:   "generate a new mnemonic and extended key pair",
 				Action: genNew,
 			},
 			{
@@ -95,6 +95,8 @@ func main() {
 		},
 	}
 
+	app.Commands = append(app.Commands, messageCommands...)
+
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
@@ -171,6 +173,15 @@ func genFromMnemonic(_ *cli.Context) error {
 		log.Fatal("must provide 'mnemonic' flag")
 	}
 
+	wordlist, err := bip39.DetectWordlist(mnemonic)
+	if err != nil {
+		return err
+	}
+
+	if err := bip39.Validate(mnemonic, wordlist); err != nil {
+		return err
+	}
+
 	s := bip39.NewSeed(mnemonic, password)
 
 	priv, err := hdkeys.ExtendedPrivKeyFromSeed(s)
@@ -211,12 +222,17 @@ func getPub(_ *cli.Context) error {
 }
 
 func genNew(_ *cli.Context) error {
-	seed, err := hdkeys.NewSeed()
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return err
+	}
+
+	m, err := bip39.NewMnemonic(entropy, bip39.English)
 	if err != nil {
 		return err
 	}
 
-	priv, err := hdkeys.ExtendedPrivKeyFromSeed(seed)
+	priv, err := hdkeys.ExtendedPrivKeyFromSeed(bip39.NewSeed(m, password))
 	if err != nil {
 		return err
 	}
@@ -226,6 +242,7 @@ func genNew(_ *cli.Context) error {
 		return err
 	}
 
+	fmt.Println("Mnemonic:\t", m)
 	fmt.Println("Priv:\t", base58.Encode(priv.Serialize()))
 	fmt.Println("Pub:\t", base58.Encode(pub.Serialize()))
 
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/hdkeys"
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/message"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	addr      string
+	addrType  string
+	msg       string
+	sig       string
+	sigFormat string
+)
+
+var messageFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:        "address",
+		Usage:       "address the message is signed for",
+		Destination: &addr,
+	},
+	&cli.StringFlag{
+		Name:        "type",
+		Value:       "p2wpkh",
+		Usage:       "address type to sign for: p2pkh, p2sh-p2wpkh, p2wpkh or p2tr",
+		Destination: &addrType,
+	},
+	&cli.StringFlag{
+		Name:        "message",
+		Usage:       "message to sign or verify",
+		Destination: &msg,
+	},
+	&cli.StringFlag{
+		Name:        "signature",
+		Usage:       "base64 signature to verify",
+		Destination: &sig,
+	},
+	&cli.StringFlag{
+		Name:        "format",
+		Value:       "simple",
+		Usage:       "signature format: legacy, simple or full",
+		Destination: &sigFormat,
+	},
+}
+
+var messageCommands = []*cli.Command{
+	{
+		Name:   "signMessage",
+		Usage:  "sign a message with the key at 'path' below 'xpriv'",
+		Flags:  messageFlags,
+		Action: signMessage,
+	},
+	{
+		Name:   "verifyMessage",
+		Usage:  "verify a legacy or BIP322 message signature",
+		Flags:  messageFlags,
+		Action: verifyMessage,
+	},
+}
+
+func signMessage(_ *cli.Context) error {
+	if xpriv == "" {
+		log.Fatal("must provide 'xpriv' flag")
+	}
+
+	if path == "" {
+		path = "m"
+	}
+
+	key, err := hdkeys.Parse(xpriv)
+	if err != nil {
+		return err
+	}
+
+	child, err := key.ChildFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	if !child.IsPrivate {
+		log.Fatal("'xpriv' must be an extended private key")
+	}
+
+	priv, err := privatekey.FromBytes(child.Key)
+	if err != nil {
+		return err
+	}
+
+	a, err := keyAddress(priv, addrType)
+	if err != nil {
+		return err
+	}
+
+	var s string
+	switch sigFormat {
+	case "legacy":
+		s, err = message.SignLegacy(priv, a, msg)
+	case "simple":
+		s, err = message.SignSimple(priv, a, msg)
+	case "full":
+		s, err = message.SignFull(priv, a, msg)
+	default:
+		return fmt.Errorf("unknown signature format %q", sigFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Address:\t", a)
+	fmt.Println("Signature:\t", s)
+
+	return nil
+}
+
+// keyAddress returns the mainnet address of the given type for the key.
+func keyAddress(priv *privatekey.PrivateKey, typ string) (*address.Address,
+	error) {
+
+	pk := priv.PubKey
+
+	var (
+		s   script.Script
+		err error
+	)
+	switch typ {
+	case "p2pkh":
+		s, err = script.P2PKH(helpers.Hash160(pk.Sec(true)))
+	case "p2sh-p2wpkh":
+		var redeem script.Script
+		redeem, err = script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+		if err == nil {
+			s, err = script.P2SH(redeem)
+		}
+	case "p2wpkh":
+		s, err = script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+	case "p2tr":
+		var q *s256point.S256Point
+		q, err = taproot.ComputeOutputKey(pk, nil)
+		if err == nil {
+			s, err = script.P2TR(q.XOnly())
+		}
+	default:
+		return nil, fmt.Errorf("unknown address type %q", typ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return address.FromScript(s, address.MainNet)
+}
+
+func verifyMessage(_ *cli.Context) error {
+	if addr == "" || sig == "" {
+		log.Fatal("must provide 'address' and 'signature' flags")
+	}
+
+	a, err := address.Decode(addr)
+	if err != nil {
+		return err
+	}
+
+	if err := message.Verify(a, msg, sig); err != nil {
+		return err
+	}
+
+	fmt.Println("Signature is valid")
+
+	return nil
+}
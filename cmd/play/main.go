@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 
-	"github.com/btcsuite/btcutil/base58"
-	"github.com/ellemouton/btc/fieldelement"
+	"github.com/ellemouton/btc/address"
 	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/message"
 	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
 	"github.com/ellemouton/btc/signature"
 )
 
@@ -43,6 +46,7 @@ var inf = []info{
 
 /*
 These are all valid signatures in old Bitcoin Armory style using the message hash function sha256(sha256('Bitcoin Signed Message:\n' + message)).
+That hash leaves out the length prefixes of the standard signed message hash computed by message.LegacyHash, which is used below, so none of them verify as standard signed messages.
 */
 
 func pkToAddr(pk string) string {
@@ -51,13 +55,17 @@ func pkToAddr(pk string) string {
 		log.Fatal(err)
 	}
 
-	return base58.CheckEncode(helpers.Hash160(pkBytes), 0x0)
-}
+	s, err := script.P2PKH(helpers.Hash160(pkBytes))
+	if err != nil {
+		log.Fatal(err)
+	}
 
-var msgTmpl = "Bitcoin Signed Message:\n%s"
+	addr, err := address.FromScript(s, address.MainNet)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-func msgHash(msg string) []byte {
-	return helpers.DoubleSha256([]byte(fmt.Sprintf(msgTmpl, msg)))
+	return addr.String()
 }
 
 type parsed struct {
@@ -73,7 +81,7 @@ func parse(i info) (*parsed, error) {
 	if err != nil {
 		return nil, err
 	}
-	res.pk = &s256point.S256Point{p}
+	res.pk = p.(*s256point.S256Point)
 
 	sig, err := hex.DecodeString(i.sig)
 	if err != nil {
@@ -85,7 +93,7 @@ func parse(i info) (*parsed, error) {
 		S:  new(big.Int).SetBytes(sig[32:]),
 	}
 
-	res.z = msgHash(i.message)
+	res.z = message.LegacyHash(i.message)
 
 	return &res, nil
 }
@@ -105,28 +113,54 @@ func parseAll(il []info) ([]parsed, error) {
 	return res, nil
 }
 
+// nonceY returns the y coordinate of the signature's nonce point R by
+// finding the recovery id that recovers the signer's public key.
+func nonceY(p parsed) (*big.Int, error) {
+	for recID := byte(0); recID < 4; recID++ {
+		pk, err := s256point.RecoverPubKey(p.z, p.sig, recID)
+		if err != nil {
+			continue
+		}
+
+		if !bytes.Equal(pk.Sec(true), p.pk.Sec(true)) {
+			continue
+		}
+
+		R, err := s256point.RecoverNoncePoint(p.sig, recID)
+		if err != nil {
+			return nil, err
+		}
+
+		return R.GetY().GetNum(), nil
+	}
+
+	return nil, errors.New("no recovery id matches the public key")
+}
+
 func printStuff(p parsed) {
-	ry, err := getRy(p.pk, p.z, p.sig)
+	fmt.Println("--------------------------------------------")
+	ry, err := nonceY(p)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println("signature does not verify:", err)
+		fmt.Println()
+		return
 	}
-	fmt.Println("--------------------------------------------")
 	fmt.Println("z:\t", p.z)
 	fmt.Println("P_x:\t", p.pk.GetX().GetNum().Bytes())
 	fmt.Println("P_y:\t", p.pk.GetY().GetNum().Bytes())
 	fmt.Println("rx:\t", p.sig.Rx.Bytes())
-	fmt.Println("ry:\t", ry.GetNum().Bytes())
+	fmt.Println("ry:\t", ry.Bytes())
 	fmt.Println("s:\t", p.sig.S.Bytes())
 	fmt.Println()
 	fmt.Println("z:\t", new(big.Int).SetBytes(p.z))
 	fmt.Println("P_x:\t", p.pk.GetX().GetNum())
 	fmt.Println("P_y:\t", p.pk.GetY().GetNum())
 	fmt.Println("rx:\t", p.sig.Rx)
-	fmt.Println("ry:\t", ry.GetNum())
+	fmt.Println("ry:\t", ry)
 	fmt.Println("s:\t", p.sig.S)
 	fmt.Println()
 	fmt.Println("rx:\t", hex.EncodeToString(p.sig.Rx.Bytes()))
-	fmt.Println("ry:\t", hex.EncodeToString(ry.GetNum().Bytes()))
+	fmt.Println("ry:\t", hex.EncodeToString(ry.Bytes()))
 	fmt.Println("s:\t", hex.EncodeToString(p.sig.S.Bytes()))
 	fmt.Println()
 }
@@ -156,53 +190,3 @@ func main() {
 	fmt.Println(s256point.G.GetX().GetNum().Bytes())
 	fmt.Println(s256point.G.GetY().GetNum().Bytes())
 }
-
-func getRy(p *s256point.S256Point, hash []byte, sig *signature.Signature) (fieldelement.FieldElement, error) {
-	z := hashToInt(hash, s256point.N)
-
-	exp := &big.Int{}
-	exp.Sub(s256point.N, big.NewInt(2))
-
-	s_inv := &big.Int{}
-	s_inv.Exp(sig.S, exp, s256point.N)
-
-	u := &big.Int{}
-	u.Mul(z, s_inv)
-	u.Mod(u, s256point.N)
-
-	v := &big.Int{}
-	v.Mul(sig.Rx, s_inv)
-	v.Mod(v, s256point.N)
-
-	uG, err := s256point.G.Mul(u)
-	if err != nil {
-		return nil, err
-	}
-
-	vG, err := p.Mul(v)
-	if err != nil {
-		return nil, err
-	}
-
-	total, err := uG.Add(vG)
-	if err != nil {
-		return nil, err
-	}
-
-	return total.GetY(), nil
-}
-
-func hashToInt(hash []byte, n *big.Int) *big.Int {
-	orderBits := n.BitLen()
-	orderBytes := (orderBits + 7) / 8
-	if len(hash) > orderBytes {
-		hash = hash[:orderBytes]
-	}
-
-	ret := new(big.Int).SetBytes(hash)
-	excess := len(hash)*8 - orderBits
-	if excess > 0 {
-		ret.Rsh(ret, uint(excess))
-	}
-	return ret
-}
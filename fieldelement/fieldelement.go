@@ -12,6 +12,7 @@ type FieldElement interface {
 	Copy() FieldElement
 	String() string
 	Hex() string
+	Equal(FieldElement) bool
 	Add(FieldElement) (FieldElement, error)
 	Sub(FieldElement) (FieldElement, error)
 	Mul(FieldElement) (FieldElement, error)
@@ -61,6 +62,11 @@ func (e *Element) Hex() string {
 	return fmt.Sprintf("%064x", e.Num)
 }
 
+// Equal returns true if o is the same number in the same field.
+func (e *Element) Equal(o FieldElement) bool {
+	return e.Num.Cmp(o.GetNum()) == 0 && e.Prime.Cmp(o.GetPrime()) == 0
+}
+
 func (e *Element) Add(o FieldElement) (FieldElement, error) {
 	if e.Prime.Cmp(o.GetPrime()) != 0 {
 		return nil, errors.New("Cant add numbers of different fields")
@@ -29,6 +29,18 @@ func TestEqual(t *testing.T) {
 	require.Equal(t, f1, f2)
 	require.NotEqual(t, f1, f3)
 	require.NotEqual(t, f1, f4)
+
+	require.True(t, f1.Equal(f2))
+	require.False(t, f1.Equal(f3))
+	require.False(t, f1.Equal(f4))
+
+	// Equal compares values rather than representations.
+	z1, err := New(new(big.Int), big.NewInt(19))
+	require.NoError(t, err)
+
+	z2, err := f1.Sub(f1)
+	require.NoError(t, err)
+	require.True(t, z1.Equal(z2))
 }
 
 func TestCopy(t *testing.T) {
@@ -0,0 +1,250 @@
+package hdkeys
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+)
+
+// HardenedKeyStart is the index of the first hardened child. The index
+// written as i' in a path is HardenedKeyStart + i.
+const HardenedKeyStart = uint32(0x80000000)
+
+// Purpose is the first level of a BIP43 path, which determines the kind of
+// addresses that are derived below it.
+type Purpose uint32
+
+const (
+	// PurposeBIP44 derives P2PKH addresses.
+	PurposeBIP44 Purpose = 44
+
+	// PurposeBIP49 derives P2WPKH addresses nested in P2SH.
+	PurposeBIP49 Purpose = 49
+
+	// PurposeBIP84 derives native P2WPKH addresses.
+	PurposeBIP84 Purpose = 84
+
+	// PurposeBIP86 derives P2TR addresses that can only be spent with the
+	// key path.
+	PurposeBIP86 Purpose = 86
+)
+
+// ScriptType returns the type of the scripts derived for the purpose. Nested
+// segwit is reported as script.TypeP2SH.
+func (p Purpose) ScriptType() (script.Type, error) {
+	switch p {
+	case PurposeBIP44:
+		return script.TypeP2PKH, nil
+	case PurposeBIP49:
+		return script.TypeP2SH, nil
+	case PurposeBIP84:
+		return script.TypeP2WPKH, nil
+	case PurposeBIP86:
+		return script.TypeP2TR, nil
+	}
+
+	return 0, fmt.Errorf("unknown purpose %d", p)
+}
+
+// pkScript returns the script that pays to pk for the purpose.
+func (p Purpose) pkScript(pk *s256point.S256Point) (script.Script, error) {
+	switch p {
+	case PurposeBIP44:
+		return script.P2PKH(helpers.Hash160(pk.Sec(true)))
+
+	case PurposeBIP49:
+		redeem, err := script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+		if err != nil {
+			return nil, err
+		}
+
+		return script.P2SH(redeem)
+
+	case PurposeBIP84:
+		return script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+
+	case PurposeBIP86:
+		q, err := taproot.ComputeOutputKey(pk, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return script.P2TR(q.XOnly())
+	}
+
+	return nil, fmt.Errorf("unknown purpose %d", p)
+}
+
+// CoinType is the second level of a BIP44 path, which is registered in
+// SLIP-44. All test networks share a coin type.
+type CoinType uint32
+
+const (
+	CoinTypeBitcoin CoinType = 0
+	CoinTypeTestnet CoinType = 1
+)
+
+// Network returns the network that addresses are encoded for.
+func (c CoinType) Network() *address.Network {
+	if c == CoinTypeBitcoin {
+		return address.MainNet
+	}
+
+	return address.TestNet
+}
+
+// Chain is the fourth level of a BIP44 path, which separates the addresses
+// that are given out for receiving payments from those used for change.
+type Chain uint32
+
+const (
+	ExternalChain Chain = 0
+	InternalChain Chain = 1
+)
+
+// AccountPath returns the path m/purpose'/coin_type'/account'.
+func AccountPath(purpose Purpose, coin CoinType, account uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'", purpose, coin, account)
+}
+
+// Account is the key of a BIP44 style account, from which the receive and
+// change addresses are derived.
+type Account struct {
+	// Key is the key at m/purpose'/coin_type'/account'. It may be a
+	// public key, in which case only public keys are derived from it.
+	Key *ExtendedKey
+
+	Purpose  Purpose
+	CoinType CoinType
+
+	// Number is the account number in the path. It is kept here rather
+	// than read from the key's child index, which isn't known for keys
+	// that were imported without their path.
+	Number uint32
+}
+
+// Account derives the account key m/purpose'/coin_type'/account' from a
+// master private key. The version of the account key is set to the SLIP-132
+// version of the purpose and coin type.
+func (ext *ExtendedKey) Account(purpose Purpose, coin CoinType,
+	account uint32) (*Account, error) {
+
+	if !ext.IsPrivate {
+		return nil, errors.New("account keys can only be derived from " +
+			"a private key")
+	}
+
+	if ext.Depth != 0 {
+		return nil, errors.New("account keys must be derived from the " +
+			"master key")
+	}
+
+	if _, err := purpose.ScriptType(); err != nil {
+		return nil, err
+	}
+
+	if uint32(coin) >= HardenedKeyStart || account >= HardenedKeyStart {
+		return nil, errors.New("coin type and account must be less " +
+			"than 2^31")
+	}
+
+	key := ext
+	for _, i := range []uint32{uint32(purpose), uint32(coin), account} {
+		var err error
+		key, err = key.Child(HardenedKeyStart + i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The account key is encoded the way wallets export it, such as a
+	// zprv for a BIP84 account on mainnet.
+	return &Account{
+		Key:      key.WithVersion(versionFor(purpose, coin)),
+		Purpose:  purpose,
+		CoinType: coin,
+		Number:   account,
+	}, nil
+}
+
+// DerivedAddress is a key derived from an account together with the address
+// that it controls.
+type DerivedAddress struct {
+	// Path is the full derivation path of the key.
+	Path string
+
+	// Key is private if the account key is.
+	Key *ExtendedKey
+
+	Address *address.Address
+	Script  script.Script
+}
+
+// Receive derives the receive address at the given index, at
+// m/purpose'/coin_type'/account'/0/index.
+func (a *Account) Receive(index uint32) (*DerivedAddress, error) {
+	return a.Derive(ExternalChain, index)
+}
+
+// Change derives the change address at the given index, at
+// m/purpose'/coin_type'/account'/1/index.
+func (a *Account) Change(index uint32) (*DerivedAddress, error) {
+	return a.Derive(InternalChain, index)
+}
+
+// Derive derives the key and address at the given chain and index below the
+// account.
+func (a *Account) Derive(chain Chain, index uint32) (*DerivedAddress,
+	error) {
+
+	if index >= HardenedKeyStart {
+		return nil, errors.New("address index must be less than 2^31")
+	}
+
+	chainKey, err := a.Key.Child(uint32(chain))
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := chainKey.Child(index)
+	if err != nil {
+		return nil, err
+	}
+
+	pub := key
+	if key.IsPrivate {
+		pub, err = key.ExtendedPubKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pk, err := s256point.Parse(pub.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	pkScript, err := a.Purpose.pkScript(pk.(*s256point.S256Point))
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := address.FromScript(pkScript, a.CoinType.Network())
+	if err != nil {
+		return nil, err
+	}
+
+	return &DerivedAddress{
+		Path: fmt.Sprintf("%s/%d/%d", AccountPath(
+			a.Purpose, a.CoinType, a.Number,
+		), chain, index),
+		Key:     key,
+		Address: addr,
+		Script:  pkScript,
+	}, nil
+}
@@ -0,0 +1,167 @@
+package hdkeys
+
+import (
+	"testing"
+
+	"github.com/ellemouton/btc/bip39"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountAddresses(t *testing.T) {
+	// The test vectors of BIP44, BIP49, BIP84 and BIP86 all use this
+	// mnemonic without a passphrase.
+	seed := bip39.NewSeed("abandon abandon abandon abandon abandon "+
+		"abandon abandon abandon abandon abandon abandon about", "")
+
+	master, err := ExtendedPrivKeyFromSeed(seed)
+	require.NoError(t, err)
+
+	tests := []struct {
+		purpose Purpose
+		coin    CoinType
+		chain   Chain
+		index   uint32
+		path    string
+		address string
+	}{
+		{
+			purpose: PurposeBIP44,
+			coin:    CoinTypeBitcoin,
+			path:    "m/44'/0'/0'/0/0",
+			address: "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA",
+		},
+		{
+			purpose: PurposeBIP49,
+			coin:    CoinTypeTestnet,
+			path:    "m/49'/1'/0'/0/0",
+			address: "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2",
+		},
+		{
+			purpose: PurposeBIP84,
+			coin:    CoinTypeBitcoin,
+			path:    "m/84'/0'/0'/0/0",
+			address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+		},
+		{
+			purpose: PurposeBIP84,
+			coin:    CoinTypeBitcoin,
+			index:   1,
+			path:    "m/84'/0'/0'/0/1",
+			address: "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g",
+		},
+		{
+			purpose: PurposeBIP84,
+			coin:    CoinTypeBitcoin,
+			chain:   InternalChain,
+			path:    "m/84'/0'/0'/1/0",
+			address: "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el",
+		},
+		{
+			purpose: PurposeBIP86,
+			coin:    CoinTypeBitcoin,
+			path:    "m/86'/0'/0'/0/0",
+			address: "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+		},
+		{
+			purpose: PurposeBIP86,
+			coin:    CoinTypeBitcoin,
+			index:   1,
+			path:    "m/86'/0'/0'/0/1",
+			address: "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh",
+		},
+		{
+			purpose: PurposeBIP86,
+			coin:    CoinTypeBitcoin,
+			chain:   InternalChain,
+			path:    "m/86'/0'/0'/1/0",
+			address: "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			account, err := master.Account(test.purpose, test.coin, 0)
+			require.NoError(t, err)
+
+			derived, err := account.Derive(test.chain, test.index)
+			require.NoError(t, err)
+			require.Equal(t, test.path, derived.Path)
+			require.Equal(t, test.address, derived.Address.String())
+			require.True(t, derived.Key.IsPrivate)
+
+			// The key matches the one derived from the path.
+			key, err := master.ChildFromPath(test.path)
+			require.NoError(t, err)
+			require.Equal(t, key.Key, derived.Key.Key)
+
+			addrScript, err := derived.Address.Script()
+			require.NoError(t, err)
+			require.Equal(t, addrScript, derived.Script)
+
+			// Watch-only accounts derive the same addresses.
+			pub, err := account.Key.ExtendedPubKey()
+			require.NoError(t, err)
+
+			watchOnly := &Account{
+				Key:      pub,
+				Purpose:  test.purpose,
+				CoinType: test.coin,
+			}
+			derived, err = watchOnly.Derive(test.chain, test.index)
+			require.NoError(t, err)
+			require.Equal(t, test.address, derived.Address.String())
+			require.False(t, derived.Key.IsPrivate)
+		})
+	}
+
+	account, err := master.Account(PurposeBIP84, CoinTypeBitcoin, 0)
+	require.NoError(t, err)
+
+	receive, err := account.Receive(1)
+	require.NoError(t, err)
+	require.Equal(t, "m/84'/0'/0'/0/1", receive.Path)
+
+	change, err := account.Change(0)
+	require.NoError(t, err)
+	require.Equal(t, "m/84'/0'/0'/1/0", change.Path)
+
+	_, err = account.Receive(HardenedKeyStart)
+	require.Error(t, err)
+
+	// The path uses the account number, also for a watch-only account
+	// whose key doesn't record the index it was derived at.
+	account, err = master.Account(PurposeBIP84, CoinTypeTestnet, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), account.Number)
+
+	receive, err = account.Receive(2)
+	require.NoError(t, err)
+	require.Equal(t, "m/84'/1'/3'/0/2", receive.Path)
+
+	pub, err := account.Key.ExtendedPubKey()
+	require.NoError(t, err)
+	pub.Index = 0
+
+	watchOnly := &Account{
+		Key:      pub,
+		Purpose:  PurposeBIP84,
+		CoinType: CoinTypeTestnet,
+		Number:   3,
+	}
+	derived, err := watchOnly.Receive(2)
+	require.NoError(t, err)
+	require.Equal(t, receive.Path, derived.Path)
+	require.Equal(t, receive.Address, derived.Address)
+
+	_, err = master.Account(Purpose(45), CoinTypeBitcoin, 0)
+	require.Error(t, err)
+
+	_, err = master.Account(PurposeBIP84, CoinTypeBitcoin, HardenedKeyStart)
+	require.Error(t, err)
+
+	_, err = account.Key.Account(PurposeBIP84, CoinTypeBitcoin, 0)
+	require.Error(t, err)
+
+	require.Equal(t, "m/86'/1'/2'", AccountPath(PurposeBIP86,
+		CoinTypeTestnet, 2))
+}
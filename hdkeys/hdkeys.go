@@ -3,31 +3,24 @@ package hdkeys
 import (
 	"bytes"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha512"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"math/big"
-	"math/rand"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/ellemouton/btc/helpers"
 	"github.com/ellemouton/btc/privatekey"
 	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/secp256k1"
 )
 
 const (
 	seedSize = 64
 )
 
-var (
-	XprivVersion, _ = hex.DecodeString("0488ade4")
-	XpubVersion, _  = hex.DecodeString("0488b21e")
-)
-
 func ExtendedPrivKeyFromSeed(s []byte) (*ExtendedKey, error) {
 	hmac := hmac.New(sha512.New, []byte("Bitcoin seed"))
 	_, err := hmac.Write(s)
@@ -45,7 +38,7 @@ func ExtendedPrivKeyFromSeed(s []byte) (*ExtendedKey, error) {
 	}
 
 	return &ExtendedKey{
-		Version:     XprivVersion,
+		Version:     append([]byte(nil), XprivVersion...),
 		Key:         key,
 		ChainCode:   chaincode,
 		Depth:       0,
@@ -60,39 +53,45 @@ func (priv *ExtendedKey) ExtendedPubKey() (*ExtendedKey, error) {
 		return nil, errors.New("key is already a public key")
 	}
 
-	privKey, err := privatekey.New(new(big.Int).SetBytes(priv.Key))
+	privKey, err := privatekey.FromBytes(priv.Key)
 	if err != nil {
 		return nil, err
 	}
 
 	pubKey := privKey.PubKey.Sec(true)
 
+	// The public key keeps the network and script type of the private key.
+	version, err := priv.KeyVersion()
+	if err != nil {
+		return nil, err
+	}
+
 	return &ExtendedKey{
-		Version:     XpubVersion,
+		Version:     append([]byte(nil), version.Public...),
 		Key:         pubKey,
-		ChainCode:   priv.ChainCode,
+		ChainCode:   append([]byte(nil), priv.ChainCode...),
 		Depth:       priv.Depth,
-		FingerPrint: priv.FingerPrint,
+		FingerPrint: append([]byte(nil), priv.FingerPrint...),
 		Index:       priv.Index,
 		IsPrivate:   false,
 	}, nil
 }
 
 func (ext *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
-	if !ext.IsPrivate && i >= uint32(0x80000000) {
+	if !ext.IsPrivate && i >= HardenedKeyStart {
 		return nil, errors.New("cant derive a hardened child from a public key")
 	}
 
 	index := uint32Bytes(i)
 
 	var data []byte
-	if i >= uint32(0x80000000) {
+	if i >= HardenedKeyStart {
 		// hardened. so private key
 		data = append([]byte{0x0}, ext.Key...)
 	} else {
 		// normal. so we add the pub key bytes
 		if ext.IsPrivate {
-			privKey, err := privatekey.New(new(big.Int).SetBytes(ext.Key))
+			privKey, err := privatekey.FromBytes(ext.Key)
 			if err != nil {
 				return nil, err
 			}
@@ -111,6 +110,7 @@ func (ext *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
 	constant := hmac.Sum(nil)
 
 	child := &ExtendedKey{
+		Version:   append([]byte(nil), ext.Version...),
 		Depth:     ext.Depth + 1,
 		ChainCode: constant[32:],
 		IsPrivate: ext.IsPrivate,
@@ -118,42 +118,43 @@ func (ext *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
 	}
 
 	if ext.IsPrivate {
-		child.Version = XprivVersion
-		child.Key = addPrivKeys(constant[:32], ext.Key)
+		child.Key, err = addPrivKeys(constant[:32], ext.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: child %d", err, i)
+		}
 
-		privKey, err := privatekey.New(new(big.Int).SetBytes(ext.Key))
+		privKey, err := privatekey.FromBytes(ext.Key)
 		if err != nil {
 			return nil, err
 		}
 		child.FingerPrint = helpers.Hash160(privKey.PubKey.Sec(true))[:4]
 
 	} else {
-		child.Version = XpubVersion
 		child.FingerPrint = helpers.Hash160(ext.Key)[:4]
 
-		privKey, err := privatekey.New(new(big.Int).SetBytes(constant[:32]))
+		// IL must be a valid key, in which case the child key is
+		// IL*G plus the parent key.
+		il, err := privatekey.FromBytes(constant[:32])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: child %d", ErrInvalidChild, i)
 		}
 
-		pubKey := privKey.PubKey.Sec(true)
-
-		p1, err := s256point.Parse(pubKey)
+		parent, err := s256point.Parse(ext.Key)
 		if err != nil {
 			return nil, err
 		}
 
-		p2, err := s256point.Parse(ext.Key)
+		p, err := il.PubKey.Add(parent)
 		if err != nil {
 			return nil, err
 		}
 
-		p3, err := p1.Add(p2)
-		if err != nil {
-			return nil, err
+		pubKey := p.(*s256point.S256Point)
+		if pubKey.IsInfinity() {
+			return nil, fmt.Errorf("%w: child %d", ErrInvalidChild, i)
 		}
 
-		child.Key = (&s256point.S256Point{p3}).Sec(true)
+		child.Key = pubKey.Sec(true)
 	}
 
 	return child, nil
@@ -180,21 +181,22 @@ func (ext *ExtendedKey) ChildFromPath(path string) (*ExtendedKey, error) {
 	return key, nil
 }
 
-func addPrivKeys(key1 []byte, key2 []byte) []byte {
-	var key1Int big.Int
-	var key2Int big.Int
-	key1Int.SetBytes(key1)
-	key2Int.SetBytes(key2)
-
-	key1Int.Add(&key1Int, &key2Int)
-	key1Int.Mod(&key1Int, s256point.N)
+// addPrivKeys returns the child key IL + kpar mod n using constant-time
+// arithmetic since both are secret. BIP32 treats the child as invalid if IL
+// is not less than n or the sum is zero.
+func addPrivKeys(il []byte, kpar []byte) ([]byte, error) {
+	var k1, k2 secp256k1.Scalar
+	if k1.SetByteSlice(il) {
+		return nil, ErrInvalidChild
+	}
+	k2.SetByteSlice(kpar)
 
-	b := key1Int.Bytes()
-	if len(b) < 32 {
-		extra := make([]byte, 32-len(b))
-		b = append(extra, b...)
+	if k1.Add(&k1, &k2).IsZero() {
+		return nil, ErrInvalidChild
 	}
-	return b
+
+	b := k1.Bytes()
+	return b[:], nil
 }
 
 func uint32Bytes(i uint32) []byte {
@@ -207,14 +209,14 @@ func validatePrivateKey(key []byte) error {
 	if fmt.Sprintf("%x", key) == "0000000000000000000000000000000000000000000000000000000000000000" || //if the key is zero
 		bytes.Compare(key, s256point.N.Bytes()) >= 0 || //or is outside of the curve
 		len(key) != 32 { //or is too short
-		return errors.New("invalid priv key bytes")
+		return fmt.Errorf("%w: not in [1, N-1]", ErrInvalidPrivateKey)
 	}
 
 	return nil
 }
 
+// NewSeed returns a random seed read from crypto/rand.
 func NewSeed() ([]byte, error) {
-	rand.Seed(time.Now().UnixNano())
 	token := make([]byte, seedSize)
 	_, err := rand.Read(token)
 	return token, err
@@ -241,7 +243,7 @@ func getPath(path string) ([]uint32, error) {
 			if err != nil {
 				return nil, err
 			}
-			i = uint32(1<<31 + num)
+			i = HardenedKeyStart + uint32(num)
 		} else {
 			// normal child
 			num, err := strconv.Atoi(v)
@@ -96,4 +96,19 @@ func TestChildDerivation(t *testing.T) {
 		require.NoError(t,err)
 		require.Equal(t, test.expectChildSer, base58.Encode(c.Serialize()))
 	}
-}
\ No newline at end of file
+}
+
+func TestExtendedPubKeyCopies(t *testing.T) {
+	master, err := ExtendedPrivKeyFromSeed(make([]byte, seedSize))
+	require.NoError(t, err)
+
+	pub, err := master.ExtendedPubKey()
+	require.NoError(t, err)
+
+	// Changing the public key leaves the private key as it was.
+	chainCode := append([]byte(nil), master.ChainCode...)
+	pub.ChainCode[0] ^= 0xff
+	pub.FingerPrint[0] ^= 0xff
+	require.Equal(t, chainCode, master.ChainCode)
+	require.Equal(t, []byte{0, 0, 0, 0}, master.FingerPrint)
+}
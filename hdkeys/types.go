@@ -1,10 +1,48 @@
 package hdkeys
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+)
+
+// serializedKeyLen is the length of a serialized extended key without its
+// checksum.
+const serializedKeyLen = 78
+
+var (
+	// ErrInvalidLength is returned when a decoded extended key is not 82
+	// bytes long.
+	ErrInvalidLength = errors.New("extended key must be 82 bytes")
+
+	// ErrInvalidChecksum is returned when the checksum of an encoded
+	// extended key doesn't match its payload.
+	ErrInvalidChecksum = errors.New("invalid extended key checksum")
+
+	// ErrVersionMismatch is returned when a private version is used with a
+	// public key or the other way around.
+	ErrVersionMismatch = errors.New("version does not match the key type")
+
+	// ErrInvalidPrivateKey is returned when a private key is not in
+	// [1, N-1] or has an invalid prefix.
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+
+	// ErrInvalidPublicKey is returned when a public key is not a valid
+	// compressed point on the curve.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+
+	// ErrInvalidMasterKey is returned when a key at depth zero has a
+	// parent fingerprint or an index.
+	ErrInvalidMasterKey = errors.New("invalid master key")
+
+	// ErrInvalidChild is returned in the rare case that BIP32 gives no
+	// valid key for a child index. The next index should be used instead.
+	ErrInvalidChild = errors.New("invalid child key")
 )
 
 type ExtendedKey struct {
@@ -23,7 +61,7 @@ func (ext *ExtendedKey) Clone() (*ExtendedKey, error) {
 }
 
 func (ext *ExtendedKey) Serialize() []byte {
-	result := make([]byte, 78)
+	result := make([]byte, serializedKeyLen)
 
 	copy(result[:4], ext.Version)
 	copy(result[4:5], []byte{byte(ext.Depth)})
@@ -39,30 +77,81 @@ func (ext *ExtendedKey) Serialize() []byte {
 	return append(result, helpers.DoubleSha256(result)[:4]...)
 }
 
+// Parse decodes a base58 encoded extended key. It checks the checksum, that
+// the version is registered and matches the type of key, that the key is a
+// valid private key or a public key on the curve, and that a key at depth
+// zero has no parent fingerprint or index. The returned key does not share
+// memory with any other key.
 func Parse(s string) (*ExtendedKey, error) {
 	b := base58.Decode(s)
-	if len(b) != 82 {
-		return nil, errors.New("incorrect length")
+	if len(b) != serializedKeyLen+4 {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidLength,
+			len(b))
 	}
 
-	depth, _ := binary.Varint(b[4:5])
-	index, _ := binary.Varint(b[9:13])
+	payload, checksum := b[:serializedKeyLen], b[serializedKeyLen:]
+	if !bytes.Equal(helpers.DoubleSha256(payload)[:4], checksum) {
+		return nil, ErrInvalidChecksum
+	}
 
-	key := b[45:78]
-	isPriv := key[0] == byte(0x0)
+	version := payload[:4]
+	_, isPriv, err := LookupVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := int64(payload[4])
+	fingerPrint := payload[5:9]
+	index := binary.BigEndian.Uint32(payload[9:13])
+	key := payload[45:78]
 
-	if isPriv {
+	if depth == 0 {
+		if !bytes.Equal(fingerPrint, []byte{0, 0, 0, 0}) {
+			return nil, fmt.Errorf("%w: non-zero parent fingerprint",
+				ErrInvalidMasterKey)
+		}
+		if index != 0 {
+			return nil, fmt.Errorf("%w: non-zero index",
+				ErrInvalidMasterKey)
+		}
+	}
+
+	switch {
+	case isPriv && key[0] == 0x00:
 		key = key[1:]
+		if err := validatePrivateKey(key); err != nil {
+			return nil, err
+		}
+
+	case !isPriv && (key[0] == 0x02 || key[0] == 0x03):
+		if _, err := s256point.Parse(key); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+
+	case isPriv && (key[0] == 0x02 || key[0] == 0x03):
+		return nil, fmt.Errorf("%w: private version %x with a public "+
+			"key", ErrVersionMismatch, version)
+
+	case !isPriv && key[0] == 0x00:
+		return nil, fmt.Errorf("%w: public version %x with a private "+
+			"key", ErrVersionMismatch, version)
+
+	case isPriv:
+		return nil, fmt.Errorf("%w: invalid prefix %02x",
+			ErrInvalidPrivateKey, key[0])
+
+	default:
+		return nil, fmt.Errorf("%w: invalid prefix %02x",
+			ErrInvalidPublicKey, key[0])
 	}
 
 	return &ExtendedKey{
-		Version:     b[:4],
+		Version:     append([]byte(nil), version...),
 		Depth:       depth,
-		FingerPrint: b[5:9],
-		Index:       index,
-		ChainCode:   b[13:45],
-		Key:         key,
+		FingerPrint: append([]byte(nil), fingerPrint...),
+		Index:       int64(index),
+		ChainCode:   append([]byte(nil), payload[13:45]...),
+		Key:         append([]byte(nil), key...),
 		IsPrivate:   isPriv,
 	}, nil
 }
-
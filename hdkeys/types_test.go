@@ -0,0 +1,130 @@
+package hdkeys
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvalid(t *testing.T) {
+	// These are the invalid keys of BIP32 test vector 5.
+	tests := []struct {
+		name string
+		key  string
+		err  error
+	}{
+		{
+			name: "pubkey version / prvkey mismatch",
+			key:  "xpub661MyMwAqRbcEYS8w7XLSVeEsBXy79zSzH1J8vCdxAZningWLdN3zgtU6LBpB85b3D2yc8sfvZU521AAwdZafEz7mnzBBsz4wKY5fTtTQBm",
+			err:  ErrVersionMismatch,
+		},
+		{
+			name: "prvkey version / pubkey mismatch",
+			key:  "xprv9s21ZrQH143K24Mfq5zL5MhWK9hUhhGbd45hLXo2Pq2oqzMMo63oStZzFGTQQD3dC4H2D5GBj7vWvSQaaBv5cxi9gafk7NF3pnBju6dwKvH",
+			err:  ErrVersionMismatch,
+		},
+		{
+			name: "invalid pubkey prefix 04",
+			key:  "xpub661MyMwAqRbcEYS8w7XLSVeEsBXy79zSzH1J8vCdxAZningWLdN3zgtU6Txnt3siSujt9RCVYsx4qHZGc62TG4McvMGcAUjeuwZdduYEvFn",
+			err:  ErrInvalidPublicKey,
+		},
+		{
+			name: "invalid prvkey prefix 04",
+			key:  "xprv9s21ZrQH143K24Mfq5zL5MhWK9hUhhGbd45hLXo2Pq2oqzMMo63oStZzFGpWnsj83BHtEy5Zt8CcDr1UiRXuWCmTQLxEK9vbz5gPstX92JQ",
+			err:  ErrInvalidPrivateKey,
+		},
+		{
+			name: "invalid pubkey prefix 01",
+			key:  "xpub661MyMwAqRbcEYS8w7XLSVeEsBXy79zSzH1J8vCdxAZningWLdN3zgtU6N8ZMMXctdiCjxTNq964yKkwrkBJJwpzZS4HS2fxvyYUA4q2Xe4",
+			err:  ErrInvalidPublicKey,
+		},
+		{
+			name: "invalid prvkey prefix 01",
+			key:  "xprv9s21ZrQH143K24Mfq5zL5MhWK9hUhhGbd45hLXo2Pq2oqzMMo63oStZzFAzHGBP2UuGCqWLTAPLcMtD9y5gkZ6Eq3Rjuahrv17fEQ3Qen6J",
+			err:  ErrInvalidPrivateKey,
+		},
+		{
+			name: "zero depth with non-zero parent fingerprint",
+			key:  "xprv9s2SPatNQ9Vc6GTbVMFPFo7jsaZySyzk7L8n2uqKXJen3KUmvQNTuLh3fhZMBoG3G4ZW1N2kZuHEPY53qmbZzCHshoQnNf4GvELZfqTUrcv",
+			err:  ErrInvalidMasterKey,
+		},
+		{
+			name: "zero depth with non-zero parent fingerprint",
+			key:  "xpub661no6RGEX3uJkY4bNnPcw4URcQTrSibUZ4NqJEw5eBkv7ovTwgiT91XX27VbEXGENhYRCf7hyEbWrR3FewATdCEebj6znwMfQkhRYHRLpJ",
+			err:  ErrInvalidMasterKey,
+		},
+		{
+			name: "zero depth with non-zero index",
+			key:  "xprv9s21ZrQH4r4TsiLvyLXqM9P7k1K3EYhA1kkD6xuquB5i39AU8KF42acDyL3qsDbU9NmZn6MsGSUYZEsuoePmjzsB3eFKSUEh3Gu1N3cqVUN",
+			err:  ErrInvalidMasterKey,
+		},
+		{
+			name: "zero depth with non-zero index",
+			key:  "xpub661MyMwAuDcm6CRQ5N4qiHKrJ39Xe1R1NyfouMKTTWcguwVcfrZJaNvhpebzGerh7gucBvzEQWRugZDuDXjNDRmXzSZe4c7mnTK97pTvGS8",
+			err:  ErrInvalidMasterKey,
+		},
+		{
+			name: "unknown extended key version",
+			key:  "DMwo58pR1QLEFihHiXPVykYB6fJmsTeHvyTp7hRThAtCX8CvYzgPcn8XnmdfHGMQzT7ayAmfo4z3gY5KfbrZWZ6St24UVf2Qgo6oujFktLHdHY4",
+			err:  ErrUnknownVersion,
+		},
+		{
+			name: "unknown extended key version",
+			key:  "DMwo58pR1QLEFihHiXPVykYB6fJmsTeHvyTp7hRThAtCX8CvYzgPcn8XnmdfHPmHJiEDXkTiJTVV9rHEBUem2mwVbbNfvT2MTcAqj3nesx8uBf9",
+			err:  ErrUnknownVersion,
+		},
+		{
+			name: "private key 0 not in 1..n-1",
+			key:  "xprv9s21ZrQH143K24Mfq5zL5MhWK9hUhhGbd45hLXo2Pq2oqzMMo63oStZzF93Y5wvzdUayhgkkFoicQZcP3y52uPPxFnfoLZB21Teqt1VvEHx",
+			err:  ErrInvalidPrivateKey,
+		},
+		{
+			name: "private key n not in 1..n-1",
+			key:  "xprv9s21ZrQH143K24Mfq5zL5MhWK9hUhhGbd45hLXo2Pq2oqzMMo63oStZzFAzHGBP2UuGCqWLTAPLcMtD5SDKr24z3aiUvKr9bJpdrcLg1y3G",
+			err:  ErrInvalidPrivateKey,
+		},
+		{
+			name: "invalid pubkey 020000000000000000000000000000000000000000000000000000000000000007",
+			key:  "xpub661MyMwAqRbcEYS8w7XLSVeEsBXy79zSzH1J8vCdxAZningWLdN3zgtU6Q5JXayek4PRsn35jii4veMimro1xefsM58PgBMrvdYre8QyULY",
+			err:  ErrInvalidPublicKey,
+		},
+		{
+			name: "invalid checksum",
+			key:  "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHL",
+			err:  ErrInvalidChecksum,
+		},
+		{
+			name: "invalid length",
+			key:  "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jP",
+			err:  ErrInvalidLength,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.key)
+		require.Error(t, err, test.name)
+		require.True(t, errors.Is(err, test.err), "%s: %v", test.name,
+			err)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	// A key at depth 3 of BIP32 test vector 1, m/0'/1/2'.
+	s := "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM"
+
+	k, err := Parse(s)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), k.Depth)
+	require.Equal(t, int64(HardenedKeyStart+2), k.Index)
+	require.True(t, k.IsPrivate)
+	require.Len(t, k.Key, 32)
+	require.Equal(t, s, base58.Encode(k.Serialize()))
+
+	// Changing the parsed key leaves a second parse untouched.
+	k.Key[0] ^= 0xff
+	k2, err := Parse(s)
+	require.NoError(t, err)
+	require.NotEqual(t, k.Key, k2.Key)
+}
@@ -0,0 +1,226 @@
+package hdkeys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/script"
+)
+
+// ErrUnknownVersion is returned when the version bytes of an extended key are
+// not in the registry.
+var ErrUnknownVersion = errors.New("unknown extended key version")
+
+// KeyVersion is a pair of version bytes registered in SLIP-132. The version
+// bytes are the first four bytes of a serialized extended key and set the
+// prefix of its base58 encoding, such as xpub or zprv. They tell wallets which
+// network and kind of scripts the key is used for.
+type KeyVersion struct {
+	// Name is the prefix of the encoded public key, such as "zpub".
+	Name string
+
+	// Private and Public are the version bytes of private and public keys.
+	Private []byte
+	Public  []byte
+
+	Network *address.Network
+
+	// ScriptType is the type of the scripts that the key pays to. Nested
+	// segwit is reported as script.TypeP2SH. The xpub and tpub versions
+	// are also used for P2SH and P2TR.
+	ScriptType script.Type
+
+	// Multisig is set for the versions used by multisig wallets.
+	Multisig bool
+}
+
+var (
+	// Xpub is used for P2PKH on mainnet.
+	Xpub = &KeyVersion{
+		Name:       "xpub",
+		Private:    []byte{0x04, 0x88, 0xad, 0xe4},
+		Public:     []byte{0x04, 0x88, 0xb2, 0x1e},
+		Network:    address.MainNet,
+		ScriptType: script.TypeP2PKH,
+	}
+
+	// Ypub is used for P2WPKH nested in P2SH on mainnet.
+	Ypub = &KeyVersion{
+		Name:       "ypub",
+		Private:    []byte{0x04, 0x9d, 0x78, 0x78},
+		Public:     []byte{0x04, 0x9d, 0x7c, 0xb2},
+		Network:    address.MainNet,
+		ScriptType: script.TypeP2SH,
+	}
+
+	// YpubMultisig is used for multisig P2WSH nested in P2SH on mainnet.
+	YpubMultisig = &KeyVersion{
+		Name:       "Ypub",
+		Private:    []byte{0x02, 0x95, 0xb0, 0x05},
+		Public:     []byte{0x02, 0x95, 0xb4, 0x3f},
+		Network:    address.MainNet,
+		ScriptType: script.TypeP2SH,
+		Multisig:   true,
+	}
+
+	// Zpub is used for P2WPKH on mainnet.
+	Zpub = &KeyVersion{
+		Name:       "zpub",
+		Private:    []byte{0x04, 0xb2, 0x43, 0x0c},
+		Public:     []byte{0x04, 0xb2, 0x47, 0x46},
+		Network:    address.MainNet,
+		ScriptType: script.TypeP2WPKH,
+	}
+
+	// ZpubMultisig is used for multisig P2WSH on mainnet.
+	ZpubMultisig = &KeyVersion{
+		Name:       "Zpub",
+		Private:    []byte{0x02, 0xaa, 0x7a, 0x99},
+		Public:     []byte{0x02, 0xaa, 0x7e, 0xd3},
+		Network:    address.MainNet,
+		ScriptType: script.TypeP2WSH,
+		Multisig:   true,
+	}
+
+	// Tpub is used for P2PKH on the test networks.
+	Tpub = &KeyVersion{
+		Name:       "tpub",
+		Private:    []byte{0x04, 0x35, 0x83, 0x94},
+		Public:     []byte{0x04, 0x35, 0x87, 0xcf},
+		Network:    address.TestNet,
+		ScriptType: script.TypeP2PKH,
+	}
+
+	// Upub is used for P2WPKH nested in P2SH on the test networks.
+	Upub = &KeyVersion{
+		Name:       "upub",
+		Private:    []byte{0x04, 0x4a, 0x4e, 0x28},
+		Public:     []byte{0x04, 0x4a, 0x52, 0x62},
+		Network:    address.TestNet,
+		ScriptType: script.TypeP2SH,
+	}
+
+	// UpubMultisig is used for multisig P2WSH nested in P2SH on the test
+	// networks.
+	UpubMultisig = &KeyVersion{
+		Name:       "Upub",
+		Private:    []byte{0x02, 0x42, 0x85, 0xb5},
+		Public:     []byte{0x02, 0x42, 0x89, 0xef},
+		Network:    address.TestNet,
+		ScriptType: script.TypeP2SH,
+		Multisig:   true,
+	}
+
+	// Vpub is used for P2WPKH on the test networks.
+	Vpub = &KeyVersion{
+		Name:       "vpub",
+		Private:    []byte{0x04, 0x5f, 0x18, 0xbc},
+		Public:     []byte{0x04, 0x5f, 0x1c, 0xf6},
+		Network:    address.TestNet,
+		ScriptType: script.TypeP2WPKH,
+	}
+
+	// VpubMultisig is used for multisig P2WSH on the test networks.
+	VpubMultisig = &KeyVersion{
+		Name:       "Vpub",
+		Private:    []byte{0x02, 0x57, 0x50, 0x48},
+		Public:     []byte{0x02, 0x57, 0x54, 0x83},
+		Network:    address.TestNet,
+		ScriptType: script.TypeP2WSH,
+		Multisig:   true,
+	}
+
+	// KeyVersions are all of the known versions.
+	KeyVersions = []*KeyVersion{
+		Xpub, Ypub, YpubMultisig, Zpub, ZpubMultisig, Tpub, Upub,
+		UpubMultisig, Vpub, VpubMultisig,
+	}
+
+	// XprivVersion and XpubVersion are the version bytes of Xpub. They
+	// are copies so that changing them doesn't change the registry.
+	XprivVersion = append([]byte(nil), Xpub.Private...)
+	XpubVersion  = append([]byte(nil), Xpub.Public...)
+)
+
+// LookupVersion returns the registered version that the given version bytes
+// belong to and whether they are the private version bytes.
+func LookupVersion(version []byte) (*KeyVersion, bool, error) {
+	for _, v := range KeyVersions {
+		switch {
+		case bytes.Equal(version, v.Private):
+			return v, true, nil
+		case bytes.Equal(version, v.Public):
+			return v, false, nil
+		}
+	}
+
+	return nil, false, fmt.Errorf("%w: %x", ErrUnknownVersion, version)
+}
+
+// versionFor returns the version that wallets use to export the account keys
+// of the given purpose and coin type.
+func versionFor(purpose Purpose, coin CoinType) *KeyVersion {
+	testnet := coin != CoinTypeBitcoin
+
+	switch {
+	case purpose == PurposeBIP49 && testnet:
+		return Upub
+	case purpose == PurposeBIP49:
+		return Ypub
+	case purpose == PurposeBIP84 && testnet:
+		return Vpub
+	case purpose == PurposeBIP84:
+		return Zpub
+	case testnet:
+		return Tpub
+	}
+
+	return Xpub
+}
+
+// KeyVersion returns the registered version of the key.
+func (ext *ExtendedKey) KeyVersion() (*KeyVersion, error) {
+	v, isPriv, err := LookupVersion(ext.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPriv != ext.IsPrivate {
+		return nil, fmt.Errorf("%w: %x", ErrVersionMismatch,
+			ext.Version)
+	}
+
+	return v, nil
+}
+
+// Network returns the network of the key according to its version.
+func (ext *ExtendedKey) Network() (*address.Network, error) {
+	v, err := ext.KeyVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Network, nil
+}
+
+// WithVersion returns a copy of the key that is encoded with the given
+// version, such as to turn an xpub into the zpub of the same key. The key
+// material is unchanged.
+func (ext *ExtendedKey) WithVersion(v *KeyVersion) *ExtendedKey {
+	version := v.Public
+	if ext.IsPrivate {
+		version = v.Private
+	}
+
+	return &ExtendedKey{
+		Version:     append([]byte(nil), version...),
+		Depth:       ext.Depth,
+		Index:       ext.Index,
+		FingerPrint: append([]byte(nil), ext.FingerPrint...),
+		Key:         append([]byte(nil), ext.Key...),
+		ChainCode:   append([]byte(nil), ext.ChainCode...),
+		IsPrivate:   ext.IsPrivate,
+	}
+}
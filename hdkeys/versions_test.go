@@ -0,0 +1,126 @@
+package hdkeys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/bip39"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionPrefixes(t *testing.T) {
+	seed := make([]byte, seedSize)
+	master, err := ExtendedPrivKeyFromSeed(seed)
+	require.NoError(t, err)
+
+	for _, v := range KeyVersions {
+		priv := master.WithVersion(v)
+
+		pub, err := priv.ExtendedPubKey()
+		require.NoError(t, err)
+
+		privName := v.Name[:1] + "prv"
+		require.True(t, strings.HasPrefix(
+			base58.Encode(priv.Serialize()), privName,
+		), v.Name)
+		require.True(t, strings.HasPrefix(
+			base58.Encode(pub.Serialize()), v.Name,
+		), v.Name)
+
+		got, err := pub.KeyVersion()
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+	}
+}
+
+func TestAccountVersions(t *testing.T) {
+	seed := bip39.NewSeed("abandon abandon abandon abandon abandon "+
+		"abandon abandon abandon abandon abandon abandon about", "")
+
+	master, err := ExtendedPrivKeyFromSeed(seed)
+	require.NoError(t, err)
+
+	// BIP84 gives the account keys as zprv and zpub.
+	acct, err := master.Account(PurposeBIP84, CoinTypeBitcoin, 0)
+	require.NoError(t, err)
+	require.Equal(t, "zprvAdG4iTXWBoARxkkzNpNh8r6Qag3irQB8PzEMkAFeTRXxHpbF"+
+		"9z4QgEvBRmfvqWvGp42t42nvgGpNgYSJA9iefm1yYNZKEm7z6qUWCroSQnE",
+		base58.Encode(acct.Key.Serialize()))
+
+	pub, err := acct.Key.ExtendedPubKey()
+	require.NoError(t, err)
+	require.Equal(t, "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvP"+
+		"hXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+		base58.Encode(pub.Serialize()))
+
+	// BIP49 gives its testnet account key as a tprv rather than a uprv,
+	// which only differs in the version.
+	acct, err = master.Account(PurposeBIP49, CoinTypeTestnet, 0)
+	require.NoError(t, err)
+
+	v, err := acct.Key.KeyVersion()
+	require.NoError(t, err)
+	require.Equal(t, Upub, v)
+
+	require.Equal(t, "tprv8gRrNu65W2Msef2BdBSUgFdRTGzC8EwVXnV7UGS3faeXtuMV"+
+		"tGfEdidVeGbThs4ELEoayCAzZQ4uUji9DUiAs7erdVskqju7hrBcDvDsdbY",
+		base58.Encode(acct.Key.WithVersion(Tpub).Serialize()))
+}
+
+func TestChildKeepsVersion(t *testing.T) {
+	seed := make([]byte, seedSize)
+	master, err := ExtendedPrivKeyFromSeed(seed)
+	require.NoError(t, err)
+	master = master.WithVersion(Vpub)
+
+	child, err := master.ChildFromPath("m/84'/1'/0'/0")
+	require.NoError(t, err)
+	require.Equal(t, Vpub.Private, child.Version)
+
+	pub, err := child.ExtendedPubKey()
+	require.NoError(t, err)
+	require.Equal(t, Vpub.Public, pub.Version)
+
+	pubChild, err := pub.Child(3)
+	require.NoError(t, err)
+	require.Equal(t, Vpub.Public, pubChild.Version)
+
+	net, err := pubChild.Network()
+	require.NoError(t, err)
+	require.Equal(t, address.TestNet, net)
+
+	// Converting the version leaves the key material as it was.
+	x := pubChild.WithVersion(Xpub)
+	require.Equal(t, XpubVersion, x.Version)
+	require.Equal(t, pubChild.Key, x.Key)
+	require.Equal(t, pubChild.ChainCode, x.ChainCode)
+	require.Equal(t, pubChild.FingerPrint, x.FingerPrint)
+	require.Equal(t, Vpub.Public, pubChild.Version)
+}
+
+func TestLookupVersion(t *testing.T) {
+	v, isPriv, err := LookupVersion([]byte{0x04, 0x5f, 0x18, 0xbc})
+	require.NoError(t, err)
+	require.Equal(t, Vpub, v)
+	require.True(t, isPriv)
+
+	_, _, err = LookupVersion([]byte{0x01, 0x02, 0x03, 0x04})
+	require.True(t, errors.Is(err, ErrUnknownVersion))
+
+	// A private key that carries public version bytes is rejected.
+	seed := make([]byte, seedSize)
+	master, err := ExtendedPrivKeyFromSeed(seed)
+	require.NoError(t, err)
+	master.Version = XpubVersion
+
+	_, err = master.ExtendedPubKey()
+	require.True(t, errors.Is(err, ErrVersionMismatch))
+
+	// The exported version bytes don't alias the registry.
+	XpubVersion[0] ^= 0xff
+	require.Equal(t, []byte{0x04, 0x88, 0xb2, 0x1e}, Xpub.Public)
+	XpubVersion[0] ^= 0xff
+}
@@ -23,4 +23,25 @@ func Hash160(b []byte) []byte {
 	rip160.Write(h256.Sum(nil))
 
 	return rip160.Sum(nil)
-}
\ No newline at end of file
+}
+
+// Sha256 returns the single SHA256 hash of b.
+func Sha256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
+
+// TaggedHash computes the BIP340 tagged hash of the concatenation of msgs:
+// sha256(sha256(tag) || sha256(tag) || msgs...).
+func TaggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, m := range msgs {
+		h.Write(m)
+	}
+
+	return h.Sum(nil)
+}
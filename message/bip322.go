@@ -0,0 +1,441 @@
+package message
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+	"github.com/ellemouton/btc/tx"
+	"github.com/ellemouton/btc/varint"
+)
+
+const tagBIP322 = "BIP0322-signed-message"
+
+// Hash returns the BIP322 tagged hash of the message.
+func Hash(msg string) []byte {
+	return helpers.TaggedHash(tagBIP322, []byte(msg))
+}
+
+// toSpend returns the virtual transaction whose only output pays to the
+// scriptPubKey and whose input commits to the message.
+func toSpend(scriptPubKey script.Script, msg string) (*tx.Tx, error) {
+	scriptSig, err := script.Script{
+		{Opcode: script.OP_0},
+		script.PushData(Hash(msg)),
+	}.SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	spk, err := scriptPubKey.SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx.Tx{
+		Version: 0,
+		TxIns: []*tx.TxIn{{
+			PrevTx:    make([]byte, 32),
+			PrevIndex: 0xffffffff,
+			ScriptSig: scriptSig,
+			Sequence:  0,
+		}},
+		TxOuts: []*tx.TxOut{{
+			Amount:       0,
+			ScriptPubKey: spk,
+		}},
+	}, nil
+}
+
+// toSign returns the unsigned virtual transaction that spends the output of
+// toSpend.
+func toSign(spend *tx.Tx) (*tx.Tx, error) {
+	hash, err := spend.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	nullData, err := script.NullData(nil).SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tx.Tx{
+		Version: 0,
+		TxIns: []*tx.TxIn{{
+			PrevTx:    hash,
+			PrevIndex: 0,
+			Sequence:  0,
+		}},
+		TxOuts: []*tx.TxOut{{
+			Amount:       0,
+			ScriptPubKey: nullData,
+		}},
+	}, nil
+}
+
+// SignSimple creates a BIP322 simple signature of the message, which is the
+// base64 encoded witness of the to_sign transaction. It is supported for
+// P2WPKH and key path P2TR addresses.
+func SignSimple(priv *privatekey.PrivateKey, addr *address.Address,
+	msg string) (string, error) {
+
+	if addr.Type != script.TypeP2WPKH && addr.Type != script.TypeP2TR {
+		return "", fmt.Errorf("%w: %v has no simple signature",
+			ErrUnsupportedAddress, addr.Type)
+	}
+
+	sign, err := signToSign(priv, addr, msg)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(
+		encodeWitness(sign.TxIns[0].Witness),
+	), nil
+}
+
+// SignFull creates a BIP322 full signature of the message, which is the
+// base64 encoded to_sign transaction. It is supported for P2PKH, P2SH-P2WPKH,
+// P2WPKH and key path P2TR addresses.
+func SignFull(priv *privatekey.PrivateKey, addr *address.Address,
+	msg string) (string, error) {
+
+	sign, err := signToSign(priv, addr, msg)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := sign.Serialize()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func signToSign(priv *privatekey.PrivateKey, addr *address.Address,
+	msg string) (*tx.Tx, error) {
+
+	scriptPubKey, err := addr.Script()
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, err := keyEncoding(priv.PubKey, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	spend, err := toSpend(scriptPubKey, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := toSign(spend)
+	if err != nil {
+		return nil, err
+	}
+	in := sign.TxIns[0]
+
+	pk := priv.PubKey.Sec(compressed)
+	p2pkh, err := script.P2PKH(helpers.Hash160(pk))
+	if err != nil {
+		return nil, err
+	}
+
+	switch addr.Type {
+	case script.TypeP2PKH:
+		z, err := sign.SigHash(0, scriptPubKey, tx.SigHashAll)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := ecdsaSig(priv, z)
+		if err != nil {
+			return nil, err
+		}
+
+		in.ScriptSig, err = script.Script{
+			script.PushData(sig), script.PushData(pk),
+		}.SerializeRaw()
+		if err != nil {
+			return nil, err
+		}
+
+	case script.TypeP2SH, script.TypeP2WPKH:
+		z, err := sign.SegwitSigHash(0, p2pkh, 0, tx.SigHashAll)
+		if err != nil {
+			return nil, err
+		}
+
+		sig, err := ecdsaSig(priv, z)
+		if err != nil {
+			return nil, err
+		}
+
+		in.Witness = [][]byte{sig, pk}
+
+		if addr.Type == script.TypeP2SH {
+			redeem, err := script.P2WPKH(helpers.Hash160(pk))
+			if err != nil {
+				return nil, err
+			}
+
+			b, err := redeem.SerializeRaw()
+			if err != nil {
+				return nil, err
+			}
+
+			in.ScriptSig, err = script.Script{
+				script.PushData(b),
+			}.SerializeRaw()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+	case script.TypeP2TR:
+		tweaked, err := taproot.TweakPrivateKey(priv, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		z, err := sign.TaprootSigHash(
+			0, spend.TxOuts, tx.SigHashDefault, nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		aux := make([]byte, 32)
+		if _, err := rand.Read(aux); err != nil {
+			return nil, err
+		}
+
+		sig, err := tweaked.SignSchnorr(z, aux)
+		if err != nil {
+			return nil, err
+		}
+
+		in.Witness = [][]byte{sig.Serialize()}
+
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAddress, addr.Type)
+	}
+
+	sign.Segwit = len(in.Witness) != 0
+
+	return sign, nil
+}
+
+// ecdsaSig signs the hash and returns the DER signature followed by the
+// SIGHASH_ALL byte.
+func ecdsaSig(priv *privatekey.PrivateKey, z []byte) ([]byte, error) {
+	sig, err := priv.Sign(z)
+	if err != nil {
+		return nil, err
+	}
+
+	return sig.SerializeWithHashType(byte(tx.SigHashAll)), nil
+}
+
+// Verify verifies a message signature for the address. The signature may be a
+// legacy BIP137 signature or a BIP322 simple or full signature.
+func Verify(addr *address.Address, msg, sig string) error {
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	if len(b) == 65 && b[0] >= headerP2PKHUncompressed &&
+		b[0] <= headerMax {
+
+		return VerifyLegacy(addr, msg, sig)
+	}
+
+	if _, err := decodeWitness(b); err == nil {
+		return VerifySimple(addr, msg, sig)
+	}
+
+	return VerifyFull(addr, msg, sig)
+}
+
+// VerifySimple verifies a BIP322 simple signature of the message for the
+// address.
+func VerifySimple(addr *address.Address, msg, sig string) error {
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	witness, err := decodeWitness(b)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	scriptPubKey, err := addr.Script()
+	if err != nil {
+		return err
+	}
+
+	spend, err := toSpend(scriptPubKey, msg)
+	if err != nil {
+		return err
+	}
+
+	sign, err := toSign(spend)
+	if err != nil {
+		return err
+	}
+	sign.TxIns[0].Witness = witness
+	sign.Segwit = true
+
+	return verifyToSign(spend, sign)
+}
+
+// VerifyFull verifies a BIP322 full signature of the message for the
+// address. Proofs of funds, which spend additional inputs, are not
+// supported.
+func VerifyFull(addr *address.Address, msg, sig string) error {
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	sign, err := tx.Parse(b)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	scriptPubKey, err := addr.Script()
+	if err != nil {
+		return err
+	}
+
+	spend, err := toSpend(scriptPubKey, msg)
+	if err != nil {
+		return err
+	}
+
+	hash, err := spend.Hash()
+	if err != nil {
+		return err
+	}
+
+	if len(sign.TxIns) != 1 {
+		return fmt.Errorf("%w: to_sign must have exactly one input",
+			ErrUnsupportedAddress)
+	}
+
+	in := sign.TxIns[0]
+	if !bytes.Equal(in.PrevTx, hash) || in.PrevIndex != 0 {
+		return fmt.Errorf("%w: to_sign does not spend to_spend",
+			ErrInvalidSignature)
+	}
+
+	if len(sign.TxOuts) != 1 || sign.TxOuts[0].Amount != 0 ||
+		!bytes.Equal(sign.TxOuts[0].ScriptPubKey,
+			[]byte{byte(script.OP_RETURN)}) {
+
+		return fmt.Errorf("%w: to_sign must have a single empty "+
+			"OP_RETURN output", ErrInvalidSignature)
+	}
+
+	return verifyToSign(spend, sign)
+}
+
+// verifyToSign checks that the first input of to_sign validly spends the
+// output of to_spend. Taproot outputs may be spent with either the key path
+// or a script path.
+func verifyToSign(spend, sign *tx.Tx) error {
+	scriptPubKey, err := script.ParseRaw(spend.TxOuts[0].ScriptPubKey)
+	if err != nil {
+		return err
+	}
+	in := sign.TxIns[0]
+
+	scriptSig, err := script.ParseRaw(in.ScriptSig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	ctx, err := tx.NewSpendContext(sign, spend.TxOuts)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	err = script.Evaluate(
+		scriptSig, scriptPubKey, in.Witness, ctx, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+// encodeWitness serializes a witness stack as a count followed by the length
+// prefixed items.
+func encodeWitness(witness [][]byte) []byte {
+	var b bytes.Buffer
+
+	// Encoding a length can't fail.
+	n, _ := varint.Encode(uint64(len(witness)))
+	b.Write(n)
+	for _, item := range witness {
+		l, _ := varint.Encode(uint64(len(item)))
+		b.Write(l)
+		b.Write(item)
+	}
+
+	return b.Bytes()
+}
+
+// decodeWitness parses a witness stack serialized by encodeWitness. All of
+// the input must be consumed.
+func decodeWitness(b []byte) ([][]byte, error) {
+	r := bytes.NewReader(b)
+
+	n, err := varint.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if n > uint64(r.Len()) {
+		return nil, errors.New("witness item count exceeds its length")
+	}
+
+	witness := make([][]byte, 0, n)
+	for i := uint64(0); i < n; i++ {
+		l, err := varint.ReadFrom(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if l > uint64(r.Len()) {
+			return nil, errors.New("witness item exceeds its length")
+		}
+
+		item := make([]byte, l)
+		if _, err := io.ReadFull(r, item); err != nil {
+			return nil, err
+		}
+		witness = append(witness, item)
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("trailing bytes after witness")
+	}
+
+	return witness, nil
+}
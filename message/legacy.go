@@ -0,0 +1,143 @@
+package message
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/signature"
+	"github.com/ellemouton/btc/varint"
+)
+
+const legacyMagic = "Bitcoin Signed Message:\n"
+
+// The header byte of a BIP137 signature is the recovery id added to one of
+// these values, which record the type of address that was signed for.
+const (
+	headerP2PKHUncompressed byte = 27
+	headerP2PKHCompressed   byte = 31
+	headerP2SHP2WPKH        byte = 35
+	headerP2WPKH            byte = 39
+	headerMax               byte = 42
+)
+
+// LegacyHash returns the hash that is signed by legacy message signatures:
+// the double SHA256 of the length prefixed magic string and message.
+func LegacyHash(msg string) []byte {
+	var b bytes.Buffer
+	for _, s := range []string{legacyMagic, msg} {
+		// Encoding a length can't fail.
+		l, _ := varint.Encode(uint64(len(s)))
+		b.Write(l)
+		b.WriteString(s)
+	}
+
+	return helpers.DoubleSha256(b.Bytes())
+}
+
+// SignLegacy signs the message for the address using the BIP137 compact
+// signature format used by Bitcoin Core's signmessage. The result is base64
+// encoded. P2PKH, P2SH-P2WPKH and P2WPKH addresses are supported.
+func SignLegacy(priv *privatekey.PrivateKey, addr *address.Address,
+	msg string) (string, error) {
+
+	var header byte
+	switch addr.Type {
+	case script.TypeP2PKH:
+		header = headerP2PKHCompressed
+	case script.TypeP2SH:
+		header = headerP2SHP2WPKH
+	case script.TypeP2WPKH:
+		header = headerP2WPKH
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedAddress, addr.Type)
+	}
+
+	compressed, err := keyEncoding(priv.PubKey, addr)
+	if err != nil {
+		return "", err
+	}
+
+	if !compressed {
+		header = headerP2PKHUncompressed
+	}
+
+	sig, recID, err := priv.SignRecoverable(LegacyHash(msg))
+	if err != nil {
+		return "", err
+	}
+
+	b, err := sig.Compact(recID, compressed)
+	if err != nil {
+		return "", err
+	}
+	b[0] = header + recID
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// VerifyLegacy verifies a base64 encoded BIP137 signature of the message for
+// the address. Segwit addresses are also accepted with the P2PKH compressed
+// header, as is done by wallets that predate BIP137.
+func VerifyLegacy(addr *address.Address, msg, sig string) error {
+	b, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil || len(b) != signature.CompactSize {
+		return fmt.Errorf("%w: signature must be %d bytes of base64",
+			ErrInvalidSignature, signature.CompactSize)
+	}
+
+	header := b[0]
+	if header < headerP2PKHUncompressed || header > headerMax {
+		return fmt.Errorf("%w: invalid header byte %d",
+			ErrInvalidSignature, header)
+	}
+
+	recID := (header - headerP2PKHUncompressed) & 3
+	compressed := header >= headerP2PKHCompressed
+
+	s, _, _, err := signature.ParseCompact(
+		append([]byte{headerP2PKHUncompressed + recID}, b[1:]...),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	pk, err := s256point.RecoverPubKey(LegacyHash(msg), s, recID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	switch addr.Type {
+	case script.TypeP2PKH:
+		if header >= headerP2SHP2WPKH {
+			return fmt.Errorf("%w: segwit header for a P2PKH address",
+				ErrInvalidSignature)
+		}
+
+	case script.TypeP2SH, script.TypeP2WPKH:
+		if !compressed {
+			return fmt.Errorf("%w: uncompressed key for a segwit "+
+				"address", ErrInvalidSignature)
+		}
+
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedAddress, addr.Type)
+	}
+
+	ok, err := keyMatches(pk, addr, compressed)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: recovered key does not match the address",
+			ErrInvalidSignature)
+	}
+
+	return nil
+}
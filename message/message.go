@@ -0,0 +1,107 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+)
+
+var (
+	// ErrInvalidSignature is returned when a message signature is
+	// malformed or doesn't prove ownership of the address.
+	ErrInvalidSignature = errors.New("invalid message signature")
+
+	// ErrUnsupportedAddress is returned when messages can't be signed or
+	// verified for an address with the requested format.
+	ErrUnsupportedAddress = errors.New("unsupported address type")
+
+	// ErrKeyMismatch is returned when signing with a key that doesn't
+	// belong to the address.
+	ErrKeyMismatch = errors.New("key does not match the address")
+)
+
+// keyScript returns the scriptPubKey of the given address type that pays to
+// the public key. P2SH is taken to mean P2SH wrapped P2WPKH, which is the
+// only kind of P2SH address that a single key can sign for.
+func keyScript(pk *s256point.S256Point, t script.Type,
+	compressed bool) (script.Script, error) {
+
+	switch t {
+	case script.TypeP2PKH:
+		return script.P2PKH(helpers.Hash160(pk.Sec(compressed)))
+
+	case script.TypeP2SH:
+		redeem, err := script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+		if err != nil {
+			return nil, err
+		}
+
+		return script.P2SH(redeem)
+
+	case script.TypeP2WPKH:
+		return script.P2WPKH(helpers.Hash160(pk.Sec(true)))
+
+	case script.TypeP2TR:
+		q, err := taproot.ComputeOutputKey(pk, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return script.P2TR(q.XOnly())
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrUnsupportedAddress, t)
+}
+
+// keyMatches returns true if the address pays to the public key in the given
+// encoding.
+func keyMatches(pk *s256point.S256Point, addr *address.Address,
+	compressed bool) (bool, error) {
+
+	want, err := addr.Script()
+	if err != nil {
+		return false, err
+	}
+
+	got, err := keyScript(pk, addr.Type, compressed)
+	if err != nil {
+		return false, err
+	}
+
+	wantBytes, err := want.SerializeRaw()
+	if err != nil {
+		return false, err
+	}
+
+	gotBytes, err := got.SerializeRaw()
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(wantBytes, gotBytes), nil
+}
+
+// keyEncoding returns whether the address pays to the compressed encoding of
+// the key. Only P2PKH addresses can use the uncompressed encoding.
+func keyEncoding(pk *s256point.S256Point, addr *address.Address) (bool,
+	error) {
+
+	for _, compressed := range []bool{true, false} {
+		ok, err := keyMatches(pk, addr, compressed)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			return compressed, nil
+		}
+	}
+
+	return false, ErrKeyMismatch
+}
@@ -0,0 +1,276 @@
+package message
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+	"github.com/ellemouton/btc/tx"
+	"github.com/stretchr/testify/require"
+)
+
+// bip322Key is the private key used by the BIP322 test vectors.
+const bip322Key = "L3VFeEujGtevx9w18HD1fhRbCH67Az2dpCymeRE1SoPK6XQtaN2k"
+
+func privFromWIF(t *testing.T, wif string) *privatekey.PrivateKey {
+	payload, version, err := base58.CheckDecode(wif)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x80), version)
+
+	priv, err := privatekey.New(new(big.Int).SetBytes(payload[:32]))
+	require.NoError(t, err)
+
+	return priv
+}
+
+func addrFromKey(t *testing.T, priv *privatekey.PrivateKey, typ script.Type,
+	compressed bool) *address.Address {
+
+	s, err := keyScript(priv.PubKey, typ, compressed)
+	require.NoError(t, err)
+
+	addr, err := address.FromScript(s, address.MainNet)
+	require.NoError(t, err)
+
+	return addr
+}
+
+func TestBIP322Hash(t *testing.T) {
+	require.Equal(t,
+		"c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1",
+		hex.EncodeToString(Hash("")),
+	)
+	require.Equal(t,
+		"f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a",
+		hex.EncodeToString(Hash("Hello World")),
+	)
+}
+
+func TestBIP322Vectors(t *testing.T) {
+	addr, err := address.Decode("bc1q9vza2e8x573nczrlzms0wvx3gsqjx7vavgkx0l")
+	require.NoError(t, err)
+
+	priv := privFromWIF(t, bip322Key)
+	require.Equal(t, addr.String(), addrFromKey(t, priv, script.TypeP2WPKH, true).String())
+
+	tests := []struct {
+		msg      string
+		toSpend  string
+		toSign   string
+		expected string
+	}{
+		{
+			msg:     "",
+			toSpend: "c5680aa69bb8d860bf82d4e9cd3504b55dde018de765a91bb566283c545a99a7",
+			toSign:  "1e9654e951a5ba44c8604c4de6c67fd78a27e81dcadcfe1edf638ba3aaebaed6",
+			expected: "AkcwRAIgM2gBAQqvZX15ZiysmKmQpDrG83avLIT492QBzLnQIxYCIBaTpOaD" +
+				"20qRlEylyxFSeEA2ba9YOixpX8z46TSDtS40ASECx/EgAxlkQpQ9hYjgGu6E" +
+				"BCPMVPwVIVJqO4XCsMvViHI=",
+		},
+		{
+			msg:     "Hello World",
+			toSpend: "b79d196740ad5217771c1098fc4a4b51e0535c32236c71f1ea4d61a2d603352b",
+			toSign:  "88737ae86f2077145f93cc4b153ae9a1cb8d56afa511988c149c5c8c9d93bddf",
+			expected: "AkcwRAIgZRfIY3p7/DoVTty6YZbWS71bc5Vct9p9Fia83eRmw2QCICK/ENGf" +
+				"wLtptFluMGs2KsqoNSk89pO7F29zJLUx9a/sASECx/EgAxlkQpQ9hYjgGu6E" +
+				"BCPMVPwVIVJqO4XCsMvViHI=",
+		},
+	}
+
+	for _, test := range tests {
+		scriptPubKey, err := addr.Script()
+		require.NoError(t, err)
+
+		spend, err := toSpend(scriptPubKey, test.msg)
+		require.NoError(t, err)
+		id, err := spend.ID()
+		require.NoError(t, err)
+		require.Equal(t, test.toSpend, id)
+
+		sign, err := toSign(spend)
+		require.NoError(t, err)
+		id, err = sign.ID()
+		require.NoError(t, err)
+		require.Equal(t, test.toSign, id)
+
+		require.NoError(t, VerifySimple(addr, test.msg, test.expected))
+		require.NoError(t, Verify(addr, test.msg, test.expected))
+
+		err = VerifySimple(addr, test.msg+"!", test.expected)
+		require.True(t, errors.Is(err, ErrInvalidSignature))
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	priv := privFromWIF(t, bip322Key)
+
+	other, err := privatekey.New(big.NewInt(123456789))
+	require.NoError(t, err)
+
+	tests := []struct {
+		typ        script.Type
+		compressed bool
+		legacy     bool
+		simple     bool
+	}{
+		{typ: script.TypeP2PKH, compressed: false, legacy: true},
+		{typ: script.TypeP2PKH, compressed: true, legacy: true},
+		{typ: script.TypeP2SH, compressed: true, legacy: true},
+		{typ: script.TypeP2WPKH, compressed: true, legacy: true, simple: true},
+		{typ: script.TypeP2TR, compressed: true, simple: true},
+	}
+
+	const msg = "Hello World"
+
+	for _, test := range tests {
+		addr := addrFromKey(t, priv, test.typ, test.compressed)
+		otherAddr := addrFromKey(t, other, test.typ, test.compressed)
+
+		var sigs []string
+
+		sig, err := SignLegacy(priv, addr, msg)
+		if test.legacy {
+			require.NoError(t, err, addr)
+			require.NoError(t, VerifyLegacy(addr, msg, sig))
+			sigs = append(sigs, sig)
+		} else {
+			require.True(t, errors.Is(err, ErrUnsupportedAddress))
+		}
+
+		sig, err = SignSimple(priv, addr, msg)
+		if test.simple {
+			require.NoError(t, err)
+			require.NoError(t, VerifySimple(addr, msg, sig))
+			sigs = append(sigs, sig)
+		} else {
+			require.True(t, errors.Is(err, ErrUnsupportedAddress))
+		}
+
+		sig, err = SignFull(priv, addr, msg)
+		require.NoError(t, err)
+		require.NoError(t, VerifyFull(addr, msg, sig))
+		sigs = append(sigs, sig)
+
+		for _, sig := range sigs {
+			require.NoError(t, Verify(addr, msg, sig), addr)
+
+			err := Verify(addr, msg+" ", sig)
+			require.True(t, errors.Is(err, ErrInvalidSignature), addr)
+
+			err = Verify(otherAddr, msg, sig)
+			require.True(t, errors.Is(err, ErrInvalidSignature), addr)
+		}
+
+		// Signing for an address of another key fails.
+		_, err = SignFull(priv, otherAddr, msg)
+		require.True(t, errors.Is(err, ErrKeyMismatch))
+	}
+}
+
+func TestBIP322Taproot(t *testing.T) {
+	tests := []struct {
+		addr string
+		key  string
+		msg  string
+		sig  string
+	}{
+		{
+			addr: "bc1ppv609nr0vr25u07u95waq5lucwfm6tde4nydujnu8npg4q75mr5sxq8lt3",
+			key:  bip322Key,
+			msg:  "Hello World",
+			sig: "AUHd69PrJQEv+oKTfZ8l+WROBHuy9HKrbFCJu7U1iK2iiEy1vMU5EfMt" +
+				"jc+VSHM7aU0SDbak5IUZRVno2P5mjSafAQ==",
+		},
+		{
+			addr: "bc1pss0zhytly75awhm6x2hhvd5lnzv3vssgrf9axfheq8ldyzn88ges79fler",
+			key:  "KyrSGCFPhqZMjCe5fNTYddiLMp4tMj4gLKuJ26TsB2rvr1VJGPbt",
+			msg:  "No prefix fallback",
+			sig: "AUCJYOwOjxYAvatTAGYaVlNXBVyFuc4MwNQkOuK2tl8xhfKDONd0NjfY" +
+				"yNSYcRqeCp8hsAnCEPHAVEkO9h6vbQ/R",
+		},
+	}
+
+	for _, test := range tests {
+		addr, err := address.Decode(test.addr)
+		require.NoError(t, err)
+
+		priv := privFromWIF(t, test.key)
+		require.Equal(t, test.addr,
+			addrFromKey(t, priv, script.TypeP2TR, true).String())
+
+		require.NoError(t, VerifySimple(addr, test.msg, test.sig))
+		require.NoError(t, Verify(addr, test.msg, test.sig))
+
+		err = VerifySimple(addr, test.msg+"!", test.sig)
+		require.True(t, errors.Is(err, ErrInvalidSignature))
+	}
+}
+
+func TestBIP322TaprootScriptPath(t *testing.T) {
+	priv := privFromWIF(t, bip322Key)
+
+	internal, err := privatekey.New(big.NewInt(123456789))
+	require.NoError(t, err)
+
+	// <key> OP_CHECKSIG
+	leaf := taproot.NewBaseLeaf(script.Script{
+		script.PushData(priv.PubKey.XOnly()),
+		{Opcode: script.OP_CHECKSIG},
+	})
+
+	tree, err := taproot.AssembleTree(leaf)
+	require.NoError(t, err)
+
+	outputKey, err := taproot.ComputeOutputKey(
+		internal.PubKey, tree.RootHash(),
+	)
+	require.NoError(t, err)
+
+	scriptPubKey, err := script.P2TR(outputKey.XOnly())
+	require.NoError(t, err)
+
+	addr, err := address.FromScript(scriptPubKey, address.MainNet)
+	require.NoError(t, err)
+
+	const msg = "Hello World"
+
+	spend, err := toSpend(scriptPubKey, msg)
+	require.NoError(t, err)
+
+	sign, err := toSign(spend)
+	require.NoError(t, err)
+
+	leafHash, err := leaf.Hash()
+	require.NoError(t, err)
+
+	z, err := sign.TapscriptSigHash(
+		0, spend.TxOuts, tx.SigHashDefault, nil, leafHash, 0xffffffff,
+	)
+	require.NoError(t, err)
+
+	sig, err := priv.SignSchnorr(z, make([]byte, 32))
+	require.NoError(t, err)
+
+	leafScript, err := leaf.Script.SerializeRaw()
+	require.NoError(t, err)
+
+	control, err := tree.ControlBlock(internal.PubKey, 0)
+	require.NoError(t, err)
+
+	simple := base64.StdEncoding.EncodeToString(encodeWitness([][]byte{
+		sig.Serialize(), leafScript, control.Serialize(),
+	}))
+
+	require.NoError(t, VerifySimple(addr, msg, simple))
+	require.NoError(t, Verify(addr, msg, simple))
+
+	err = VerifySimple(addr, msg+"!", simple)
+	require.True(t, errors.Is(err, ErrInvalidSignature))
+}
@@ -0,0 +1,169 @@
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+)
+
+const (
+	tagKeyAggList  = "KeyAgg list"
+	tagKeyAggCoeff = "KeyAgg coefficient"
+)
+
+// KeySort returns a copy of the public keys sorted in lexicographical order
+// of their encoding.
+func KeySort(pubKeys [][]byte) [][]byte {
+	sorted := append([][]byte{}, pubKeys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i], sorted[j]) < 0
+	})
+
+	return sorted
+}
+
+// KeyAggContext holds the aggregate public key along with the accumulated
+// effect of any tweaks that have been applied to it.
+type KeyAggContext struct {
+	// Q is the aggregate public key after tweaking.
+	Q *s256point.S256Point
+
+	// gacc is the accumulated negation factor (1 or n-1) and tacc the
+	// accumulated tweak.
+	gacc *big.Int
+	tacc *big.Int
+}
+
+// KeyAgg aggregates the compressed public keys of the signers. The order of
+// the keys matters, so signers must agree on it, for example by using
+// KeySort.
+func KeyAgg(pubKeys [][]byte) (*KeyAggContext, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("at least one public key is required")
+	}
+
+	var (
+		pk2     = secondKey(pubKeys)
+		l       = hashKeys(pubKeys)
+		points  []*s256point.S256Point
+		scalars []*big.Int
+	)
+	for i, pk := range pubKeys {
+		p, err := cpoint(pk)
+		if err != nil {
+			return nil, &InvalidContributionError{
+				Signer: i, Contrib: "pubkey",
+			}
+		}
+
+		points = append(points, p)
+		scalars = append(scalars, keyAggCoeffInternal(l, pk, pk2))
+	}
+
+	q, err := s256point.MultiScalarMul(points, scalars)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.IsInfinity() {
+		return nil, errors.New("aggregate key is the point at infinity")
+	}
+
+	return &KeyAggContext{
+		Q:    q,
+		gacc: big.NewInt(1),
+		tacc: big.NewInt(0),
+	}, nil
+}
+
+// XOnlyPubKey returns the 32 byte x-only encoding of the aggregate key, which
+// is the key that the final signature is valid for.
+func (c *KeyAggContext) XOnlyPubKey() []byte {
+	return c.Q.XOnly()
+}
+
+// PlainPubKey returns the compressed encoding of the aggregate key.
+func (c *KeyAggContext) PlainPubKey() []byte {
+	return c.Q.Sec(true)
+}
+
+// ApplyTweak returns a new context with the tweak added to the aggregate key.
+// An x-only tweak first negates the key if its y coordinate is odd, as is
+// done by BIP341 when deriving a taproot output key from an internal key. A
+// plain tweak is the kind used by BIP32 derivation.
+func (c *KeyAggContext) ApplyTweak(tweak []byte,
+	isXOnly bool) (*KeyAggContext, error) {
+
+	if len(tweak) != 32 {
+		return nil, errors.New("tweak must be 32 bytes")
+	}
+
+	n := s256point.N
+
+	g := big.NewInt(1)
+	if isXOnly && !c.Q.HasEvenY() {
+		g = negOne()
+	}
+
+	t, ok := scalar(tweak)
+	if !ok {
+		return nil, errors.New("tweak exceeds the curve order")
+	}
+
+	q, err := s256point.MultiScalarMul(
+		[]*s256point.S256Point{c.Q, s256point.G}, []*big.Int{g, t},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.IsInfinity() {
+		return nil, errors.New("tweaked key is the point at infinity")
+	}
+
+	gacc := new(big.Int).Mul(g, c.gacc)
+	gacc.Mod(gacc, n)
+
+	tacc := new(big.Int).Mul(g, c.tacc)
+	tacc.Add(tacc, t)
+	tacc.Mod(tacc, n)
+
+	return &KeyAggContext{Q: q, gacc: gacc, tacc: tacc}, nil
+}
+
+// hashKeys returns the hash of the list of public keys.
+func hashKeys(pubKeys [][]byte) []byte {
+	return helpers.TaggedHash(tagKeyAggList, pubKeys...)
+}
+
+// secondKey returns the first key that differs from the first key in the
+// list, or 33 zero bytes if all keys are the same. That key gets a
+// coefficient of one, which saves a scalar multiplication.
+func secondKey(pubKeys [][]byte) []byte {
+	for _, pk := range pubKeys[1:] {
+		if !bytes.Equal(pk, pubKeys[0]) {
+			return pk
+		}
+	}
+
+	return make([]byte, PubKeySize)
+}
+
+// keyAggCoeff returns the coefficient of the public key in the aggregate key.
+func keyAggCoeff(pubKeys [][]byte, pk []byte) *big.Int {
+	return keyAggCoeffInternal(hashKeys(pubKeys), pk, secondKey(pubKeys))
+}
+
+func keyAggCoeffInternal(l, pk, pk2 []byte) *big.Int {
+	if bytes.Equal(pk, pk2) {
+		return big.NewInt(1)
+	}
+
+	a := new(big.Int).SetBytes(helpers.TaggedHash(tagKeyAggCoeff, l, pk))
+
+	return a.Mod(a, s256point.N)
+}
@@ -0,0 +1,164 @@
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+const (
+	// PubKeySize is the size of the compressed individual public keys.
+	PubKeySize = 33
+
+	// PubNonceSize is the size of a public nonce: two compressed points.
+	PubNonceSize = 66
+
+	// AggNonceSize is the size of an aggregate nonce.
+	AggNonceSize = 66
+
+	// SecNonceSize is the size of a secret nonce: two 32 byte scalars
+	// followed by the signer's public key.
+	SecNonceSize = 97
+
+	// PartialSigSize is the size of a partial signature.
+	PartialSigSize = 32
+)
+
+var (
+	// ErrSecNonceReused is returned when a secret nonce that has already
+	// been used to sign is passed to Sign again.
+	ErrSecNonceReused = errors.New("secret nonce has already been used")
+)
+
+// InvalidContributionError is returned when the contribution of one of the
+// signers is invalid. Signer is the index of the signer to blame, or -1 if
+// the aggregator is to blame.
+type InvalidContributionError struct {
+	Signer  int
+	Contrib string
+}
+
+func (e *InvalidContributionError) Error() string {
+	return fmt.Sprintf("invalid %s from signer %d", e.Contrib, e.Signer)
+}
+
+// cpoint decodes a 33 byte compressed point.
+func cpoint(b []byte) (*s256point.S256Point, error) {
+	if len(b) != PubKeySize || (b[0] != 0x02 && b[0] != 0x03) {
+		return nil, errors.New("invalid compressed point encoding")
+	}
+
+	p, err := s256point.Parse(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.(*s256point.S256Point), nil
+}
+
+// cpointExt is cpoint extended to decode 33 zero bytes as the point at
+// infinity.
+func cpointExt(b []byte) (*s256point.S256Point, error) {
+	if bytes.Equal(b, make([]byte, PubKeySize)) {
+		return s256point.New(nil, nil)
+	}
+
+	return cpoint(b)
+}
+
+// cbytesExt encodes a point in compressed form or as 33 zero bytes if it is
+// the point at infinity.
+func cbytesExt(p *s256point.S256Point) []byte {
+	if p.IsInfinity() {
+		return make([]byte, PubKeySize)
+	}
+
+	return p.Sec(true)
+}
+
+// negate returns -p.
+func negate(p *s256point.S256Point) (*s256point.S256Point, error) {
+	if p.IsInfinity() {
+		return p, nil
+	}
+
+	y, err := s256field.New(new(big.Int).Sub(s256field.P, p.GetY().GetNum()))
+	if err != nil {
+		return nil, err
+	}
+
+	return s256point.New(p.GetX(), y)
+}
+
+// baseMult returns k*G in constant time, for scalars that are secret.
+func baseMult(k *secp256k1.Scalar) (*s256point.S256Point, error) {
+	var p secp256k1.JacobianPoint
+	return s256point.FromJacobian(p.ScalarBaseMult(k))
+}
+
+// add returns p + q.
+func add(p, q *s256point.S256Point) (*s256point.S256Point, error) {
+	res, err := p.Add(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*s256point.S256Point), nil
+}
+
+// scalar interprets b as a big endian integer and checks that it is less
+// than the curve order.
+func scalar(b []byte) (*big.Int, bool) {
+	k := new(big.Int).SetBytes(b)
+	return k, k.Cmp(s256point.N) < 0
+}
+
+// secretScalar sets k to the secret b, which must be a 32 byte big endian
+// integer less than the curve order, without going through math/big.
+func secretScalar(k *secp256k1.Scalar, b []byte) bool {
+	if len(b) != 32 {
+		return false
+	}
+
+	var buf [32]byte
+	copy(buf[:], b)
+	overflow := k.SetBytes(&buf)
+	zero(buf[:])
+
+	return !overflow
+}
+
+// publicScalar converts a public value that is less than the curve order so
+// that it can be combined with secret scalars.
+func publicScalar(v *big.Int) *secp256k1.Scalar {
+	var k secp256k1.Scalar
+	k.SetByteSlice(bytes32(v))
+
+	return &k
+}
+
+// zero overwrites b with zeros so that secrets don't linger in memory.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// bytes32 returns k as a 32 byte big endian integer.
+func bytes32(k *big.Int) []byte {
+	b := make([]byte, 32)
+	kb := k.Bytes()
+	copy(b[32-len(kb):], kb)
+
+	return b
+}
+
+// negOne returns n-1, which is -1 modulo the curve order.
+func negOne() *big.Int {
+	return new(big.Int).Sub(s256point.N, big.NewInt(1))
+}
@@ -0,0 +1,607 @@
+package musig2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/taproot"
+	"github.com/stretchr/testify/require"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+// TestKeyAgg checks key aggregation against the BIP327 test vectors.
+func TestKeyAgg(t *testing.T) {
+	keys := [][]byte{
+		mustDecode(t, "02F9308A019258C31049344F85F89D5229B531C845836F"+
+			"99B08601F113BCE036F9"),
+		mustDecode(t, "03DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DE"+
+			"CED843240F7B502BA659"),
+		mustDecode(t, "023590A94E768F8E1815C2F24B4D80A8E3149316C3518C"+
+			"E7B7AD338368D038CA66"),
+	}
+
+	tests := []struct {
+		indices  []int
+		expected string
+	}{
+		{
+			indices: []int{0, 1, 2},
+			expected: "90539EEDE565F5D054F32CC0C220126889ED1E5D193BAF" +
+				"15AEF344FE59D4610C",
+		},
+		{
+			indices: []int{2, 1, 0},
+			expected: "6204DE8B083426DC6EAF9502D27024D53FC826BF7D2012" +
+				"148A0575435DF54B2B",
+		},
+		{
+			indices: []int{0, 0, 0},
+			expected: "B436E3BAD62B8CD409969A224731C193D051162D8C5AE8" +
+				"B109306127DA3AA935",
+		},
+		{
+			indices: []int{0, 0, 1, 1},
+			expected: "69BC22BFA5D106306E48A20679DE1D7389386124D07571" +
+				"D0D872686028C26A3E",
+		},
+	}
+
+	for _, test := range tests {
+		var pubKeys [][]byte
+		for _, i := range test.indices {
+			pubKeys = append(pubKeys, keys[i])
+		}
+
+		ctx, err := KeyAgg(pubKeys)
+		require.NoError(t, err)
+		require.Equal(t, mustDecode(t, test.expected), ctx.XOnlyPubKey())
+	}
+
+	// An invalid key is blamed on the signer that provided it.
+	bad := append([]byte{}, keys[1]...)
+	bad[0] = 0x04
+	_, err := KeyAgg([][]byte{keys[0], bad})
+
+	var contribErr *InvalidContributionError
+	require.True(t, errors.As(err, &contribErr))
+	require.Equal(t, 1, contribErr.Signer)
+}
+
+func TestKeySort(t *testing.T) {
+	keys := [][]byte{{0x03, 0x01}, {0x02, 0x05}, {0x02, 0x01}}
+
+	sorted := KeySort(keys)
+	require.Equal(t, [][]byte{{0x02, 0x01}, {0x02, 0x05}, {0x03, 0x01}},
+		sorted)
+
+	// The input is left untouched.
+	require.Equal(t, []byte{0x03, 0x01}, keys[0])
+}
+
+type testSigner struct {
+	sk       []byte
+	pk       []byte
+	secNonce []byte
+	pubNonce []byte
+}
+
+func newTestSigners(t *testing.T, n int) []*testSigner {
+	var signers []*testSigner
+	for i := 1; i <= n; i++ {
+		priv, err := privatekey.New(big.NewInt(int64(i) * 987654321))
+		require.NoError(t, err)
+
+		s := &testSigner{
+			sk: bytes32(big.NewInt(int64(i) * 987654321)),
+			pk: priv.PubKey.Sec(true),
+		}
+		signers = append(signers, s)
+	}
+
+	return signers
+}
+
+// newSession runs the nonce exchange between the signers and returns the
+// resulting session context.
+func newSession(t *testing.T, signers []*testSigner, msg []byte,
+	tweaks []Tweak) *SessionContext {
+
+	var pubKeys, pubNonces [][]byte
+	for _, s := range signers {
+		pubKeys = append(pubKeys, s.pk)
+	}
+	pubKeys = KeySort(pubKeys)
+
+	for _, s := range signers {
+		var err error
+		s.secNonce, s.pubNonce, err = NonceGen(s.sk, s.pk, nil, msg, nil)
+		require.NoError(t, err)
+
+		pubNonces = append(pubNonces, s.pubNonce)
+	}
+
+	aggNonce, err := NonceAgg(pubNonces)
+	require.NoError(t, err)
+
+	return &SessionContext{
+		AggNonce: aggNonce,
+		PubKeys:  pubKeys,
+		Tweaks:   tweaks,
+		Msg:      msg,
+	}
+}
+
+func signSession(t *testing.T, signers []*testSigner,
+	session *SessionContext) *schnorr.Signature {
+
+	var psigs [][]byte
+	for _, s := range signers {
+		psig, err := Sign(s.secNonce, s.sk, session)
+		require.NoError(t, err)
+
+		require.NoError(t, PartialSigVerify(
+			psig, s.pubNonce, s.pk, session,
+		))
+
+		psigs = append(psigs, psig)
+	}
+
+	sig, err := PartialSigAgg(psigs, session)
+	require.NoError(t, err)
+
+	return sig
+}
+
+func TestSign(t *testing.T) {
+	msg := sha256.Sum256([]byte("musig2"))
+
+	for _, n := range []int{1, 2, 3} {
+		signers := newTestSigners(t, n)
+		session := newSession(t, signers, msg[:], nil)
+		sig := signSession(t, signers, session)
+
+		ctx, err := KeyAgg(session.PubKeys)
+		require.NoError(t, err)
+
+		aggPk, err := s256point.ParseXOnly(ctx.XOnlyPubKey())
+		require.NoError(t, err)
+
+		ok, err := aggPk.VerifySchnorr(msg[:], sig)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
+
+// TestSignTaproot signs for a key path spend of a taproot output whose
+// internal key is the aggregate key.
+func TestSignTaproot(t *testing.T) {
+	msg := sha256.Sum256([]byte("taproot"))
+	signers := newTestSigners(t, 3)
+
+	var pubKeys [][]byte
+	for _, s := range signers {
+		pubKeys = append(pubKeys, s.pk)
+	}
+
+	ctx, err := KeyAgg(KeySort(pubKeys))
+	require.NoError(t, err)
+
+	internalKey, err := s256point.ParseXOnly(ctx.XOnlyPubKey())
+	require.NoError(t, err)
+
+	outputKey, err := taproot.ComputeOutputKey(internalKey, nil)
+	require.NoError(t, err)
+
+	tweak := Tweak{Tweak: taproot.TweakHash(internalKey, nil), IsXOnly: true}
+	session := newSession(t, signers, msg[:], []Tweak{tweak})
+	sig := signSession(t, signers, session)
+
+	ok, err := outputKey.VerifySchnorr(msg[:], sig)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A plain tweak followed by an x-only one also works.
+	plain := sha256.Sum256([]byte("plain"))
+	session = newSession(t, signers, msg[:], []Tweak{
+		{Tweak: plain[:]}, tweak,
+	})
+	sig = signSession(t, signers, session)
+
+	tweaked, err := ctx.ApplyTweak(plain[:], false)
+	require.NoError(t, err)
+	tweaked, err = tweaked.ApplyTweak(tweak.Tweak, true)
+	require.NoError(t, err)
+
+	ok, err = tweaked.Q.VerifySchnorr(msg[:], sig)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestInvalidPartialSig(t *testing.T) {
+	msg := sha256.Sum256([]byte("blame"))
+	signers := newTestSigners(t, 3)
+	session := newSession(t, signers, msg[:], nil)
+
+	var psigs [][]byte
+	for _, s := range signers {
+		psig, err := Sign(s.secNonce, s.sk, session)
+		require.NoError(t, err)
+
+		psigs = append(psigs, psig)
+	}
+
+	// A partial signature is only valid for the signer that made it.
+	require.Error(t, PartialSigVerify(
+		psigs[0], signers[1].pubNonce, signers[1].pk, session,
+	))
+
+	// A partial signature that exceeds the curve order is blamed on its
+	// signer during aggregation.
+	psigs[2] = bytes32(s256point.N)
+	_, err := PartialSigAgg(psigs, session)
+
+	var contribErr *InvalidContributionError
+	require.True(t, errors.As(err, &contribErr))
+	require.Equal(t, 2, contribErr.Signer)
+}
+
+func TestNonceReuse(t *testing.T) {
+	msg := sha256.Sum256([]byte("reuse"))
+	signers := newTestSigners(t, 2)
+	session := newSession(t, signers, msg[:], nil)
+
+	_, err := Sign(signers[0].secNonce, signers[0].sk, session)
+	require.NoError(t, err)
+
+	_, err = Sign(signers[0].secNonce, signers[0].sk, session)
+	require.True(t, errors.Is(err, ErrSecNonceReused))
+}
+
+func TestNonceAgg(t *testing.T) {
+	signers := newTestSigners(t, 2)
+
+	var pubNonces [][]byte
+	for _, s := range signers {
+		_, pubNonce, err := NonceGen(nil, s.pk, nil, nil, nil)
+		require.NoError(t, err)
+
+		pubNonces = append(pubNonces, pubNonce)
+	}
+
+	aggNonce, err := NonceAgg(pubNonces)
+	require.NoError(t, err)
+	require.Len(t, aggNonce, AggNonceSize)
+
+	// Nonces that cancel out aggregate to the encoding of infinity.
+	neg, err := cpoint(pubNonces[0][:33])
+	require.NoError(t, err)
+	neg, err = negate(neg)
+	require.NoError(t, err)
+
+	cancel := append(neg.Sec(true), pubNonces[1][33:]...)
+	aggNonce, err = NonceAgg([][]byte{pubNonces[0], cancel})
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, 33), aggNonce[:33])
+
+	// A truncated nonce is blamed on its signer.
+	_, err = NonceAgg([][]byte{pubNonces[0], pubNonces[1][:65]})
+
+	var contribErr *InvalidContributionError
+	require.True(t, errors.As(err, &contribErr))
+	require.Equal(t, 1, contribErr.Signer)
+}
+
+// loadVectors decodes the BIP327 test vectors in the named file of testdata.
+func loadVectors(t *testing.T, name string, v interface{}) {
+	b, err := ioutil.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, v))
+}
+
+// decodeAll decodes the hex strings at the given indices of all.
+func decodeAll(t *testing.T, all []string, indices []int) [][]byte {
+	res := make([][]byte, 0, len(indices))
+	for _, i := range indices {
+		res = append(res, mustDecode(t, all[i]))
+	}
+
+	return res
+}
+
+// optional decodes s, or returns nil if it is absent.
+func optional(t *testing.T, s *string) []byte {
+	if s == nil {
+		return nil
+	}
+
+	return mustDecode(t, *s)
+}
+
+// contribError is an expected error from the BIP327 test vectors.
+type contribError struct {
+	Type    string `json:"type"`
+	Signer  *int   `json:"signer"`
+	Contrib string `json:"contrib"`
+}
+
+// requireError checks that err matches the expected error. Errors that
+// blame a signer must be an InvalidContributionError for that signer.
+func requireError(t *testing.T, expected contribError, err error) {
+	require.Error(t, err)
+
+	if expected.Type != "invalid_contribution" {
+		return
+	}
+
+	var contribErr *InvalidContributionError
+	require.True(t, errors.As(err, &contribErr), err)
+	require.Equal(t, expected.Contrib, contribErr.Contrib)
+
+	signer := -1
+	if expected.Signer != nil {
+		signer = *expected.Signer
+	}
+	require.Equal(t, signer, contribErr.Signer)
+}
+
+func TestNonceGenVectors(t *testing.T) {
+	var vectors struct {
+		TestCases []struct {
+			Rand     string  `json:"rand_"`
+			Sk       *string `json:"sk"`
+			Pk       string  `json:"pk"`
+			AggPk    *string `json:"aggpk"`
+			Msg      *string `json:"msg"`
+			ExtraIn  *string `json:"extra_in"`
+			Expected string  `json:"expected"`
+		} `json:"test_cases"`
+	}
+	loadVectors(t, "nonce_gen_vectors.json", &vectors)
+
+	for i, test := range vectors.TestCases {
+		secNonce, pubNonce, err := nonceGen(
+			mustDecode(t, test.Rand), optional(t, test.Sk),
+			mustDecode(t, test.Pk), optional(t, test.AggPk),
+			optional(t, test.Msg), optional(t, test.ExtraIn),
+		)
+		require.NoError(t, err, i)
+		require.Equal(t, mustDecode(t, test.Expected), secNonce, i)
+		require.Len(t, pubNonce, PubNonceSize)
+	}
+}
+
+func TestNonceAggVectors(t *testing.T) {
+	var vectors struct {
+		PubNonces  []string `json:"pnonces"`
+		ValidCases []struct {
+			Indices  []int  `json:"pnonce_indices"`
+			Expected string `json:"expected"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			Indices []int        `json:"pnonce_indices"`
+			Error   contribError `json:"error"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "nonce_agg_vectors.json", &vectors)
+
+	for _, test := range vectors.ValidCases {
+		aggNonce, err := NonceAgg(
+			decodeAll(t, vectors.PubNonces, test.Indices),
+		)
+		require.NoError(t, err)
+		require.Equal(t, mustDecode(t, test.Expected), aggNonce)
+	}
+
+	for _, test := range vectors.ErrorCases {
+		_, err := NonceAgg(decodeAll(t, vectors.PubNonces, test.Indices))
+		requireError(t, test.Error, err)
+	}
+}
+
+func TestSignVerifyVectors(t *testing.T) {
+	var vectors struct {
+		Sk         string   `json:"sk"`
+		PubKeys    []string `json:"pubkeys"`
+		SecNonces  []string `json:"secnonces"`
+		PubNonces  []string `json:"pnonces"`
+		AggNonces  []string `json:"aggnonces"`
+		Msgs       []string `json:"msgs"`
+		ValidCases []struct {
+			KeyIndices    []int  `json:"key_indices"`
+			NonceIndices  []int  `json:"nonce_indices"`
+			AggNonceIndex int    `json:"aggnonce_index"`
+			MsgIndex      int    `json:"msg_index"`
+			SignerIndex   int    `json:"signer_index"`
+			Expected      string `json:"expected"`
+		} `json:"valid_test_cases"`
+		SignErrorCases []struct {
+			KeyIndices    []int        `json:"key_indices"`
+			AggNonceIndex int          `json:"aggnonce_index"`
+			MsgIndex      int          `json:"msg_index"`
+			SecNonceIndex int          `json:"secnonce_index"`
+			Error         contribError `json:"error"`
+		} `json:"sign_error_test_cases"`
+		VerifyFailCases []struct {
+			Sig          string `json:"sig"`
+			KeyIndices   []int  `json:"key_indices"`
+			NonceIndices []int  `json:"nonce_indices"`
+			MsgIndex     int    `json:"msg_index"`
+			SignerIndex  int    `json:"signer_index"`
+		} `json:"verify_fail_test_cases"`
+		VerifyErrorCases []struct {
+			Sig          string       `json:"sig"`
+			KeyIndices   []int        `json:"key_indices"`
+			NonceIndices []int        `json:"nonce_indices"`
+			MsgIndex     int          `json:"msg_index"`
+			SignerIndex  int          `json:"signer_index"`
+			Error        contribError `json:"error"`
+		} `json:"verify_error_test_cases"`
+	}
+	loadVectors(t, "sign_verify_vectors.json", &vectors)
+
+	sk := mustDecode(t, vectors.Sk)
+
+	// The secret nonce is wiped by Sign so each signature needs a copy.
+	secNonce := func(i int) []byte {
+		return mustDecode(t, vectors.SecNonces[i])
+	}
+
+	for i, test := range vectors.ValidCases {
+		pubNonces := decodeAll(t, vectors.PubNonces, test.NonceIndices)
+		aggNonce := mustDecode(t, vectors.AggNonces[test.AggNonceIndex])
+
+		got, err := NonceAgg(pubNonces)
+		require.NoError(t, err, i)
+		require.Equal(t, aggNonce, got, i)
+
+		session := &SessionContext{
+			AggNonce: aggNonce,
+			PubKeys:  decodeAll(t, vectors.PubKeys, test.KeyIndices),
+			Msg:      mustDecode(t, vectors.Msgs[test.MsgIndex]),
+		}
+
+		psig, err := Sign(secNonce(0), sk, session)
+		require.NoError(t, err, i)
+		require.Equal(t, mustDecode(t, test.Expected), psig, i)
+
+		require.NoError(t, PartialSigVerify(
+			psig, pubNonces[test.SignerIndex],
+			session.PubKeys[test.SignerIndex], session,
+		), i)
+	}
+
+	for _, test := range vectors.SignErrorCases {
+		session := &SessionContext{
+			AggNonce: mustDecode(t, vectors.AggNonces[test.AggNonceIndex]),
+			PubKeys:  decodeAll(t, vectors.PubKeys, test.KeyIndices),
+			Msg:      mustDecode(t, vectors.Msgs[test.MsgIndex]),
+		}
+
+		_, err := Sign(secNonce(test.SecNonceIndex), sk, session)
+		requireError(t, test.Error, err)
+	}
+
+	for i, test := range vectors.VerifyFailCases {
+		pubNonces := decodeAll(t, vectors.PubNonces, test.NonceIndices)
+		aggNonce, err := NonceAgg(pubNonces)
+		require.NoError(t, err, i)
+
+		session := &SessionContext{
+			AggNonce: aggNonce,
+			PubKeys:  decodeAll(t, vectors.PubKeys, test.KeyIndices),
+			Msg:      mustDecode(t, vectors.Msgs[test.MsgIndex]),
+		}
+
+		require.Error(t, PartialSigVerify(
+			mustDecode(t, test.Sig), pubNonces[test.SignerIndex],
+			session.PubKeys[test.SignerIndex], session,
+		), i)
+	}
+
+	for _, test := range vectors.VerifyErrorCases {
+		pubNonces := decodeAll(t, vectors.PubNonces, test.NonceIndices)
+
+		// The aggregate nonce is not used since decoding one of the
+		// inputs fails first.
+		session := &SessionContext{
+			AggNonce: mustDecode(t, vectors.AggNonces[0]),
+			PubKeys:  decodeAll(t, vectors.PubKeys, test.KeyIndices),
+			Msg:      mustDecode(t, vectors.Msgs[test.MsgIndex]),
+		}
+
+		err := PartialSigVerify(
+			mustDecode(t, test.Sig), pubNonces[test.SignerIndex],
+			session.PubKeys[test.SignerIndex], session,
+		)
+		requireError(t, test.Error, err)
+	}
+}
+
+func TestTweakVectors(t *testing.T) {
+	var vectors struct {
+		Sk         string   `json:"sk"`
+		PubKeys    []string `json:"pubkeys"`
+		SecNonce   string   `json:"secnonce"`
+		PubNonces  []string `json:"pnonces"`
+		AggNonce   string   `json:"aggnonce"`
+		Tweaks     []string `json:"tweaks"`
+		Msg        string   `json:"msg"`
+		ValidCases []struct {
+			KeyIndices   []int  `json:"key_indices"`
+			NonceIndices []int  `json:"nonce_indices"`
+			TweakIndices []int  `json:"tweak_indices"`
+			IsXOnly      []bool `json:"is_xonly"`
+			SignerIndex  int    `json:"signer_index"`
+			Expected     string `json:"expected"`
+		} `json:"valid_test_cases"`
+		ErrorCases []struct {
+			KeyIndices   []int        `json:"key_indices"`
+			NonceIndices []int        `json:"nonce_indices"`
+			TweakIndices []int        `json:"tweak_indices"`
+			IsXOnly      []bool       `json:"is_xonly"`
+			SignerIndex  int          `json:"signer_index"`
+			Error        contribError `json:"error"`
+		} `json:"error_test_cases"`
+	}
+	loadVectors(t, "tweak_vectors.json", &vectors)
+
+	sk := mustDecode(t, vectors.Sk)
+
+	newSession := func(keys, tweakIndices []int,
+		isXOnly []bool) *SessionContext {
+
+		var tweaks []Tweak
+		for i, j := range tweakIndices {
+			tweaks = append(tweaks, Tweak{
+				Tweak:   mustDecode(t, vectors.Tweaks[j]),
+				IsXOnly: isXOnly[i],
+			})
+		}
+
+		return &SessionContext{
+			AggNonce: mustDecode(t, vectors.AggNonce),
+			PubKeys:  decodeAll(t, vectors.PubKeys, keys),
+			Tweaks:   tweaks,
+			Msg:      mustDecode(t, vectors.Msg),
+		}
+	}
+
+	for i, test := range vectors.ValidCases {
+		session := newSession(
+			test.KeyIndices, test.TweakIndices, test.IsXOnly,
+		)
+
+		psig, err := Sign(mustDecode(t, vectors.SecNonce), sk, session)
+		require.NoError(t, err, i)
+		require.Equal(t, mustDecode(t, test.Expected), psig, i)
+
+		pubNonces := decodeAll(t, vectors.PubNonces, test.NonceIndices)
+		require.NoError(t, PartialSigVerify(
+			psig, pubNonces[test.SignerIndex],
+			session.PubKeys[test.SignerIndex], session,
+		), i)
+	}
+
+	for _, test := range vectors.ErrorCases {
+		session := newSession(
+			test.KeyIndices, test.TweakIndices, test.IsXOnly,
+		)
+
+		_, err := Sign(mustDecode(t, vectors.SecNonce), sk, session)
+		requireError(t, test.Error, err)
+	}
+}
@@ -0,0 +1,135 @@
+package musig2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+const (
+	tagAux   = "MuSig/aux"
+	tagNonce = "MuSig/nonce"
+)
+
+// NonceGen generates a secret and public nonce for a signing session. Only
+// the public key of the signer is required. The secret key, x-only aggregate
+// key, message and extra input are all optional (pass nil to omit them) but
+// providing them adds defence in depth should the random number generator be
+// weak. A nil message is treated as absent whereas an empty one is not.
+//
+// The secret nonce must be kept private and used for exactly one signature.
+// The public nonce is sent to the other signers.
+func NonceGen(sk, pk, aggPk, msg, extraIn []byte) ([]byte, []byte, error) {
+	randPrime := make([]byte, 32)
+	if _, err := rand.Read(randPrime); err != nil {
+		return nil, nil, err
+	}
+
+	return nonceGen(randPrime, sk, pk, aggPk, msg, extraIn)
+}
+
+func nonceGen(randPrime, sk, pk, aggPk, msg, extraIn []byte) ([]byte,
+	[]byte, error) {
+
+	if len(pk) != PubKeySize {
+		return nil, nil, errors.New("public key must be 33 bytes")
+	}
+
+	if sk != nil && len(sk) != 32 {
+		return nil, nil, errors.New("secret key must be 32 bytes")
+	}
+
+	if aggPk != nil && len(aggPk) != 32 {
+		return nil, nil, errors.New("aggregate public key must be 32 " +
+			"bytes")
+	}
+
+	randBytes := randPrime
+	if sk != nil {
+		randBytes = helpers.TaggedHash(tagAux, randPrime)
+		for i := range randBytes {
+			randBytes[i] ^= sk[i]
+		}
+	}
+
+	msgPrefixed := []byte{0}
+	if msg != nil {
+		msgLen := make([]byte, 8)
+		binary.BigEndian.PutUint64(msgLen, uint64(len(msg)))
+
+		msgPrefixed = append([]byte{1}, msgLen...)
+		msgPrefixed = append(msgPrefixed, msg...)
+	}
+
+	extraLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(extraLen, uint32(len(extraIn)))
+
+	secNonce := make([]byte, 0, SecNonceSize)
+	pubNonce := make([]byte, 0, PubNonceSize)
+	for i := byte(0); i < 2; i++ {
+		h := helpers.TaggedHash(tagNonce, randBytes,
+			[]byte{byte(len(pk))}, pk,
+			[]byte{byte(len(aggPk))}, aggPk,
+			msgPrefixed, extraLen, extraIn, []byte{i})
+
+		var k secp256k1.Scalar
+		k.SetByteSlice(h)
+		zero(h)
+		if k.IsZero() {
+			return nil, nil, errors.New("derived nonce is zero")
+		}
+
+		R, err := baseMult(&k)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kb := k.Bytes()
+		secNonce = append(secNonce, kb[:]...)
+		pubNonce = append(pubNonce, R.Sec(true)...)
+		zero(kb[:])
+	}
+	secNonce = append(secNonce, pk...)
+
+	return secNonce, pubNonce, nil
+}
+
+// NonceAgg aggregates the public nonces of all signers into the aggregate
+// nonce used by the signing session.
+func NonceAgg(pubNonces [][]byte) ([]byte, error) {
+	aggNonce := make([]byte, 0, AggNonceSize)
+	for j := 0; j < 2; j++ {
+		sum, err := s256point.New(nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, pubNonce := range pubNonces {
+			if len(pubNonce) != PubNonceSize {
+				return nil, &InvalidContributionError{
+					Signer: i, Contrib: "pubnonce",
+				}
+			}
+
+			R, err := cpoint(pubNonce[j*33 : (j+1)*33])
+			if err != nil {
+				return nil, &InvalidContributionError{
+					Signer: i, Contrib: "pubnonce",
+				}
+			}
+
+			sum, err = add(sum, R)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		aggNonce = append(aggNonce, cbytesExt(sum)...)
+	}
+
+	return aggNonce, nil
+}
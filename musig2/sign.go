@@ -0,0 +1,317 @@
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+const tagNonceCoeff = "MuSig/noncecoef"
+
+// Tweak is a tweak applied to the aggregate key.
+type Tweak struct {
+	Tweak   []byte
+	IsXOnly bool
+}
+
+// SessionContext holds everything that the signers of a session must agree
+// on.
+type SessionContext struct {
+	AggNonce []byte
+	PubKeys  [][]byte
+	Tweaks   []Tweak
+	Msg      []byte
+}
+
+// sessionValues holds the values derived from a session context.
+type sessionValues struct {
+	keyAgg *KeyAggContext
+	b      *big.Int
+	r      *s256point.S256Point
+	e      *big.Int
+}
+
+func (s *SessionContext) values() (*sessionValues, error) {
+	if len(s.AggNonce) != AggNonceSize {
+		return nil, errors.New("aggregate nonce must be 66 bytes")
+	}
+
+	keyAgg, err := KeyAgg(s.PubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range s.Tweaks {
+		keyAgg, err = keyAgg.ApplyTweak(t.Tweak, t.IsXOnly)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	q := keyAgg.XOnlyPubKey()
+
+	b := new(big.Int).SetBytes(
+		helpers.TaggedHash(tagNonceCoeff, s.AggNonce, q, s.Msg),
+	)
+	b.Mod(b, s256point.N)
+
+	r1, err := cpointExt(s.AggNonce[:33])
+	if err != nil {
+		return nil, &InvalidContributionError{Signer: -1, Contrib: "aggnonce"}
+	}
+
+	r2, err := cpointExt(s.AggNonce[33:])
+	if err != nil {
+		return nil, &InvalidContributionError{Signer: -1, Contrib: "aggnonce"}
+	}
+
+	r, err := s256point.MultiScalarMul(
+		[]*s256point.S256Point{r1, r2}, []*big.Int{big.NewInt(1), b},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the nonces of the signers cancel out, G is used instead so that
+	// the session can still complete.
+	if r.IsInfinity() {
+		r = s256point.G
+	}
+
+	e := new(big.Int).SetBytes(schnorr.ChallengeHash(r.XOnly(), q, s.Msg))
+	e.Mod(e, s256point.N)
+
+	return &sessionValues{keyAgg: keyAgg, b: b, r: r, e: e}, nil
+}
+
+// keyAggCoeff returns the key aggregation coefficient of the public key
+// which must be one of the keys of the session.
+func (s *SessionContext) keyAggCoeff(pk []byte) (*big.Int, error) {
+	if s.signerIndex(pk) < 0 {
+		return nil, errors.New("public key is not part of the session")
+	}
+
+	return keyAggCoeff(s.PubKeys, pk), nil
+}
+
+// signerIndex returns the position of the public key in the session, or -1
+// if it is not part of the session.
+func (s *SessionContext) signerIndex(pk []byte) int {
+	for i, k := range s.PubKeys {
+		if bytes.Equal(k, pk) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Sign creates a partial signature for the session. The secret nonce is
+// zeroed so that it can't be used again, since signing twice with the same
+// nonce reveals the secret key. The secret nonce and key are only handled as
+// constant time scalars.
+func Sign(secNonce, sk []byte, session *SessionContext) ([]byte, error) {
+	if len(secNonce) != SecNonceSize {
+		return nil, errors.New("secret nonce must be 97 bytes")
+	}
+
+	v, err := session.values()
+	if err != nil {
+		return nil, err
+	}
+
+	var k1, k2 secp256k1.Scalar
+	ok1 := secretScalar(&k1, secNonce[:32])
+	ok2 := secretScalar(&k2, secNonce[32:64])
+	if !ok1 || !ok2 {
+		return nil, errors.New("secret nonce out of range")
+	}
+	if k1.IsZero() || k2.IsZero() {
+		return nil, ErrSecNonceReused
+	}
+
+	// Wipe the secret nonce now that it has been read.
+	zero(secNonce[:64])
+
+	R1, err := baseMult(&k1)
+	if err != nil {
+		return nil, err
+	}
+
+	R2, err := baseMult(&k2)
+	if err != nil {
+		return nil, err
+	}
+	pubNonce := append(R1.Sec(true), R2.Sec(true)...)
+
+	k1.CondNeg(!v.r.HasEvenY())
+	k2.CondNeg(!v.r.HasEvenY())
+
+	var dPrime secp256k1.Scalar
+	if !secretScalar(&dPrime, sk) || dPrime.IsZero() {
+		return nil, errors.New("invalid secret key")
+	}
+
+	P, err := baseMult(&dPrime)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := P.Sec(true)
+	if !bytes.Equal(pk, secNonce[64:]) {
+		return nil, errors.New("public key does not match the nonce")
+	}
+
+	a, err := session.keyAggCoeff(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	// d = g*gacc*d', where g and gacc are public and either 1 or -1.
+	var d secp256k1.Scalar
+	d.Mul(publicScalar(v.keyAgg.gacc), &dPrime)
+	d.CondNeg(!v.keyAgg.Q.HasEvenY())
+
+	ea := new(big.Int).Mul(v.e, a)
+	ea.Mod(ea, s256point.N)
+
+	// s = k1 + b*k2 + e*a*d
+	var s, bk2 secp256k1.Scalar
+	s.Mul(publicScalar(ea), &d)
+	s.Add(&s, &k1)
+	s.Add(&s, bk2.Mul(publicScalar(v.b), &k2))
+
+	sb := s.Bytes()
+	psig := sb[:]
+
+	// Verifying our own partial signature guards against faults that
+	// could otherwise leak the secret key.
+	if err := PartialSigVerify(psig, pubNonce, pk, session); err != nil {
+		return nil, err
+	}
+
+	return psig, nil
+}
+
+// PartialSigVerify checks the partial signature of the signer with the given
+// public nonce and public key.
+func PartialSigVerify(psig, pubNonce, pk []byte,
+	session *SessionContext) error {
+
+	n := s256point.N
+
+	v, err := session.values()
+	if err != nil {
+		return err
+	}
+
+	s, ok := scalar(psig)
+	if len(psig) != PartialSigSize || !ok {
+		return errors.New("partial signature out of range")
+	}
+
+	// An invalid nonce or key is blamed on the signer being verified.
+	signer := session.signerIndex(pk)
+
+	if len(pubNonce) != PubNonceSize {
+		return &InvalidContributionError{Signer: signer, Contrib: "pubnonce"}
+	}
+
+	r1, err := cpoint(pubNonce[:33])
+	if err != nil {
+		return &InvalidContributionError{Signer: signer, Contrib: "pubnonce"}
+	}
+
+	r2, err := cpoint(pubNonce[33:])
+	if err != nil {
+		return &InvalidContributionError{Signer: signer, Contrib: "pubnonce"}
+	}
+
+	P, err := cpoint(pk)
+	if err != nil {
+		return &InvalidContributionError{Signer: signer, Contrib: "pubkey"}
+	}
+
+	a, err := session.keyAggCoeff(pk)
+	if err != nil {
+		return err
+	}
+
+	g := big.NewInt(1)
+	if !v.keyAgg.Q.HasEvenY() {
+		g = negOne()
+	}
+
+	// Check s*G = Re + e*a*g*gacc*P where Re = R1 + b*R2, negated if the
+	// session nonce has an odd y. This is done by checking that
+	// s*G - Re - e*a*g*gacc*P is the point at infinity.
+	sign := big.NewInt(1)
+	if !v.r.HasEvenY() {
+		sign = negOne()
+	}
+
+	eag := new(big.Int).Mul(v.e, a)
+	eag.Mul(eag, g)
+	eag.Mul(eag, v.keyAgg.gacc)
+
+	negR1 := new(big.Int).Sub(n, sign)
+	negR2 := new(big.Int).Mul(sign, v.b)
+	negR2.Sub(n, negR2.Mod(negR2, n))
+	negP := new(big.Int).Sub(n, eag.Mod(eag, n))
+
+	res, err := s256point.MultiScalarMul(
+		[]*s256point.S256Point{s256point.G, r1, r2, P},
+		[]*big.Int{s, negR1, negR2, negP},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !res.IsInfinity() {
+		return errors.New("invalid partial signature")
+	}
+
+	return nil
+}
+
+// PartialSigAgg combines the partial signatures of all signers into the final
+// BIP340 signature, which is valid for the x-only aggregate key.
+func PartialSigAgg(psigs [][]byte,
+	session *SessionContext) (*schnorr.Signature, error) {
+
+	n := s256point.N
+
+	v, err := session.values()
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(big.Int)
+	for i, psig := range psigs {
+		si, ok := scalar(psig)
+		if len(psig) != PartialSigSize || !ok {
+			return nil, &InvalidContributionError{
+				Signer: i, Contrib: "psig",
+			}
+		}
+		s.Add(s, si)
+	}
+
+	g := big.NewInt(1)
+	if !v.keyAgg.Q.HasEvenY() {
+		g = negOne()
+	}
+
+	// s = sum(s_i) + e*g*tacc
+	egt := new(big.Int).Mul(v.e, g)
+	egt.Mul(egt, v.keyAgg.tacc)
+	s.Add(s, egt)
+	s.Mod(s, n)
+
+	return schnorr.New(v.r.GetX().GetNum(), s), nil
+}
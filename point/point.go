@@ -4,14 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"reflect"
 
 	"github.com/ellemouton/btc/fieldelement"
 )
 
 type Point interface {
 	Add(Point) (Point, error)
+	Sub(Point) (Point, error)
+	Neg() (Point, error)
+	Double() (Point, error)
 	Mul(*big.Int) (Point, error)
+	Equal(Point) bool
+	IsInfinity() bool
+	IsOnCurve() bool
 	Copy() Point
 	String() string
 	GetA() fieldelement.FieldElement
@@ -28,50 +33,84 @@ type point struct {
 }
 
 func New(x, y, a, b fieldelement.FieldElement) (Point, error) {
+	p := &point{
+		X: x,
+		Y: y,
+		A: a,
+		B: b,
+	}
+
 	if x == nil && y == nil {
-		return &point{
-			X: nil,
-			Y: nil,
-			A: a,
-			B: b,
-		}, nil
+		return p, nil
+	}
+
+	if x == nil || y == nil || !p.IsOnCurve() {
+		return nil, errors.New(fmt.Sprintf("(%d, %d) is not on the curve", x, y))
+	}
+
+	return p, nil
+}
+
+// IsOnCurve returns true if the point satisfies y^2 = x^3 + ax + b. The point
+// at infinity is always on the curve.
+func (p *point) IsOnCurve() bool {
+	if p.IsInfinity() {
+		return true
+	}
+
+	if p.X == nil || p.Y == nil {
+		return false
 	}
 
-	t1, err := y.Pow(big.NewInt(2))
+	lhs, err := p.Y.Pow(big.NewInt(2))
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	t2, err := x.Pow(big.NewInt(3))
+	rhs, err := p.X.Pow(big.NewInt(3))
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	t3, err := a.Mul(x)
+	ax, err := p.A.Mul(p.X)
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	t4, err := t2.Add(t3)
+	rhs, err = rhs.Add(ax)
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	t5, err := t4.Add(b)
+	rhs, err = rhs.Add(p.B)
 	if err != nil {
-		return nil, err
+		return false
 	}
 
-	if !reflect.DeepEqual(t1, t5) {
-		return nil, errors.New(fmt.Sprintf("(%d, %d) is not on the curve", x, y))
+	return lhs.Equal(rhs)
+}
+
+// IsInfinity returns true if p is the point at infinity, which is the
+// identity of the group.
+func (p *point) IsInfinity() bool {
+	return p.X == nil && p.Y == nil
+}
+
+// Equal returns true if p and o are the same point on the same curve.
+func (p *point) Equal(o Point) bool {
+	if !p.sameCurve(o) {
+		return false
 	}
 
-	return &point{
-		X: x,
-		Y: y,
-		A: a,
-		B: b,
-	}, nil
+	if p.IsInfinity() || o.IsInfinity() {
+		return p.IsInfinity() && o.IsInfinity()
+	}
+
+	return p.X.Equal(o.GetX()) && p.Y.Equal(o.GetY())
+}
+
+func (p *point) sameCurve(o Point) bool {
+	return p.A.Equal(o.GetA()) && p.B.Equal(o.GetB())
 }
 
 func (p *point) GetA() fieldelement.FieldElement {
@@ -91,17 +130,17 @@ func (p *point) GetY() fieldelement.FieldElement {
 }
 
 func (p *point) Copy() Point {
-	a := p.A.Copy()
-	b := p.B.Copy()
-	x := p.X.Copy()
-	y := p.Y.Copy()
+	c := &point{
+		A: p.A.Copy(),
+		B: p.B.Copy(),
+	}
 
-	return &point{
-		A: a,
-		B: b,
-		X: x,
-		Y: y,
+	if !p.IsInfinity() {
+		c.X = p.X.Copy()
+		c.Y = p.Y.Copy()
 	}
+
+	return c
 }
 
 func (p *point) String() string {
@@ -109,11 +148,11 @@ func (p *point) String() string {
 }
 
 func (p *point) Add(o Point) (Point, error) {
-	if !reflect.DeepEqual(p.A, o.GetA()) || !reflect.DeepEqual(p.B, o.GetB()) {
+	if !p.sameCurve(o) {
 		return nil, errors.New(fmt.Sprintf("points %v, %v are not on the same curve", p, o))
 	}
 
-	if p.X == nil {
+	if p.IsInfinity() {
 		return &point{
 			X: o.GetX(),
 			Y: o.GetY(),
@@ -122,7 +161,7 @@ func (p *point) Add(o Point) (Point, error) {
 		}, nil
 	}
 
-	if o.GetX() == nil {
+	if o.IsInfinity() {
 		return &point{
 			X: p.X,
 			Y: p.Y,
@@ -131,7 +170,7 @@ func (p *point) Add(o Point) (Point, error) {
 		}, nil
 	}
 
-	if reflect.DeepEqual(p.X, o.GetX()) && !reflect.DeepEqual(p.Y, o.GetY()) {
+	if p.X.Equal(o.GetX()) && !p.Y.Equal(o.GetY()) {
 		return &point{
 			X: nil,
 			Y: nil,
@@ -140,7 +179,7 @@ func (p *point) Add(o Point) (Point, error) {
 		}, nil
 	}
 
-	if reflect.DeepEqual(p.X, o.GetX()) && p.Y.GetNum().Sign() == 0 {
+	if p.X.Equal(o.GetX()) && p.Y.GetNum().Sign() == 0 {
 		return &point{
 			X: nil,
 			Y: nil,
@@ -149,7 +188,7 @@ func (p *point) Add(o Point) (Point, error) {
 		}, nil
 	}
 
-	if !reflect.DeepEqual(p.X, o.GetX()) {
+	if !p.X.Equal(o.GetX()) {
 		s1, err := (o.GetY()).Sub(p.Y)
 		if err != nil {
 			return nil, err
@@ -272,6 +311,46 @@ func (p *point) Add(o Point) (Point, error) {
 	}, nil
 }
 
+// Neg returns -p, the point with the same x coordinate and the negated y
+// coordinate.
+func (p *point) Neg() (Point, error) {
+	if p.IsInfinity() {
+		return p.Copy(), nil
+	}
+
+	zero, err := p.Y.Sub(p.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := zero.Sub(p.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	return &point{
+		X: p.X,
+		Y: y,
+		A: p.A,
+		B: p.B,
+	}, nil
+}
+
+// Sub returns p - o.
+func (p *point) Sub(o Point) (Point, error) {
+	neg, err := o.Neg()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Add(neg)
+}
+
+// Double returns p + p.
+func (p *point) Double() (Point, error) {
+	return p.Add(p)
+}
+
 func (p *point) Mul(c *big.Int) (Point, error) {
 	coef := new(big.Int).Set(c)
 	current := p.Copy()
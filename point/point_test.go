@@ -310,3 +310,123 @@ func TestMul2(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, p3, p2)
 }
+
+// newPoint223 returns the point (x, y) on y^2 = x^3 + 7 over F_223, or the
+// point at infinity if x and y are negative.
+func newPoint223(t *testing.T, x, y int64) Point {
+	prime := big.NewInt(223)
+
+	a, err := fieldelement.New(big.NewInt(0), prime)
+	require.NoError(t, err)
+
+	b, err := fieldelement.New(big.NewInt(7), prime)
+	require.NoError(t, err)
+
+	if x < 0 && y < 0 {
+		p, err := New(nil, nil, a, b)
+		require.NoError(t, err)
+
+		return p
+	}
+
+	xf, err := fieldelement.New(big.NewInt(x), prime)
+	require.NoError(t, err)
+
+	yf, err := fieldelement.New(big.NewInt(y), prime)
+	require.NoError(t, err)
+
+	p, err := New(xf, yf, a, b)
+	require.NoError(t, err)
+
+	return p
+}
+
+func TestNegSub(t *testing.T) {
+	p1 := newPoint223(t, 192, 105)
+	p2 := newPoint223(t, 17, 56)
+	p3 := newPoint223(t, 170, 142)
+	inf := newPoint223(t, -1, -1)
+
+	neg, err := p1.Neg()
+	require.NoError(t, err)
+	require.True(t, neg.Equal(newPoint223(t, 192, 223-105)))
+	require.True(t, neg.IsOnCurve())
+
+	sum, err := p1.Add(neg)
+	require.NoError(t, err)
+	require.True(t, sum.IsInfinity())
+
+	diff, err := p3.Sub(p2)
+	require.NoError(t, err)
+	require.True(t, diff.Equal(p1))
+
+	diff, err = p1.Sub(p1)
+	require.NoError(t, err)
+	require.True(t, diff.Equal(inf))
+
+	neg, err = inf.Neg()
+	require.NoError(t, err)
+	require.True(t, neg.IsInfinity())
+}
+
+func TestDouble(t *testing.T) {
+	p := newPoint223(t, 192, 105)
+
+	d, err := p.Double()
+	require.NoError(t, err)
+	require.True(t, d.Equal(newPoint223(t, 49, 71)))
+
+	m, err := p.Mul(big.NewInt(2))
+	require.NoError(t, err)
+	require.True(t, d.Equal(m))
+
+	d, err = newPoint223(t, -1, -1).Double()
+	require.NoError(t, err)
+	require.True(t, d.IsInfinity())
+}
+
+func TestEqual(t *testing.T) {
+	p1 := newPoint223(t, 192, 105)
+	p2 := newPoint223(t, 17, 56)
+	inf := newPoint223(t, -1, -1)
+
+	require.True(t, p1.Equal(p1.Copy()))
+	require.False(t, p1.Equal(p2))
+	require.False(t, p1.Equal(inf))
+	require.False(t, inf.Equal(p1))
+	require.True(t, inf.Equal(inf.Copy()))
+	require.True(t, inf.IsOnCurve())
+
+	// Points on different curves are never equal.
+	prime := big.NewInt(223)
+	a, err := fieldelement.New(big.NewInt(0), prime)
+	require.NoError(t, err)
+	b, err := fieldelement.New(big.NewInt(5), prime)
+	require.NoError(t, err)
+
+	other, err := New(nil, nil, a, b)
+	require.NoError(t, err)
+	require.False(t, inf.Equal(other))
+}
+
+func TestIsOnCurve(t *testing.T) {
+	prime := big.NewInt(223)
+	a, err := fieldelement.New(big.NewInt(0), prime)
+	require.NoError(t, err)
+	b, err := fieldelement.New(big.NewInt(7), prime)
+	require.NoError(t, err)
+	x, err := fieldelement.New(big.NewInt(200), prime)
+	require.NoError(t, err)
+	y, err := fieldelement.New(big.NewInt(119), prime)
+	require.NoError(t, err)
+
+	_, err = New(x, y, a, b)
+	require.Error(t, err)
+
+	_, err = New(x, nil, a, b)
+	require.Error(t, err)
+
+	off := &point{X: x, Y: y, A: a, B: b}
+	require.False(t, off.IsOnCurve())
+	require.True(t, newPoint223(t, 192, 105).IsOnCurve())
+}
@@ -4,87 +4,202 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
 	"math/big"
 
 	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/secp256k1"
 	"github.com/ellemouton/btc/signature"
 )
 
+// KeySize is the size of a serialized private key.
+const KeySize = 32
+
+// ErrInvalidKey is returned when a serialized private key is not a number
+// between 1 and N-1.
+var ErrInvalidKey = errors.New("invalid private key")
+
 type PrivateKey struct {
-	secret *big.Int
+	secret secp256k1.Scalar
 	PubKey *s256point.S256Point
+
+	// Compressed is true if the public key should be serialized in its
+	// compressed form, which is how wallets know which addresses belong
+	// to a key. It is true for new keys and is set from the WIF encoding
+	// when a key is imported.
+	Compressed bool
 }
 
+// New returns the private key with the given secret, which is reduced modulo
+// the curve order and must fit in 256 bits. The secret is copied straight
+// into the constant-time secp256k1 scalar type, which all operations that
+// involve it use. Callers that hold the secret as bytes should use FromBytes
+// so it never becomes a big.Int.
 func New(s *big.Int) (*PrivateKey, error) {
-	p, err := s256point.G.Mul(s)
+	if s.Sign() < 0 {
+		return nil, errors.New("private key is negative")
+	}
+
+	if s.BitLen() > 8*KeySize {
+		return nil, fmt.Errorf("%w: larger than %d bits", ErrInvalidKey,
+			8*KeySize)
+	}
+
+	var (
+		buf    [KeySize]byte
+		secret secp256k1.Scalar
+	)
+	s.FillBytes(buf[:])
+	secret.SetBytes(&buf)
+	zero(buf[:])
+
+	return newFromScalar(&secret)
+}
+
+func newFromScalar(secret *secp256k1.Scalar) (*PrivateKey, error) {
+	if secret.IsZero() {
+		return nil, errors.New("private key is zero")
+	}
+
+	var p secp256k1.JacobianPoint
+	pubKey, err := s256point.FromJacobian(p.ScalarBaseMult(secret))
 	if err != nil {
 		return nil, err
 	}
 
-	return &PrivateKey{
-		secret: s,
-		PubKey: p.(*s256point.S256Point),
-	}, nil
+	priv := &PrivateKey{PubKey: pubKey, Compressed: true}
+	priv.secret.Set(secret)
+
+	return priv, nil
+}
+
+// FromBytes returns the private key with the given 32 byte big endian secret.
+// Unlike New the secret is not reduced, so it must be between 1 and N-1.
+func FromBytes(b []byte) (*PrivateKey, error) {
+	if len(b) != KeySize {
+		return nil, fmt.Errorf("%w: must be %d bytes, got %d",
+			ErrInvalidKey, KeySize, len(b))
+	}
+
+	var (
+		buf    [KeySize]byte
+		secret secp256k1.Scalar
+	)
+	copy(buf[:], b)
+	overflow := secret.SetBytes(&buf)
+	zero(buf[:])
+	if overflow || secret.IsZero() {
+		return nil, fmt.Errorf("%w: out of range", ErrInvalidKey)
+	}
+
+	return newFromScalar(&secret)
+}
+
+// Bytes returns the 32 byte big endian secret.
+func (p *PrivateKey) Bytes() []byte {
+	b := p.secret.Bytes()
+	return b[:]
+}
+
+// PubKeySec returns the SEC encoding of the public key, compressed or not as
+// given by p.Compressed.
+func (p *PrivateKey) PubKeySec() []byte {
+	return p.PubKey.Sec(p.Compressed)
 }
 
 func (p *PrivateKey) Hex() string {
-	return fmt.Sprintf("%064x", p.secret)
+	b := p.secret.Bytes()
+	return hex.EncodeToString(b[:])
 }
 
 func (p *PrivateKey) Sign(hash []byte) (*signature.Signature, error) {
+	sig, _, err := p.SignRecoverable(hash)
+	return sig, err
+}
+
+// SignRecoverable signs the hash and also returns the recovery id that lets
+// s256point.RecoverPubKey recover the public key from the signature.
+func (p *PrivateKey) SignRecoverable(hash []byte) (*signature.Signature, byte,
+	error) {
+
 	k := p.DeterministicK(hash)
 
-	z := hashToInt(hash, s256point.N)
+	var z secp256k1.Scalar
+	z.SetByteSlice(hashToInt(hash, s256point.N).Bytes())
 
-	R, err := s256point.G.Mul(k)
-	if err != nil {
-		return nil, err
+	var R secp256k1.JacobianPoint
+	if !R.ScalarBaseMult(k).ToAffine() {
+		return nil, 0, errors.New("nonce point is the point at infinity")
 	}
 
-	r := R.GetX().GetNum()
+	// r is the x coordinate of R reduced modulo N.
+	rx := R.X.Bytes()
+	var r secp256k1.Scalar
+	overflow := r.SetBytes(&rx)
 
-	exp := &big.Int{}
-	exp.Sub(s256point.N, big.NewInt(2))
+	var recID byte
+	if R.Y.IsOdd() {
+		recID |= 1
+	}
+	if overflow {
+		recID |= 2
+	}
 
-	kInv := &big.Int{}
-	kInv.Exp(k, exp, s256point.N)
+	// s = k^-1 * (z + r*d)
+	var s, kInv secp256k1.Scalar
+	s.Mul(&r, &p.secret)
+	s.Add(&s, &z)
+	s.Mul(&s, kInv.Inverse(k))
+
+	// Negating s corresponds to negating R, which flips the parity of its
+	// y coordinate.
+	if s.IsHigh() {
+		s.CondNeg(true)
+		recID ^= 1
+	}
 
-	s := &big.Int{}
-	s.Mul(r, p.secret)
-	s.Add(s, z)
-	s.Mul(s, kInv)
-	s.Mod(s, s256point.N)
+	rb, sb := r.Bytes(), s.Bytes()
+
+	return &signature.Signature{
+		Rx: new(big.Int).SetBytes(rb[:]),
+		S:  new(big.Int).SetBytes(sb[:]),
+	}, recID, nil
+}
 
-	halfN := &big.Int{}
-	halfN.Div(s256point.N, big.NewInt(2))
+// SignCompact signs the hash and returns the 65 byte compact recoverable
+// signature. Compressed records whether the public key should be recovered
+// in its compressed encoding.
+func (p *PrivateKey) SignCompact(hash []byte, compressed bool) ([]byte,
+	error) {
 
-	if s.Cmp(halfN) > 0 {
-		s.Sub(s256point.N, s)
+	sig, recID, err := p.SignRecoverable(hash)
+	if err != nil {
+		return nil, err
 	}
 
-	return &signature.Signature{Rx: r, S: s}, nil
+	return sig.Compact(recID, compressed)
 }
 
-var (
-	// Used in RFC6979 implementation when testing the nonce for correctness
-	one = big.NewInt(1)
+// oneInitializer is used to fill a byte slice with byte 0x01.  It is provided
+// here to avoid the need to create it multiple times.
+var oneInitializer = []byte{0x01}
 
-	// oneInitializer is used to fill a byte slice with byte 0x01.  It is provided
-	// here to avoid the need to create it multiple times.
-	oneInitializer = []byte{0x01}
-)
-
-func (p *PrivateKey) DeterministicK(hash []byte) *big.Int {
+// DeterministicK returns the RFC6979 nonce for signing the hash. The nonce is
+// as secret as the key, so it is derived and returned as a constant-time
+// scalar without passing through big.Int.
+func (p *PrivateKey) DeterministicK(hash []byte) *secp256k1.Scalar {
 	n := s256point.N
-	e := p.secret
+	e := p.secret.Bytes()
 	alg := sha256.New
 
-	qlen := n.BitLen()
 	holen := alg().Size()
-	rolen := (qlen + 7) >> 3
-	bx := append(int2octets(e, rolen), bits2octets(hash, n, rolen)...)
+	rolen := KeySize
+	bx := append(e[:], bits2octets(hash, n, rolen)...)
+	defer zero(bx)
+	defer zero(e[:])
 
 	// Step B
 	v := bytes.Repeat(oneInitializer, holen)
@@ -106,25 +221,35 @@ func (p *PrivateKey) DeterministicK(hash []byte) *big.Int {
 
 	// Step H
 	for {
-		// Step H1
-		var t []byte
+		// Step H1 and H2. The order is 256 bits, the size of a
+		// single SHA256 output, so T is just the next V.
+		v = mac(alg, k, v)
 
-		// Step H2
-		for len(t)*8 < qlen {
-			v = mac(alg, k, v)
-			t = append(t, v...)
-		}
+		var (
+			t     [KeySize]byte
+			nonce secp256k1.Scalar
+		)
+		copy(t[:], v)
 
 		// Step H3
-		secret := hashToInt(t, n)
-		if secret.Cmp(one) >= 0 && secret.Cmp(n) < 0 {
-			return secret
+		overflow := nonce.SetBytes(&t)
+		zero(t[:])
+		if !overflow && !nonce.IsZero() {
+			return &nonce
 		}
+
 		k = mac(alg, k, append(v, 0x00))
 		v = mac(alg, k, v)
 	}
 }
 
+// zero overwrites b with zeros so that secrets don't linger in memory.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 // mac returns an HMAC of the given key and message.
 func mac(alg func() hash.Hash, k, m []byte) []byte {
 	h := hmac.New(alg, k)
@@ -1,9 +1,16 @@
 package privatekey
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/address"
+	"github.com/ellemouton/btc/s256point"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,3 +27,163 @@ func TestSign(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, valid)
 }
+
+func TestDeterministicK(t *testing.T) {
+	// Widely used RFC6979 vectors for secp256k1 with SHA256.
+	tests := []struct {
+		secret *big.Int
+		msg    string
+		k      string
+	}{
+		{
+			secret: big.NewInt(1),
+			msg:    "Satoshi Nakamoto",
+			k:      "8f8a276c19f4149656b280621e358cce24f5f52542772691ee69063b74f15d15",
+		},
+		{
+			secret: new(big.Int).Sub(s256point.N, big.NewInt(1)),
+			msg:    "Satoshi Nakamoto",
+			k:      "33a19b60e25fb6f4435af53a3d42d493644827367e6453928554f43e49aa6f90",
+		},
+		{
+			secret: big.NewInt(1),
+			msg:    "All those moments will be lost in time, like tears in rain. Time to die...",
+			k:      "38aa22d72376b4dbc472e06c3ba403ee0a394da63fc58d88686c611aba98d6b3",
+		},
+	}
+
+	for _, test := range tests {
+		privKey, err := New(test.secret)
+		require.NoError(t, err)
+
+		hash := sha256.Sum256([]byte(test.msg))
+		k := privKey.DeterministicK(hash[:]).Bytes()
+		require.Equal(t, test.k, hex.EncodeToString(k[:]))
+	}
+
+	// Secrets are reduced but must fit in 256 bits.
+	_, err := New(new(big.Int).Lsh(big.NewInt(1), 256))
+	require.True(t, errors.Is(err, ErrInvalidKey))
+
+	privKey, err := New(new(big.Int).Add(s256point.N, big.NewInt(5)))
+	require.NoError(t, err)
+	require.Equal(t, "0000000000000000000000000000000000000000000000000000000000000005", privKey.Hex())
+}
+
+func TestSignCompact(t *testing.T) {
+	for i := int64(1); i <= 10; i++ {
+		privKey, err := New(big.NewInt(i * 7919))
+		require.NoError(t, err)
+
+		hash := sha256.Sum256([]byte{byte(i)})
+		compressed := i%2 == 0
+
+		sig, err := privKey.SignCompact(hash[:], compressed)
+		require.NoError(t, err)
+
+		pk, c, err := s256point.RecoverCompact(hash[:], sig)
+		require.NoError(t, err)
+		require.Equal(t, compressed, c)
+		require.Equal(t, privKey.PubKey.Sec(true), pk.Sec(true))
+	}
+}
+
+func TestWIF(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		wif        string
+		net        *address.Network
+		compressed bool
+	}{
+		{
+			name:   "mainnet uncompressed",
+			secret: "0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d",
+			wif:    "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ",
+			net:    address.MainNet,
+		},
+		{
+			name:       "mainnet compressed",
+			secret:     "0000000000000000000000000000000000000000000000000000000000000001",
+			wif:        "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn",
+			net:        address.MainNet,
+			compressed: true,
+		},
+		{
+			name:   "mainnet uncompressed one",
+			secret: "0000000000000000000000000000000000000000000000000000000000000001",
+			wif:    "5HpHagT65TZzG1PH3CSu63k8DbpvD8s5ip4nEB3kEsreAnchuDf",
+			net:    address.MainNet,
+		},
+		{
+			name:       "testnet compressed",
+			secret:     "0000000000000000000000000000000000000000000000000000000000000001",
+			wif:        "cMahea7zqjxrtgAbB7LSGbcQUr1uX1ojuat9jZodMN87JcbXMTcA",
+			net:        address.TestNet,
+			compressed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			priv, net, err := ParseWIF(test.wif)
+			require.NoError(t, err)
+			require.Equal(t, test.net, net)
+			require.Equal(t, test.compressed, priv.Compressed)
+			require.Equal(t, test.secret, priv.Hex())
+			require.Equal(t, test.wif, priv.WIF(test.net))
+
+			require.Equal(t, priv.PubKey.Sec(test.compressed),
+				priv.PubKeySec())
+		})
+	}
+
+	for _, wif := range []string{
+		"",
+		// Bad checksum.
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTK",
+		// A 32 byte payload followed by a byte other than 0x01.
+		base58.CheckEncode(append(make([]byte, 31), 1, 2), 0x80),
+	} {
+		_, _, err := ParseWIF(wif)
+		require.True(t, errors.Is(err, ErrInvalidWIF), err)
+	}
+
+	// Keys outside of the valid range and unknown versions are rejected.
+	_, _, err := ParseWIF(base58.CheckEncode(make([]byte, 32), 0x80))
+	require.True(t, errors.Is(err, ErrInvalidKey), err)
+
+	one := append(make([]byte, 31), 1)
+	_, _, err = ParseWIF(base58.CheckEncode(one, 0x00))
+	require.True(t, errors.Is(err, address.ErrUnknownNetwork), err)
+}
+
+func TestFromBytes(t *testing.T) {
+	priv, err := New(big.NewInt(12345))
+	require.NoError(t, err)
+	require.True(t, priv.Compressed)
+
+	b := priv.Bytes()
+	require.Len(t, b, KeySize)
+
+	priv2, err := FromBytes(b)
+	require.NoError(t, err)
+	require.Equal(t, priv.Hex(), priv2.Hex())
+	require.True(t, priv.PubKey.Equal(priv2.PubKey))
+
+	nMinusOne := new(big.Int).Sub(s256point.N, big.NewInt(1))
+	_, err = FromBytes(nMinusOne.Bytes())
+	require.NoError(t, err)
+
+	for _, b := range [][]byte{
+		nil,
+		make([]byte, 31),
+		make([]byte, 33),
+		make([]byte, 32),
+		s256point.N.Bytes(),
+		bytes.Repeat([]byte{0xff}, 32),
+	} {
+		_, err := FromBytes(b)
+		require.True(t, errors.Is(err, ErrInvalidKey), err)
+	}
+}
@@ -0,0 +1,92 @@
+package privatekey
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+// SignSchnorr creates a BIP340 signature over msg. The aux bytes are 32 bytes
+// of auxiliary randomness that are mixed into the nonce to protect against
+// side channel attacks. Signing is still safe if they are all zero. The
+// signature is verified before it is returned.
+func (p *PrivateKey) SignSchnorr(msg, aux []byte) (*schnorr.Signature, error) {
+	if len(aux) != 32 {
+		return nil, fmt.Errorf("auxiliary randomness must be 32 bytes, "+
+			"got %d", len(aux))
+	}
+
+	// The key is negated if needed so that its public key has an even y
+	// and so matches the x-only key that verifiers use.
+	var d secp256k1.Scalar
+	d.Set(&p.secret).CondNeg(!p.PubKey.HasEvenY())
+	pubKey := p.PubKey.XOnly()
+
+	t := d.Bytes()
+	for i, b := range schnorr.AuxHash(aux) {
+		t[i] ^= b
+	}
+
+	var k secp256k1.Scalar
+	k.SetByteSlice(schnorr.NonceHash(t[:], pubKey, msg))
+	if k.IsZero() {
+		return nil, errors.New("derived nonce is zero")
+	}
+
+	var R secp256k1.JacobianPoint
+	if !R.ScalarBaseMult(&k).ToAffine() {
+		return nil, errors.New("nonce point is the point at infinity")
+	}
+	k.CondNeg(R.Y.IsOdd())
+
+	r := R.X.Bytes()
+
+	var e secp256k1.Scalar
+	e.SetByteSlice(schnorr.ChallengeHash(r[:], pubKey, msg))
+
+	// s = k + e*d
+	var s secp256k1.Scalar
+	s.Mul(&e, &d)
+	s.Add(&s, &k)
+
+	sb := s.Bytes()
+	sig := schnorr.New(
+		new(big.Int).SetBytes(r[:]), new(big.Int).SetBytes(sb[:]),
+	)
+
+	ok, err := p.PubKey.VerifySchnorr(msg, sig)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("created an invalid schnorr signature")
+	}
+
+	return sig, nil
+}
+
+// TweakXOnly returns the private key d + t mod n, where d is first negated if
+// needed so that its public key has an even y coordinate. This is how the
+// key for a taproot output key is derived from its internal key.
+func (p *PrivateKey) TweakXOnly(tweak []byte) (*PrivateKey, error) {
+	if len(tweak) > 32 {
+		return nil, errors.New("tweak must be at most 32 bytes")
+	}
+
+	var t secp256k1.Scalar
+	if t.SetByteSlice(tweak) {
+		return nil, errors.New("tweak exceeds the curve order")
+	}
+
+	var d secp256k1.Scalar
+	d.Set(&p.secret).CondNeg(!p.PubKey.HasEvenY())
+	d.Add(&d, &t)
+	if d.IsZero() {
+		return nil, errors.New("tweaked private key is zero")
+	}
+
+	return newFromScalar(&d)
+}
@@ -0,0 +1,78 @@
+package privatekey
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/ellemouton/btc/address"
+)
+
+// wifCompressed is appended to the secret in a WIF string if the public key
+// is compressed.
+const wifCompressed = 0x01
+
+// ErrInvalidWIF is returned when a string is not a valid WIF private key.
+var ErrInvalidWIF = errors.New("invalid WIF private key")
+
+// wifNetworks are the networks that WIF strings are decoded for. Signet and
+// regtest share the testnet version byte.
+var wifNetworks = []*address.Network{address.MainNet, address.TestNet}
+
+// WIF returns the key in wallet import format for the given network. The
+// compression flag is included if p.Compressed is set.
+func (p *PrivateKey) WIF(net *address.Network) string {
+	payload := p.Bytes()
+	if p.Compressed {
+		payload = append(payload, wifCompressed)
+	}
+
+	return base58.CheckEncode(payload, net.PrivateKeyID)
+}
+
+// ParseWIF decodes a private key in wallet import format and returns the
+// network it is for. The returned key's Compressed flag is set from the
+// encoding. Signet and regtest keys decode as testnet since they can't be
+// told apart.
+func ParseWIF(wif string) (*PrivateKey, *address.Network, error) {
+	payload, version, err := base58.CheckDecode(wif)
+	switch err {
+	case nil:
+	case base58.ErrChecksum:
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidWIF)
+	default:
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidWIF, err)
+	}
+
+	var compressed bool
+	switch {
+	case len(payload) == KeySize:
+	case len(payload) == KeySize+1 && payload[KeySize] == wifCompressed:
+		compressed = true
+		payload = payload[:KeySize]
+
+	default:
+		return nil, nil, fmt.Errorf("%w: invalid payload length %d",
+			ErrInvalidWIF, len(payload))
+	}
+
+	var net *address.Network
+	for _, n := range wifNetworks {
+		if n.PrivateKeyID == version {
+			net = n
+			break
+		}
+	}
+	if net == nil {
+		return nil, nil, fmt.Errorf("%w: version byte 0x%02x",
+			address.ErrUnknownNetwork, version)
+	}
+
+	priv, err := FromBytes(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv.Compressed = compressed
+
+	return priv, net, nil
+}
@@ -0,0 +1,54 @@
+package s256point
+
+import (
+	"math/big"
+
+	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+// Jacobian returns the point in the representation used by the secp256k1
+// package, which does its arithmetic in Jacobian coordinates on fixed size
+// integers.
+func (s *S256Point) Jacobian() secp256k1.JacobianPoint {
+	var p secp256k1.JacobianPoint
+	if s.IsInfinity() {
+		return p
+	}
+
+	p.X.SetByteSlice(s.GetX().GetNum().Bytes())
+	p.Y.SetByteSlice(s.GetY().GetNum().Bytes())
+	p.Z.SetInt(1)
+
+	return p
+}
+
+// FromJacobian converts a point from the secp256k1 package into an S256Point.
+func FromJacobian(p *secp256k1.JacobianPoint) (*S256Point, error) {
+	a := *p
+	if !a.ToAffine() {
+		return New(nil, nil)
+	}
+
+	xb, yb := a.X.Bytes(), a.Y.Bytes()
+
+	x, err := s256field.New(new(big.Int).SetBytes(xb[:]))
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := s256field.New(new(big.Int).SetBytes(yb[:]))
+	if err != nil {
+		return nil, err
+	}
+
+	return New(x, y)
+}
+
+// toScalar reduces c modulo N. It uses math/big and so runs in variable time.
+func toScalar(c *big.Int) secp256k1.Scalar {
+	var k secp256k1.Scalar
+	k.SetByteSlice(new(big.Int).Mod(c, N).Bytes())
+
+	return k
+}
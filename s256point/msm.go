@@ -0,0 +1,40 @@
+package s256point
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ellemouton/btc/secp256k1"
+)
+
+// MultiScalarMul computes scalars[0]*points[0] + ... + scalars[n]*points[n]
+// using Strauss' method with wNAF and the GLV endomorphism. The doublings are
+// shared between all of the terms and any multiples of G use precomputed
+// tables, which makes this much cheaper than multiplying every point
+// separately. It is not constant time, so the scalars must be public.
+func MultiScalarMul(points []*S256Point, scalars []*big.Int) (*S256Point,
+	error) {
+
+	if len(points) != len(scalars) {
+		return nil, errors.New("number of points and scalars must match")
+	}
+
+	var (
+		u  secp256k1.Scalar
+		ks = make([]secp256k1.Scalar, 0, len(points))
+		ps = make([]secp256k1.JacobianPoint, 0, len(points))
+	)
+	for i, p := range points {
+		k := toScalar(scalars[i])
+		if p.Equal(G) {
+			u.Add(&u, &k)
+			continue
+		}
+
+		ks = append(ks, k)
+		ps = append(ps, p.Jacobian())
+	}
+
+	var res secp256k1.JacobianPoint
+	return FromJacobian(res.MultiScalarMultVarTime(&u, ks, ps))
+}
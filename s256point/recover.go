@@ -0,0 +1,108 @@
+package s256point
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/signature"
+)
+
+// RecoverPubKey recovers the public key that created the signature over the
+// hash. The recovery id selects which of the up to four candidate keys is
+// returned: bit 0 is the parity of the y coordinate of the nonce point R and
+// bit 1 is set if the x coordinate of R was at least N.
+func RecoverPubKey(hash []byte, sig *signature.Signature,
+	recID byte) (*S256Point, error) {
+
+	if recID > 3 {
+		return nil, errors.New("recovery id must be between 0 and 3")
+	}
+
+	if sig.Rx.Sign() <= 0 || sig.Rx.Cmp(N) >= 0 ||
+		sig.S.Sign() <= 0 || sig.S.Cmp(N) >= 0 {
+
+		return nil, errors.New("signature values out of range")
+	}
+
+	R, err := RecoverNoncePoint(sig, recID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Q = r^-1 * (s*R - z*G)
+	rInv := new(big.Int).ModInverse(sig.Rx, N)
+
+	u1 := new(big.Int).Mul(sig.S, rInv)
+	u1.Mod(u1, N)
+
+	u2 := new(big.Int).Mul(hashToInt(hash, N), rInv)
+	u2.Sub(N, u2.Mod(u2, N))
+
+	q, err := MultiScalarMul(
+		[]*S256Point{R, G}, []*big.Int{u1, u2},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.IsInfinity() {
+		return nil, errors.New("recovered key is the point at infinity")
+	}
+
+	return q, nil
+}
+
+// RecoverNoncePoint returns the nonce point R of the signature that is
+// selected by the recovery id, as used by RecoverPubKey. Its x coordinate is
+// r, plus N if bit 1 of the recovery id is set, and bit 0 is the parity of
+// its y coordinate.
+func RecoverNoncePoint(sig *signature.Signature, recID byte) (*S256Point,
+	error) {
+
+	if recID > 3 {
+		return nil, errors.New("recovery id must be between 0 and 3")
+	}
+
+	if sig.Rx.Sign() <= 0 || sig.Rx.Cmp(N) >= 0 {
+		return nil, errors.New("signature r out of range")
+	}
+
+	x := new(big.Int).Set(sig.Rx)
+	if recID&2 != 0 {
+		x.Add(x, N)
+	}
+
+	if x.Cmp(s256field.P) >= 0 {
+		return nil, errors.New("nonce point x coordinate out of range")
+	}
+
+	// Lift x to the point R with the y parity given by the recovery id.
+	b := make([]byte, 0, PubKeyBytesLenCompressed)
+	b = append(b, pubkeyCompressedEven+(recID&1))
+	b = paddedAppend(32, b, x.Bytes())
+
+	R, err := Parse(b)
+	if err != nil {
+		return nil, errors.New("nonce point is not on the curve")
+	}
+
+	return R.(*S256Point), nil
+}
+
+// RecoverCompact recovers the public key from a 65 byte compact signature
+// over the hash. It also returns whether the signer used the compressed
+// encoding of the key.
+func RecoverCompact(hash, sig []byte) (*S256Point, bool, error) {
+	s, recID, compressed, err := signature.ParseCompact(sig)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pk, err := RecoverPubKey(hash, s, recID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return pk, compressed, nil
+}
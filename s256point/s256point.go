@@ -2,15 +2,20 @@ package s256point
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"math/big"
-	"reflect"
 
-	"github.com/ellemouton/btc/fieldelement"
 	"github.com/ellemouton/btc/point"
 	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/secp256k1"
 	"github.com/ellemouton/btc/signature"
 )
 
+// ErrInvalidPubKey is returned when a public key encoding is malformed or
+// doesn't describe a point on the curve.
+var ErrInvalidPubKey = errors.New("invalid public key")
+
 var (
 	N *big.Int
 	G *S256Point
@@ -50,30 +55,27 @@ func ParseFromString(s string) (point.Point, error) {
 	return Parse(b)
 }
 
+// Parse decodes a 33 byte compressed or 65 byte uncompressed SEC encoded
+// public key. Coordinates that are not less than the field size, points that
+// are not on the curve and any other prefix or length are rejected with
+// ErrInvalidPubKey.
 func Parse(b []byte) (point.Point, error) {
-	if b[0] == 4 {
-		x := (&big.Int{}).SetBytes(b[1:33])
-
-		xf, err := s256field.New(x)
-		if err != nil {
-			return nil, err
-		}
-
-		y := (&big.Int{}).SetBytes(b[33:65])
-		yf, err := s256field.New(y)
-		if err != nil {
-			return nil, err
-		}
-
-		return New(xf, yf)
+	switch {
+	case len(b) == PubKeyBytesLenUncompressed && b[0] == pubkeyUncompressed:
+	case len(b) == PubKeyBytesLenCompressed &&
+		(b[0] == pubkeyCompressedEven || b[0] == pubkeyCompressedOdd):
+
+	default:
+		return nil, fmt.Errorf("%w: %d bytes with prefix %#x",
+			ErrInvalidPubKey, len(b), prefix(b))
 	}
 
-	isEven := (b[0]%2 == 0)
-	x, err := s256field.New((&big.Int{}).SetBytes(b[1:]))
+	x, err := parseCoord(b[1:33])
 	if err != nil {
 		return nil, err
 	}
 
+	// alpha = x^3 + 7 is y^2 for points on the curve.
 	alpha, err := x.Pow(big.NewInt(3))
 	if err != nil {
 		return nil, err
@@ -84,35 +86,71 @@ func Parse(b []byte) (point.Point, error) {
 		return nil, err
 	}
 
+	if b[0] == pubkeyUncompressed {
+		y, err := parseCoord(b[33:])
+		if err != nil {
+			return nil, err
+		}
+
+		y2, err := y.Pow(big.NewInt(2))
+		if err != nil {
+			return nil, err
+		}
+
+		if !y2.Equal(alpha) {
+			return nil, fmt.Errorf("%w: point is not on the curve",
+				ErrInvalidPubKey)
+		}
+
+		return New(x, y)
+	}
+
+	// If alpha has no square root then no point has this x coordinate,
+	// which is only detected by squaring the candidate root.
 	beta, err := s256field.Sqrt(alpha)
 	if err != nil {
 		return nil, err
 	}
 
-	var evenBeta fieldelement.FieldElement
-	var oddBeta fieldelement.FieldElement
+	beta2, err := beta.Pow(big.NewInt(2))
+	if err != nil {
+		return nil, err
+	}
 
-	m := (&big.Int{}).Mod(beta.GetNum(), big.NewInt(2))
-	if m.Cmp(big.NewInt(0)) == 0 {
-		evenBeta = beta
-		oB, err := s256field.New((&big.Int{}).Sub(s256field.P, beta.GetNum()))
-		if err != nil {
-			return nil, err
-		}
-		oddBeta = oB
-	} else {
-		oddBeta = beta
-		eB, err := s256field.New((&big.Int{}).Sub(s256field.P, beta.GetNum()))
+	if !beta2.Equal(alpha) {
+		return nil, fmt.Errorf("%w: x coordinate is not on the curve",
+			ErrInvalidPubKey)
+	}
+
+	y := beta
+	if isOdd(beta.GetNum()) != (b[0] == pubkeyCompressedOdd) {
+		y, err = s256field.New(new(big.Int).Sub(s256field.P, beta.GetNum()))
 		if err != nil {
 			return nil, err
 		}
-		evenBeta = eB
 	}
 
-	if isEven {
-		return New(x, evenBeta)
+	return New(x, y)
+}
+
+// parseCoord decodes a 32 byte coordinate, which must be less than the field
+// size.
+func parseCoord(b []byte) (s256field.S256Field, error) {
+	c := new(big.Int).SetBytes(b)
+	if c.Cmp(s256field.P) >= 0 {
+		return nil, fmt.Errorf("%w: coordinate exceeds the field size",
+			ErrInvalidPubKey)
+	}
+
+	return s256field.New(c)
+}
+
+func prefix(b []byte) byte {
+	if len(b) == 0 {
+		return 0
 	}
-	return New(x, oddBeta)
+
+	return b[0]
 }
 
 func (s *S256Point) Add(o point.Point) (point.Point, error) {
@@ -124,11 +162,26 @@ func (s *S256Point) Add(o point.Point) (point.Point, error) {
 	return &S256Point{p}, nil
 }
 
-func (s *S256Point) Mul(c *big.Int) (point.Point, error) {
-	coef := &big.Int{}
-	coef.Mod(c, N)
+func (s *S256Point) Sub(o point.Point) (point.Point, error) {
+	p, err := s.Point.Sub(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S256Point{p}, nil
+}
+
+func (s *S256Point) Neg() (point.Point, error) {
+	p, err := s.Point.Neg()
+	if err != nil {
+		return nil, err
+	}
+
+	return &S256Point{p}, nil
+}
 
-	p, err := s.Point.Mul(coef)
+func (s *S256Point) Double() (point.Point, error) {
+	p, err := s.Point.Double()
 	if err != nil {
 		return nil, err
 	}
@@ -136,6 +189,24 @@ func (s *S256Point) Mul(c *big.Int) (point.Point, error) {
 	return &S256Point{p}, nil
 }
 
+// Mul returns c*s using the secp256k1 package, with the precomputed tables
+// for G when s is the generator. The multiplication itself is constant time
+// but c is reduced and converted through math/big, which is not, so secret
+// scalars should be multiplied with secp256k1.Scalar directly instead.
+func (s *S256Point) Mul(c *big.Int) (point.Point, error) {
+	k := toScalar(c)
+	a := s.Jacobian()
+
+	var p secp256k1.JacobianPoint
+	if s.Equal(G) {
+		p.ScalarBaseMult(&k)
+	} else {
+		p.ScalarMult(&k, &a)
+	}
+
+	return FromJacobian(&p)
+}
+
 const (
 	pubkeyCompressedEven byte = 0x2 // y_bit + x coord
 	pubkeyCompressedOdd  byte = 0x3 // y_bit + x coord
@@ -172,6 +243,12 @@ func (s *S256Point) SecString(compressed bool) string {
 }
 
 func (s *S256Point) Verify(hash []byte, sig *signature.Signature) (bool, error) {
+	if sig.Rx.Sign() <= 0 || sig.Rx.Cmp(N) >= 0 ||
+		sig.S.Sign() <= 0 || sig.S.Cmp(N) >= 0 {
+
+		return false, nil
+	}
+
 	z := hashToInt(hash, N)
 
 	exp := &big.Int{}
@@ -188,22 +265,16 @@ func (s *S256Point) Verify(hash []byte, sig *signature.Signature) (bool, error)
 	v.Mul(sig.Rx, s_inv)
 	v.Mod(v, N)
 
-	uG, err := G.Mul(u)
-	if err != nil {
-		return false, err
-	}
-
-	vG, err := s.Mul(v)
+	total, err := MultiScalarMul([]*S256Point{G, s}, []*big.Int{u, v})
 	if err != nil {
 		return false, err
 	}
 
-	total, err := uG.Add(vG)
-	if err != nil {
-		return false, err
+	if total.IsInfinity() {
+		return false, nil
 	}
 
-	return reflect.DeepEqual(total.GetX().GetNum(), sig.Rx), nil
+	return total.GetX().GetNum().Cmp(sig.Rx) == 0, nil
 }
 
 // This is borrowed from crypto/ecdsa.
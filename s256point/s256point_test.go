@@ -1,9 +1,11 @@
 package s256point
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/ellemouton/btc/point"
 	"github.com/ellemouton/btc/s256field"
 	"github.com/ellemouton/btc/signature"
 	"github.com/stretchr/testify/require"
@@ -135,3 +137,188 @@ func TestParse(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, p1, p3)
 }
+
+func TestMul(t *testing.T) {
+	p, err := G.Mul(big.NewInt(1000003))
+	require.NoError(t, err)
+
+	for _, k := range []*big.Int{
+		big.NewInt(1), big.NewInt(7919), new(big.Int).Sub(N, big.NewInt(3)),
+		new(big.Int).Add(N, big.NewInt(2)), big.NewInt(-5),
+	} {
+		for _, base := range []*S256Point{G, p.(*S256Point)} {
+			got, err := base.Mul(k)
+			require.NoError(t, err)
+
+			// The generic affine implementation is the reference.
+			want, err := base.Point.Mul(new(big.Int).Mod(k, N))
+			require.NoError(t, err)
+
+			require.Equal(t, want.GetX(), got.GetX())
+			require.Equal(t, want.GetY(), got.GetY())
+		}
+	}
+
+	inf, err := p.Mul(N)
+	require.NoError(t, err)
+	require.Nil(t, inf.GetX())
+}
+
+func TestParseInvalid(t *testing.T) {
+	gx := "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	gy := "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+	p := "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "empty", key: ""},
+		{name: "prefix only", key: "02"},
+		{name: "compressed wrong prefix", key: "04" + gx},
+		{name: "uncompressed wrong prefix", key: "02" + gx + gy},
+		{name: "hybrid", key: "06" + gx + gy},
+		{name: "truncated", key: "02" + gx[:62]},
+		{name: "trailing byte", key: "02" + gx + "00"},
+		{name: "x not on curve", key: "02" + "0000000000000000000000000000000000000000000000000000000000000005"},
+		{name: "x exceeds field", key: "02" + p},
+		{name: "y exceeds field", key: "04" + gx + p},
+		{name: "y not on curve", key: "04" + gx + gx},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFromString(test.key)
+			require.True(t, errors.Is(err, ErrInvalidPubKey), err)
+		})
+	}
+
+	for _, key := range []string{"02" + gx, "04" + gx + gy} {
+		p, err := ParseFromString(key)
+		require.NoError(t, err)
+		require.True(t, p.Equal(G))
+	}
+
+	p3, err := ParseFromString("03" + gx)
+	require.NoError(t, err)
+
+	negG, err := G.Neg()
+	require.NoError(t, err)
+	require.True(t, p3.Equal(negG))
+}
+
+func TestPointOps(t *testing.T) {
+	twoG, err := G.Double()
+	require.NoError(t, err)
+
+	mul, err := G.Mul(big.NewInt(2))
+	require.NoError(t, err)
+	require.True(t, twoG.Equal(mul))
+	require.IsType(t, &S256Point{}, twoG)
+
+	g, err := twoG.Sub(G)
+	require.NoError(t, err)
+	require.True(t, g.Equal(G))
+	require.IsType(t, &S256Point{}, g)
+
+	inf, err := G.Sub(G)
+	require.NoError(t, err)
+	require.True(t, inf.IsInfinity())
+	require.True(t, inf.IsOnCurve())
+	require.True(t, G.IsOnCurve())
+}
+
+func TestMultiScalarMul(t *testing.T) {
+	var (
+		points  []*S256Point
+		scalars []*big.Int
+	)
+	expected, err := New(nil, nil)
+	require.NoError(t, err)
+
+	var want = point.Point(expected)
+	for i := int64(1); i <= 3; i++ {
+		p, err := G.Mul(big.NewInt(i * 1000003))
+		require.NoError(t, err)
+
+		k := new(big.Int).Sub(N, big.NewInt(i*7919))
+		points = append(points, p.(*S256Point))
+		scalars = append(scalars, k)
+
+		kP, err := p.Mul(k)
+		require.NoError(t, err)
+
+		want, err = want.Add(kP)
+		require.NoError(t, err)
+	}
+
+	got, err := MultiScalarMul(points, scalars)
+	require.NoError(t, err)
+	require.Equal(t, want.GetX(), got.GetX())
+	require.Equal(t, want.GetY(), got.GetY())
+
+	// P - P is the point at infinity.
+	got, err = MultiScalarMul(
+		[]*S256Point{G, G}, []*big.Int{big.NewInt(5), new(big.Int).Sub(N, big.NewInt(5))},
+	)
+	require.NoError(t, err)
+	require.Nil(t, got.GetX())
+
+	_, err = MultiScalarMul([]*S256Point{G}, nil)
+	require.Error(t, err)
+}
+
+func TestRecoverPubKey(t *testing.T) {
+	z, _ := new(big.Int).SetString("bc62d4b80d9e36da29c16c5d4d9f11731f36052c72401a76c23c0fb5a9b74423", 16)
+	r, _ := new(big.Int).SetString("37206a0610995c58074999cb9767b87af4c4978db68c06e8e6e81d282047a7c6", 16)
+	s, _ := new(big.Int).SetString("8ca63759c1157ebeaec0d03cecca119fc9a75bf8e6d0fa65c841c8e2738cdaec", 16)
+	px, _ := new(big.Int).SetString("04519fac3d910ca7e7138f7013706f619fa8f033e6ec6e09370ea38cee6a7574", 16)
+
+	sig := &signature.Signature{Rx: r, S: s}
+
+	// Exactly one of the recovery ids with a small nonce x coordinate
+	// yields the signing key.
+	var matches int
+	for recID := byte(0); recID < 2; recID++ {
+		pk, err := RecoverPubKey(z.Bytes(), sig, recID)
+		require.NoError(t, err)
+
+		valid, err := pk.Verify(z.Bytes(), sig)
+		require.NoError(t, err)
+		require.True(t, valid)
+
+		R, err := RecoverNoncePoint(sig, recID)
+		require.NoError(t, err)
+		require.True(t, R.GetX().GetNum().Cmp(sig.Rx) == 0)
+		require.Equal(t, recID == 0, R.HasEvenY())
+
+		if pk.GetX().GetNum().Cmp(px) == 0 {
+			matches++
+
+			compact, err := sig.Compact(recID, true)
+			require.NoError(t, err)
+
+			rpk, compressed, err := RecoverCompact(z.Bytes(), compact)
+			require.NoError(t, err)
+			require.True(t, compressed)
+			require.Equal(t, pk.Sec(true), rpk.Sec(true))
+		}
+	}
+	require.Equal(t, 1, matches)
+
+	// r + N exceeds the field size so recovery id 2 and 3 are invalid.
+	_, err := RecoverPubKey(z.Bytes(), sig, 2)
+	require.Error(t, err)
+
+	_, err = RecoverPubKey(z.Bytes(), sig, 4)
+	require.Error(t, err)
+
+	_, err = RecoverNoncePoint(sig, 2)
+	require.Error(t, err)
+
+	_, err = RecoverNoncePoint(sig, 4)
+	require.Error(t, err)
+
+	_, err = RecoverPubKey(z.Bytes(), &signature.Signature{Rx: N, S: s}, 0)
+	require.Error(t, err)
+}
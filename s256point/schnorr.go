@@ -0,0 +1,76 @@
+package s256point
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/s256field"
+	"github.com/ellemouton/btc/schnorr"
+)
+
+// XOnly returns the 32 byte x-only encoding of the point as used by BIP340.
+func (s *S256Point) XOnly() []byte {
+	return paddedAppend(schnorr.PubKeySize, nil, s.GetX().GetNum().Bytes())
+}
+
+// HasEvenY returns true if the y coordinate of the point is even.
+func (s *S256Point) HasEvenY() bool {
+	return !isOdd(s.GetY().GetNum())
+}
+
+// ParseXOnly decodes a 32 byte x-only public key into the point with that x
+// coordinate and an even y coordinate. An error is returned if x is not
+// less than the field size or is not the x coordinate of a point on the curve.
+func ParseXOnly(b []byte) (*S256Point, error) {
+	if len(b) != schnorr.PubKeySize {
+		return nil, fmt.Errorf("x-only public key must be %d bytes, got %d",
+			schnorr.PubKeySize, len(b))
+	}
+
+	p, err := Parse(append([]byte{pubkeyCompressedEven}, b...))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.(*S256Point), nil
+}
+
+// VerifySchnorr verifies a BIP340 signature over msg. As BIP340 keys only
+// commit to the x coordinate, the point is treated as its x-only key, which is
+// the point with the same x coordinate and an even y.
+func (s *S256Point) VerifySchnorr(msg []byte, sig *schnorr.Signature) (bool,
+	error) {
+
+	if sig.R.Cmp(s256field.P) >= 0 || sig.S.Cmp(N) >= 0 {
+		return false, nil
+	}
+
+	pubKey := s.XOnly()
+
+	pk := s
+	if !pk.HasEvenY() {
+		var err error
+		pk, err = ParseXOnly(pubKey)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r := paddedAppend(32, nil, sig.R.Bytes())
+	e := new(big.Int).SetBytes(schnorr.ChallengeHash(r, pubKey, msg))
+	e.Mod(e, N)
+
+	// R = s*G - e*P, with the subtraction done by multiplying P by n-e.
+	R, err := MultiScalarMul(
+		[]*S256Point{G, pk}, []*big.Int{sig.S, new(big.Int).Sub(N, e)},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if R.IsInfinity() || isOdd(R.GetY().GetNum()) {
+		return false, nil
+	}
+
+	return R.GetX().GetNum().Cmp(sig.R) == 0, nil
+}
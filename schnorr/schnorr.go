@@ -0,0 +1,91 @@
+package schnorr
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/helpers"
+)
+
+const (
+	// SignatureSize is the size of an encoded BIP340 signature.
+	SignatureSize = 64
+
+	// PubKeySize is the size of an x-only public key.
+	PubKeySize = 32
+
+	tagChallenge = "BIP0340/challenge"
+	tagAux       = "BIP0340/aux"
+	tagNonce     = "BIP0340/nonce"
+)
+
+// Signature is a BIP340 Schnorr signature. R is the x coordinate of the
+// nonce point, which always has an even y coordinate.
+type Signature struct {
+	R *big.Int
+	S *big.Int
+}
+
+func New(r, s *big.Int) *Signature {
+	return &Signature{
+		R: r,
+		S: s,
+	}
+}
+
+// Serialize returns the 64 byte encoding of the signature: the 32 byte big
+// endian R followed by the 32 byte big endian S.
+func (s *Signature) Serialize() []byte {
+	b := make([]byte, SignatureSize)
+	r, sb := s.R.Bytes(), s.S.Bytes()
+	copy(b[32-len(r):32], r)
+	copy(b[SignatureSize-len(sb):], sb)
+
+	return b
+}
+
+func (s *Signature) String() string {
+	return hex.EncodeToString(s.Serialize())
+}
+
+func ParseFromString(s string) (*Signature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(b)
+}
+
+// Parse decodes a 64 byte signature. Whether R and S are in range is checked
+// during verification since an out of range value simply makes a signature
+// invalid.
+func Parse(sig []byte) (*Signature, error) {
+	if len(sig) != SignatureSize {
+		return nil, fmt.Errorf("schnorr signature must be %d bytes, got "+
+			"%d", SignatureSize, len(sig))
+	}
+
+	return &Signature{
+		R: new(big.Int).SetBytes(sig[:32]),
+		S: new(big.Int).SetBytes(sig[32:]),
+	}, nil
+}
+
+// ChallengeHash returns the BIP340 challenge hash of the encoded nonce point,
+// the x-only public key and the message.
+func ChallengeHash(r, pubKey, msg []byte) []byte {
+	return helpers.TaggedHash(tagChallenge, r, pubKey, msg)
+}
+
+// AuxHash returns the BIP340 tagged hash of the auxiliary randomness.
+func AuxHash(aux []byte) []byte {
+	return helpers.TaggedHash(tagAux, aux)
+}
+
+// NonceHash returns the BIP340 tagged hash from which the signing nonce is
+// derived.
+func NonceHash(t, pubKey, msg []byte) []byte {
+	return helpers.TaggedHash(tagNonce, t, pubKey, msg)
+}
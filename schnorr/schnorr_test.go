@@ -0,0 +1,79 @@
+package schnorr_test
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBIP340Vectors runs the test vectors from BIP340.
+func TestBIP340Vectors(t *testing.T) {
+	f, err := os.Open("testdata/test-vectors.csv")
+	require.NoError(t, err)
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	for _, rec := range records[1:] {
+		var (
+			index      = rec[0]
+			secretKey  = mustHex(t, rec[1])
+			pubKey     = mustHex(t, rec[2])
+			aux        = mustHex(t, rec[3])
+			msg        = mustHex(t, rec[4])
+			sigBytes   = mustHex(t, rec[5])
+			wantResult = rec[6] == "TRUE"
+		)
+
+		t.Run(index, func(t *testing.T) {
+			if len(secretKey) > 0 {
+				priv, err := privatekey.New(
+					new(big.Int).SetBytes(secretKey),
+				)
+				require.NoError(t, err)
+				require.Equal(t, pubKey, priv.PubKey.XOnly())
+
+				sig, err := priv.SignSchnorr(msg, aux)
+				require.NoError(t, err)
+				require.Equal(t, sigBytes, sig.Serialize())
+			}
+
+			sig, err := schnorr.Parse(sigBytes)
+			require.NoError(t, err)
+
+			pk, err := s256point.ParseXOnly(pubKey)
+			if err != nil {
+				require.False(t, wantResult)
+				return
+			}
+
+			ok, err := pk.VerifySchnorr(msg, sig)
+			require.NoError(t, err)
+			require.Equal(t, wantResult, ok)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	_, err := schnorr.Parse(make([]byte, 63))
+	require.Error(t, err)
+
+	sig, err := schnorr.ParseFromString("00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4")
+	require.NoError(t, err)
+	require.Equal(t, "00000000000000000000003b78ce563f89a0ed9414f5aa28ad0d96d6795f9c6376afb1548af603b3eb45c9f8207dee1060cb71c04e80f593060b07d28308d7f4", sig.String())
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
@@ -0,0 +1,173 @@
+package script
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// String returns the script in the ASM format used by Bitcoin Core. Pushes of
+// up to four bytes are shown as decimal numbers, longer pushes as hex and all
+// other opcodes by name, with the small integer opcodes shown as numbers.
+func (s Script) String() string {
+	parts := make([]string, 0, len(s))
+	for _, e := range s {
+		parts = append(parts, e.asm())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (e Elem) asm() string {
+	switch {
+	case e.Opcode <= OP_PUSHDATA4:
+		if len(e.Data) > defaultScriptNumLen {
+			return hex.EncodeToString(e.Data)
+		}
+
+		// The length is checked above so decoding can't fail.
+		n, _ := decodeNum(e.Data, false, defaultScriptNumLen)
+
+		return strconv.FormatInt(n, 10)
+
+	case e.Opcode == OP_1NEGATE:
+		return "-1"
+
+	case e.Opcode >= OP_1 && e.Opcode <= OP_16:
+		return strconv.Itoa(int(e.Opcode - OP_1 + 1))
+	}
+
+	return e.Opcode.String()
+}
+
+// ParseASM assembles a script from its text form. Tokens are separated by
+// whitespace and may be:
+//   - an opcode name, with or without the OP_ prefix, such as OP_DUP or DUP.
+//   - a push opcode such as OP_DATA_3 or OP_PUSHDATA1 followed by a token of
+//     hex encoded data that it can push, which gives a push with exactly that
+//     encoding.
+//   - a decimal number in the range that Script.String shows as a number,
+//     which is pushed using the smallest encoding, such as OP_0, OP_1NEGATE or
+//     OP_1 to OP_16.
+//   - hex encoded data, which is pushed using the smallest push opcode.
+//
+// This accepts the output of Script.String, but the round trip loses the
+// encoding of pushes of up to four bytes, which are shown as numbers and
+// parsed back as the smallest push of that number. For example OP_DATA_1 00
+// comes back as OP_0 and OP_DATA_2 0100 as OP_1. Scripts in which every such
+// push is already the smallest push of a minimally encoded number survive
+// unchanged.
+func ParseASM(asm string) (Script, error) {
+	script := Script{}
+
+	toks := strings.Fields(asm)
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+
+		op, ok := opcodes[tok]
+		if !ok {
+			op, ok = opcodes["OP_"+tok]
+		}
+
+		if ok && op > OP_0 && op <= OP_PUSHDATA4 {
+			if i+1 == len(toks) {
+				return nil, fmt.Errorf("%v at position %d has no data",
+					op, i)
+			}
+
+			i++
+			data, err := hex.DecodeString(toks[i])
+			if err != nil || !canPush(op, len(data)) {
+				return nil, fmt.Errorf("%v at position %d can't push %q",
+					op, i-1, toks[i])
+			}
+
+			script = append(script, Elem{Opcode: op, Data: data})
+			continue
+		}
+
+		if ok {
+			script = append(script, Elem{Opcode: op})
+			continue
+		}
+
+		if n, ok := parseASMNum(tok); ok {
+			script = append(script, numElem(n))
+			continue
+		}
+
+		data, err := hex.DecodeString(tok)
+		if err != nil || len(data) == 0 {
+			return nil, fmt.Errorf("invalid token %q at position %d",
+				tok, i)
+		}
+
+		script = append(script, pushElem(data))
+	}
+
+	return script, nil
+}
+
+// canPush returns true if the push opcode can push n bytes.
+func canPush(op Opcode, n int) bool {
+	switch op {
+	case OP_PUSHDATA1:
+		return n <= 0xff
+	case OP_PUSHDATA2:
+		return n <= 0xffff
+	case OP_PUSHDATA4:
+		return uint64(n) <= math.MaxUint32
+	}
+
+	return n == int(op)
+}
+
+// maxASMNum is the largest magnitude of the numbers shown by Script.String,
+// which are the pushes that decode as script numbers of up to
+// defaultScriptNumLen bytes.
+const maxASMNum = 1<<(8*defaultScriptNumLen-1) - 1
+
+// parseASMNum parses tok as a number in the form produced by Script.String.
+// Anything else, such as numbers with a sign of +, leading zeros or a
+// magnitude above maxASMNum, is rejected so that it can be treated as hex
+// instead.
+func parseASMNum(tok string) (int64, bool) {
+	digits := strings.TrimPrefix(tok, "-")
+	if digits == "" || (len(digits) > 1 && digits[0] == '0') ||
+		tok == "-0" {
+
+		return 0, false
+	}
+
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+
+	n, err := strconv.ParseInt(tok, 10, 64)
+	if err != nil || n > maxASMNum || n < -maxASMNum {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// numElem returns the element that pushes n onto the stack using the
+// smallest possible encoding.
+func numElem(n int64) Elem {
+	switch {
+	case n == 0:
+		return Elem{Opcode: OP_0}
+
+	case n == -1:
+		return Elem{Opcode: OP_1NEGATE}
+
+	case n >= 1 && n <= 16:
+		return Elem{Opcode: OP_1 + Opcode(n-1)}
+	}
+
+	return pushElem(encodeNum(n))
+}
@@ -0,0 +1,1460 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/signature"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Flags control which consensus and policy rules are enforced during script
+// evaluation.
+type Flags uint32
+
+const (
+	// VerifyP2SH evaluates BIP16 pay-to-script-hash redeem scripts.
+	VerifyP2SH Flags = 1 << iota
+
+	// VerifyStrictEncoding requires signatures to have a defined hash type
+	// and public keys to be validly encoded.
+	VerifyStrictEncoding
+
+	// VerifyDERSignatures enforces BIP66 strict DER signature encoding.
+	VerifyDERSignatures
+
+	// VerifyLowS requires the S value of signatures to be at most N/2.
+	VerifyLowS
+
+	// VerifyNullDummy requires the extra element consumed by
+	// OP_CHECKMULTISIG to be empty (BIP147).
+	VerifyNullDummy
+
+	// VerifySigPushOnly requires scriptSigs to only contain pushes.
+	VerifySigPushOnly
+
+	// VerifyMinimalData requires pushes and numbers to be minimally
+	// encoded.
+	VerifyMinimalData
+
+	// VerifyDiscourageUpgradableNops fails scripts that execute the NOPs
+	// reserved for soft forks.
+	VerifyDiscourageUpgradableNops
+
+	// VerifyCleanStack requires exactly one element to remain on the stack
+	// after evaluation.
+	VerifyCleanStack
+
+	// VerifyCheckLockTimeVerify enables OP_CHECKLOCKTIMEVERIFY (BIP65).
+	VerifyCheckLockTimeVerify
+
+	// VerifyCheckSequenceVerify enables OP_CHECKSEQUENCEVERIFY (BIP112).
+	VerifyCheckSequenceVerify
+
+	// VerifyWitness evaluates segregated witness programs (BIP141).
+	VerifyWitness
+
+	// VerifyDiscourageUpgradableWitnessProgram fails witness programs with
+	// an unknown version.
+	VerifyDiscourageUpgradableWitnessProgram
+
+	// VerifyMinimalIf requires the argument of OP_IF and OP_NOTIF in
+	// witness scripts to be empty or exactly 0x01.
+	VerifyMinimalIf
+
+	// VerifyNullFail requires failed signatures to be empty.
+	VerifyNullFail
+
+	// VerifyWitnessPubKeyType requires public keys in segwit scripts to
+	// be compressed.
+	VerifyWitnessPubKeyType
+
+	// VerifyTaproot evaluates taproot spends of witness v1 programs
+	// (BIP341 and BIP342).
+	VerifyTaproot
+
+	// VerifyDiscourageUpgradableTaprootVersion fails script path spends of
+	// leaves with an unknown leaf version.
+	VerifyDiscourageUpgradableTaprootVersion
+
+	// VerifyDiscourageOpSuccess fails tapscripts that contain an
+	// OP_SUCCESSx opcode.
+	VerifyDiscourageOpSuccess
+
+	// VerifyDiscourageUpgradablePubKeyType fails tapscript signature
+	// checks with a public key of an unknown type.
+	VerifyDiscourageUpgradablePubKeyType
+)
+
+const (
+	VerifyNone Flags = 0
+
+	// StandardVerifyFlags are the flags that a tx must satisfy to be
+	// relayed by Bitcoin Core.
+	StandardVerifyFlags = VerifyP2SH | VerifyStrictEncoding |
+		VerifyDERSignatures | VerifyLowS | VerifyNullDummy |
+		VerifySigPushOnly | VerifyMinimalData |
+		VerifyDiscourageUpgradableNops | VerifyCleanStack |
+		VerifyCheckLockTimeVerify | VerifyCheckSequenceVerify |
+		VerifyWitness | VerifyDiscourageUpgradableWitnessProgram |
+		VerifyMinimalIf | VerifyNullFail | VerifyWitnessPubKeyType |
+		VerifyTaproot | VerifyDiscourageUpgradableTaprootVersion |
+		VerifyDiscourageOpSuccess | VerifyDiscourageUpgradablePubKeyType
+)
+
+const (
+	maxScriptSize             = 10000
+	maxScriptElementSize      = 520
+	maxOpsPerScript           = 201
+	maxStackSize              = 1000
+	maxPubKeysPerMultiSig     = 20
+	lockTimeThreshold         = 500000000
+	sequenceFinal             = 0xffffffff
+	sequenceLockTimeDisabled  = 1 << 31
+	sequenceLockTimeIsSeconds = 1 << 22
+	sequenceLockTimeMask      = 0x0000ffff
+)
+
+type sigVersion int
+
+const (
+	sigVersionBase sigVersion = iota
+	sigVersionWitnessV0
+	sigVersionTapscript
+)
+
+// TxContext is the view of the spending transaction that is needed to
+// evaluate the signature and locktime opcodes. It is implemented by *tx.Tx
+// and *tx.SpendContext.
+type TxContext interface {
+	SigHash(inputIndex int, subScript Script, hashType uint32) ([]byte,
+		error)
+	SegwitSigHash(inputIndex int, scriptCode Script, amount uint64,
+		hashType uint32) ([]byte, error)
+	GetVersion() int64
+	GetLocktime() uint32
+	GetSequence(inputIndex int) uint32
+}
+
+type engine struct {
+	tx       TxContext
+	inputIdx int
+	amount   uint64
+	flags    Flags
+
+	// taproot is set while evaluating a taproot spend.
+	taproot *taprootExec
+}
+
+// Evaluate verifies that the scriptSig and witness of the input at inputIdx
+// of tx satisfy the scriptPubKey of the output it spends. The amount is the
+// value of the spent output and is only needed for segwit inputs. A nil
+// error means the spend is valid under the given flags, otherwise the
+// returned Error describes the failure.
+//
+// Taproot spends are evaluated when VerifyTaproot is set, in which case tx
+// must implement TaprootTxContext for signatures to be checked. Other witness
+// versions, and v1 programs nested in P2SH, are treated as anyone-can-spend
+// unless VerifyDiscourageUpgradableWitnessProgram is set.
+func Evaluate(scriptSig, scriptPubKey Script, witness [][]byte,
+	tx TxContext, inputIdx int, amount uint64, flags Flags) error {
+
+	e := &engine{
+		tx:       tx,
+		inputIdx: inputIdx,
+		amount:   amount,
+		flags:    flags,
+	}
+
+	if e.hasFlag(VerifySigPushOnly) && !scriptSig.IsPushOnly() {
+		return scriptError(ErrSigPushOnly, "scriptSig is not push only")
+	}
+
+	var st stack
+	if err := e.execute(scriptSig, &st, sigVersionBase); err != nil {
+		return err
+	}
+
+	var stCopy stack
+	if e.hasFlag(VerifyP2SH) {
+		stCopy = append(stack{}, st...)
+	}
+
+	if err := e.execute(scriptPubKey, &st, sigVersionBase); err != nil {
+		return err
+	}
+
+	if err := checkTrue(st); err != nil {
+		return err
+	}
+
+	var hadWitness bool
+	if e.hasFlag(VerifyWitness) {
+		if version, program, ok := scriptPubKey.witnessProgram(); ok {
+			hadWitness = true
+
+			if len(scriptSig) != 0 {
+				return scriptError(ErrWitnessMalleated,
+					"native witness program spent with a "+
+						"non-empty scriptSig")
+			}
+
+			err := e.verifyWitnessProgram(
+				witness, version, program, false,
+			)
+			if err != nil {
+				return err
+			}
+
+			// Leave a single element on the stack so that the
+			// clean stack check passes.
+			st = st[:1]
+		}
+	}
+
+	if e.hasFlag(VerifyP2SH) && scriptPubKey.isP2SH() {
+		if !scriptSig.IsPushOnly() {
+			return scriptError(ErrSigPushOnly, "P2SH scriptSig is "+
+				"not push only")
+		}
+
+		st = stCopy
+
+		redeemBytes, err := st.pop()
+		if err != nil {
+			return err
+		}
+
+		if len(redeemBytes) > maxScriptSize {
+			return scriptError(ErrScriptSize, fmt.Sprintf("redeem "+
+				"script size %d exceeds the max of %d",
+				len(redeemBytes), maxScriptSize))
+		}
+
+		redeem, err := ParseRaw(redeemBytes)
+		if err != nil {
+			return err
+		}
+
+		if err := e.execute(redeem, &st, sigVersionBase); err != nil {
+			return err
+		}
+
+		if err := checkTrue(st); err != nil {
+			return err
+		}
+
+		version, program, ok := redeem.witnessProgram()
+		if e.hasFlag(VerifyWitness) && ok {
+			hadWitness = true
+
+			push := pushElem(redeemBytes)
+			if len(scriptSig) != 1 || scriptSig[0].Opcode != push.Opcode ||
+				!bytes.Equal(scriptSig[0].Data, push.Data) {
+
+				return scriptError(ErrWitnessMalleatedP2SH,
+					"P2SH witness program spent with a "+
+						"scriptSig that is not a single "+
+						"push of the redeem script")
+			}
+
+			err := e.verifyWitnessProgram(
+				witness, version, program, true,
+			)
+			if err != nil {
+				return err
+			}
+
+			st = st[:1]
+		}
+	}
+
+	if e.hasFlag(VerifyCleanStack) && len(st) != 1 {
+		return scriptError(ErrCleanStack, fmt.Sprintf("stack contains "+
+			"%d elements after evaluation, expected 1", len(st)))
+	}
+
+	if e.hasFlag(VerifyWitness) && !hadWitness && len(witness) != 0 {
+		return scriptError(ErrWitnessUnexpected, "witness provided for "+
+			"a non-witness script")
+	}
+
+	return nil
+}
+
+func (e *engine) hasFlag(f Flags) bool {
+	return e.flags&f == f
+}
+
+func checkTrue(st stack) error {
+	if len(st) == 0 {
+		return scriptError(ErrEvalFalse, "stack empty after evaluation")
+	}
+
+	if !asBool(st[len(st)-1]) {
+		return scriptError(ErrEvalFalse, "false stack entry at end of "+
+			"script evaluation")
+	}
+
+	return nil
+}
+
+// verifyWitnessProgram evaluates the witness of a witness program. isP2SH is
+// set if the program is the redeem script of a P2SH output.
+func (e *engine) verifyWitnessProgram(witness [][]byte, version int,
+	program []byte, isP2SH bool) error {
+
+	var (
+		s   Script
+		st  stack
+		err error
+	)
+
+	switch {
+	case version == 0 && len(program) == 20:
+		if len(witness) != 2 {
+			return scriptError(ErrWitnessProgramMismatch, fmt.Sprintf(
+				"P2WPKH witness must have 2 items, has %d",
+				len(witness)))
+		}
+
+		s = Script{
+			{Opcode: OP_DUP},
+			{Opcode: OP_HASH160},
+			pushElem(program),
+			{Opcode: OP_EQUALVERIFY},
+			{Opcode: OP_CHECKSIG},
+		}
+		st = append(stack{}, witness...)
+
+	case version == 0 && len(program) == 32:
+		if len(witness) == 0 {
+			return scriptError(ErrWitnessProgramWitnessEmpty,
+				"P2WSH witness is empty")
+		}
+
+		witnessScript := witness[len(witness)-1]
+		if len(witnessScript) > maxScriptSize {
+			return scriptError(ErrScriptSize, fmt.Sprintf("witness "+
+				"script size %d exceeds the max of %d",
+				len(witnessScript), maxScriptSize))
+		}
+
+		h := sha256.Sum256(witnessScript)
+		if !bytes.Equal(h[:], program) {
+			return scriptError(ErrWitnessProgramMismatch,
+				"witness script does not match the P2WSH "+
+					"program")
+		}
+
+		s, err = ParseRaw(witnessScript)
+		if err != nil {
+			return err
+		}
+		st = append(stack{}, witness[:len(witness)-1]...)
+
+	case version == 0:
+		return scriptError(ErrWitnessProgramWrongLength, fmt.Sprintf(
+			"witness v0 program has invalid length %d",
+			len(program)))
+
+	case version == 1 && len(program) == 32 && !isP2SH:
+		if !e.hasFlag(VerifyTaproot) {
+			return nil
+		}
+
+		return e.verifyTaproot(witness, program)
+
+	default:
+		if e.hasFlag(VerifyDiscourageUpgradableWitnessProgram) {
+			return scriptError(ErrDiscourageUpgradableWitnessProgram,
+				fmt.Sprintf("witness program version %d is "+
+					"reserved for soft forks", version))
+		}
+
+		return nil
+	}
+
+	return e.executeWitnessScript(s, st, sigVersionWitnessV0)
+}
+
+// executeWitnessScript runs a P2WSH or tapscript witness script with the
+// rest of the witness as its initial stack.
+func (e *engine) executeWitnessScript(s Script, st stack,
+	sv sigVersion) error {
+
+	for _, item := range st {
+		if len(item) > maxScriptElementSize {
+			return scriptError(ErrPushSize, fmt.Sprintf("witness "+
+				"item of size %d exceeds the max of %d",
+				len(item), maxScriptElementSize))
+		}
+	}
+
+	if err := e.execute(s, &st, sv); err != nil {
+		return err
+	}
+
+	// Witness scripts implicitly require a clean stack.
+	if len(st) != 1 {
+		return scriptError(ErrCleanStack, fmt.Sprintf("witness stack "+
+			"contains %d elements after evaluation, expected 1",
+			len(st)))
+	}
+
+	return checkTrue(st)
+}
+
+// execState is the state of the evaluation of a single script.
+type execState struct {
+	script     Script
+	sigVersion sigVersion
+	st         *stack
+	alt        stack
+	condStack  []bool
+	opCount    int
+
+	// codeSep is the index of the first element after the most recently
+	// executed OP_CODESEPARATOR.
+	codeSep int
+}
+
+// codeSepPos returns the position of the most recently executed
+// OP_CODESEPARATOR as committed to by tapscript signatures.
+func (s *execState) codeSepPos() uint32 {
+	if s.codeSep == 0 {
+		return noCodeSeparator
+	}
+
+	return uint32(s.codeSep - 1)
+}
+
+func (s *execState) executing() bool {
+	for _, c := range s.condStack {
+		if !c {
+			return false
+		}
+	}
+
+	return true
+}
+
+// execute runs the script using st as the main stack. Tapscripts are not
+// subject to the script size and operation count limits.
+func (e *engine) execute(script Script, st *stack, sv sigVersion) error {
+	if sv != sigVersionTapscript && script.rawLen() > maxScriptSize {
+		return scriptError(ErrScriptSize, fmt.Sprintf("script size %d "+
+			"exceeds the max of %d", script.rawLen(), maxScriptSize))
+	}
+
+	s := &execState{
+		script:     script,
+		sigVersion: sv,
+		st:         st,
+	}
+
+	for i, el := range script {
+		op := el.Opcode
+		exec := s.executing()
+
+		if len(el.Data) > maxScriptElementSize {
+			return scriptError(ErrPushSize, fmt.Sprintf("push of "+
+				"size %d exceeds the max of %d", len(el.Data),
+				maxScriptElementSize))
+		}
+
+		if op > OP_16 && sv != sigVersionTapscript {
+			s.opCount++
+			if s.opCount > maxOpsPerScript {
+				return scriptError(ErrOpCount, fmt.Sprintf(
+					"exceeded max operation limit of %d",
+					maxOpsPerScript))
+			}
+		}
+
+		if isDisabled(op) {
+			return scriptError(ErrDisabledOpcode, fmt.Sprintf(
+				"attempt to execute disabled opcode 0x%02x", op))
+		}
+
+		switch {
+		case exec && op <= OP_PUSHDATA4:
+			if e.hasFlag(VerifyMinimalData) && !isMinimalPush(el) {
+				return scriptError(ErrMinimalData, fmt.Sprintf(
+					"push at index %d is not minimal", i))
+			}
+
+			data := el.Data
+			if data == nil {
+				data = []byte{}
+			}
+			s.st.push(data)
+
+		case exec || (op >= OP_IF && op <= OP_ENDIF):
+			if err := e.step(s, i, op); err != nil {
+				return err
+			}
+		}
+
+		if len(*s.st)+len(s.alt) > maxStackSize {
+			return scriptError(ErrStackSize, fmt.Sprintf("combined "+
+				"stack size exceeds the max of %d", maxStackSize))
+		}
+	}
+
+	if len(s.condStack) != 0 {
+		return scriptError(ErrUnbalancedConditional, "end of script "+
+			"reached in conditional execution")
+	}
+
+	return nil
+}
+
+func isDisabled(op Opcode) bool {
+	switch op {
+	case OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT, OP_INVERT, OP_AND, OP_OR,
+		OP_XOR, OP_2MUL, OP_2DIV, OP_MUL, OP_DIV, OP_MOD, OP_LSHIFT,
+		OP_RSHIFT:
+
+		return true
+	}
+
+	return false
+}
+
+// isMinimalPush returns true if the element pushes its data using the
+// smallest possible opcode.
+func isMinimalPush(el Elem) bool {
+	op := el.Opcode
+	data := el.Data
+
+	switch {
+	case len(data) == 0:
+		return op == OP_0
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		// Should have used OP_1 .. OP_16.
+		return false
+	case len(data) == 1 && data[0] == 0x81:
+		// Should have used OP_1NEGATE.
+		return false
+	case len(data) <= 75:
+		return int(op) == len(data)
+	case len(data) <= 255:
+		return op == OP_PUSHDATA1
+	case len(data) <= 65535:
+		return op == OP_PUSHDATA2
+	}
+
+	return true
+}
+
+// step executes a single non-push opcode.
+func (e *engine) step(s *execState, idx int, op Opcode) error {
+	st := s.st
+	requireMinimal := e.hasFlag(VerifyMinimalData)
+
+	switch op {
+	case OP_1NEGATE:
+		st.pushNum(-1)
+
+	case OP_1, OP_2, OP_3, OP_4, OP_5, OP_6, OP_7, OP_8, OP_9, OP_10,
+		OP_11, OP_12, OP_13, OP_14, OP_15, OP_16:
+
+		st.pushNum(int64(op - OP_1 + 1))
+
+	// Flow control.
+	case OP_NOP:
+
+	case OP_IF, OP_NOTIF:
+		val := false
+		if s.executing() {
+			b, err := st.pop()
+			if err != nil {
+				return scriptError(ErrUnbalancedConditional,
+					"OP_IF with empty stack")
+			}
+
+			// Minimal arguments are policy for witness v0 scripts
+			// but consensus for tapscripts.
+			if s.sigVersion == sigVersionTapscript ||
+				(s.sigVersion == sigVersionWitnessV0 &&
+					e.hasFlag(VerifyMinimalIf)) {
+
+				if len(b) > 1 || (len(b) == 1 && b[0] != 1) {
+					return scriptError(ErrMinimalIf,
+						"OP_IF argument is not minimal")
+				}
+			}
+
+			val = asBool(b)
+			if op == OP_NOTIF {
+				val = !val
+			}
+		}
+		s.condStack = append(s.condStack, val)
+
+	case OP_ELSE:
+		if len(s.condStack) == 0 {
+			return scriptError(ErrUnbalancedConditional,
+				"OP_ELSE without matching OP_IF")
+		}
+		s.condStack[len(s.condStack)-1] = !s.condStack[len(s.condStack)-1]
+
+	case OP_ENDIF:
+		if len(s.condStack) == 0 {
+			return scriptError(ErrUnbalancedConditional,
+				"OP_ENDIF without matching OP_IF")
+		}
+		s.condStack = s.condStack[:len(s.condStack)-1]
+
+	case OP_VERIFY:
+		ok, err := st.popBool()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return scriptError(ErrVerify, "OP_VERIFY failed")
+		}
+
+	case OP_RETURN:
+		return scriptError(ErrOpReturn, "script returned early")
+
+	// Stack operations.
+	case OP_TOALTSTACK:
+		b, err := st.pop()
+		if err != nil {
+			return err
+		}
+		s.alt.push(b)
+
+	case OP_FROMALTSTACK:
+		b, err := s.alt.pop()
+		if err != nil {
+			return scriptError(ErrInvalidAltStackOperation,
+				"OP_FROMALTSTACK with empty alt stack")
+		}
+		st.push(b)
+
+	case OP_2DROP:
+		if err := st.checkSize(2); err != nil {
+			return err
+		}
+		*st = (*st)[:len(*st)-2]
+
+	case OP_2DUP:
+		return st.dupN(2)
+
+	case OP_3DUP:
+		return st.dupN(3)
+
+	case OP_2OVER:
+		if err := st.checkSize(4); err != nil {
+			return err
+		}
+		a, _ := st.peek(3)
+		b, _ := st.peek(2)
+		st.push(a)
+		st.push(b)
+
+	case OP_2ROT:
+		if err := st.checkSize(6); err != nil {
+			return err
+		}
+		a, _ := st.remove(5)
+		b, _ := st.remove(4)
+		st.push(a)
+		st.push(b)
+
+	case OP_2SWAP:
+		if err := st.checkSize(4); err != nil {
+			return err
+		}
+		a, _ := st.remove(3)
+		b, _ := st.remove(2)
+		st.push(a)
+		st.push(b)
+
+	case OP_IFDUP:
+		b, err := st.peek(0)
+		if err != nil {
+			return err
+		}
+		if asBool(b) {
+			st.push(b)
+		}
+
+	case OP_DEPTH:
+		st.pushNum(int64(len(*st)))
+
+	case OP_DROP:
+		if _, err := st.pop(); err != nil {
+			return err
+		}
+
+	case OP_DUP:
+		return st.dupN(1)
+
+	case OP_NIP:
+		if _, err := st.remove(1); err != nil {
+			return err
+		}
+
+	case OP_OVER:
+		b, err := st.peek(1)
+		if err != nil {
+			return err
+		}
+		st.push(b)
+
+	case OP_PICK, OP_ROLL:
+		n, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		if n < 0 || n >= int64(len(*st)) {
+			return scriptError(ErrInvalidStackOperation, fmt.Sprintf(
+				"index %d is invalid for stack size %d", n,
+				len(*st)))
+		}
+
+		var b []byte
+		if op == OP_PICK {
+			b, _ = st.peek(int(n))
+		} else {
+			b, _ = st.remove(int(n))
+		}
+		st.push(b)
+
+	case OP_ROT:
+		b, err := st.remove(2)
+		if err != nil {
+			return err
+		}
+		st.push(b)
+
+	case OP_SWAP:
+		b, err := st.remove(1)
+		if err != nil {
+			return err
+		}
+		st.push(b)
+
+	case OP_TUCK:
+		if err := st.checkSize(2); err != nil {
+			return err
+		}
+		top, _ := st.peek(0)
+		idx := len(*st) - 2
+		*st = append((*st)[:idx], append(stack{top}, (*st)[idx:]...)...)
+
+	case OP_SIZE:
+		b, err := st.peek(0)
+		if err != nil {
+			return err
+		}
+		st.pushNum(int64(len(b)))
+
+	// Bitwise logic.
+	case OP_EQUAL, OP_EQUALVERIFY:
+		if err := st.checkSize(2); err != nil {
+			return err
+		}
+		a, _ := st.pop()
+		b, _ := st.pop()
+		equal := bytes.Equal(a, b)
+
+		if op == OP_EQUALVERIFY {
+			if !equal {
+				return scriptError(ErrEqualVerify,
+					"OP_EQUALVERIFY failed")
+			}
+			break
+		}
+		st.pushBool(equal)
+
+	// Arithmetic.
+	case OP_1ADD, OP_1SUB, OP_NEGATE, OP_ABS, OP_NOT, OP_0NOTEQUAL:
+		n, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case OP_1ADD:
+			n++
+		case OP_1SUB:
+			n--
+		case OP_NEGATE:
+			n = -n
+		case OP_ABS:
+			if n < 0 {
+				n = -n
+			}
+		case OP_NOT:
+			n = boolToNum(n == 0)
+		case OP_0NOTEQUAL:
+			n = boolToNum(n != 0)
+		}
+		st.pushNum(n)
+
+	case OP_ADD, OP_SUB, OP_BOOLAND, OP_BOOLOR, OP_NUMEQUAL,
+		OP_NUMEQUALVERIFY, OP_NUMNOTEQUAL, OP_LESSTHAN, OP_GREATERTHAN,
+		OP_LESSTHANOREQUAL, OP_GREATERTHANOREQUAL, OP_MIN, OP_MAX:
+
+		if err := st.checkSize(2); err != nil {
+			return err
+		}
+		b, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		a, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+
+		var n int64
+		switch op {
+		case OP_ADD:
+			n = a + b
+		case OP_SUB:
+			n = a - b
+		case OP_BOOLAND:
+			n = boolToNum(a != 0 && b != 0)
+		case OP_BOOLOR:
+			n = boolToNum(a != 0 || b != 0)
+		case OP_NUMEQUAL, OP_NUMEQUALVERIFY:
+			n = boolToNum(a == b)
+		case OP_NUMNOTEQUAL:
+			n = boolToNum(a != b)
+		case OP_LESSTHAN:
+			n = boolToNum(a < b)
+		case OP_GREATERTHAN:
+			n = boolToNum(a > b)
+		case OP_LESSTHANOREQUAL:
+			n = boolToNum(a <= b)
+		case OP_GREATERTHANOREQUAL:
+			n = boolToNum(a >= b)
+		case OP_MIN:
+			n = a
+			if b < a {
+				n = b
+			}
+		case OP_MAX:
+			n = a
+			if b > a {
+				n = b
+			}
+		}
+
+		if op == OP_NUMEQUALVERIFY {
+			if n == 0 {
+				return scriptError(ErrNumEqualVerify,
+					"OP_NUMEQUALVERIFY failed")
+			}
+			break
+		}
+		st.pushNum(n)
+
+	case OP_WITHIN:
+		if err := st.checkSize(3); err != nil {
+			return err
+		}
+		max, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		min, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		x, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		st.pushBool(min <= x && x < max)
+
+	// Crypto.
+	case OP_RIPEMD160, OP_SHA1, OP_SHA256, OP_HASH160, OP_HASH256:
+		b, err := st.pop()
+		if err != nil {
+			return err
+		}
+
+		var h []byte
+		switch op {
+		case OP_RIPEMD160:
+			r := ripemd160.New()
+			r.Write(b)
+			h = r.Sum(nil)
+		case OP_SHA1:
+			s := sha1.Sum(b)
+			h = s[:]
+		case OP_SHA256:
+			h = helpers.Sha256(b)
+		case OP_HASH160:
+			h = helpers.Hash160(b)
+		case OP_HASH256:
+			h = helpers.DoubleSha256(b)
+		}
+		st.push(h)
+
+	case OP_CODESEPARATOR:
+		s.codeSep = idx + 1
+
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		if err := st.checkSize(2); err != nil {
+			return err
+		}
+		pubKey, _ := st.pop()
+		sig, _ := st.pop()
+
+		var (
+			ok  bool
+			err error
+		)
+		if s.sigVersion == sigVersionTapscript {
+			ok, err = e.checkSigTapscript(
+				sig, pubKey, s.codeSepPos(),
+			)
+		} else {
+			subScript := s.script[s.codeSep:]
+			if s.sigVersion == sigVersionBase {
+				subScript = subScript.FindAndDelete(sig)
+			}
+
+			ok, err = e.checkSig(
+				sig, pubKey, subScript, s.sigVersion,
+			)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !ok && e.hasFlag(VerifyNullFail) && len(sig) > 0 {
+			return scriptError(ErrSigNullFail, "signature must be "+
+				"empty if the check fails")
+		}
+
+		if op == OP_CHECKSIGVERIFY {
+			if !ok {
+				return scriptError(ErrCheckSigVerify,
+					"OP_CHECKSIGVERIFY failed")
+			}
+			break
+		}
+		st.pushBool(ok)
+
+	case OP_CHECKSIGADD:
+		if s.sigVersion != sigVersionTapscript {
+			return scriptError(ErrBadOpcode, fmt.Sprintf(
+				"attempt to execute invalid opcode 0x%02x",
+				byte(op)))
+		}
+
+		if err := st.checkSize(3); err != nil {
+			return err
+		}
+		pubKey, _ := st.pop()
+		n, err := st.popNum(requireMinimal)
+		if err != nil {
+			return err
+		}
+		sig, _ := st.pop()
+
+		ok, err := e.checkSigTapscript(sig, pubKey, s.codeSepPos())
+		if err != nil {
+			return err
+		}
+		st.pushNum(n + boolToNum(ok))
+
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		if s.sigVersion == sigVersionTapscript {
+			return scriptError(ErrTapscriptCheckMultiSig,
+				"OP_CHECKMULTISIG is disabled in tapscript")
+		}
+
+		ok, err := e.checkMultiSig(s)
+		if err != nil {
+			return err
+		}
+
+		if op == OP_CHECKMULTISIGVERIFY {
+			if !ok {
+				return scriptError(ErrCheckMultiSigVerify,
+					"OP_CHECKMULTISIGVERIFY failed")
+			}
+			break
+		}
+		st.pushBool(ok)
+
+	// Locktime.
+	case OP_CHECKLOCKTIMEVERIFY:
+		if !e.hasFlag(VerifyCheckLockTimeVerify) {
+			return e.upgradableNop(op)
+		}
+		return e.checkLockTime(st)
+
+	case OP_CHECKSEQUENCEVERIFY:
+		if !e.hasFlag(VerifyCheckSequenceVerify) {
+			return e.upgradableNop(op)
+		}
+		return e.checkSequence(st)
+
+	case OP_NOP1, OP_NOP4, OP_NOP5, OP_NOP6, OP_NOP7, OP_NOP8, OP_NOP9,
+		OP_NOP10:
+
+		return e.upgradableNop(op)
+
+	default:
+		return scriptError(ErrBadOpcode, fmt.Sprintf("attempt to "+
+			"execute invalid opcode 0x%02x", byte(op)))
+	}
+
+	return nil
+}
+
+func boolToNum(v bool) int64 {
+	if v {
+		return 1
+	}
+
+	return 0
+}
+
+func (e *engine) upgradableNop(op Opcode) error {
+	if e.hasFlag(VerifyDiscourageUpgradableNops) {
+		return scriptError(ErrDiscourageUpgradableNops, fmt.Sprintf(
+			"opcode 0x%02x is reserved for soft forks", byte(op)))
+	}
+
+	return nil
+}
+
+func (e *engine) checkLockTime(st *stack) error {
+	b, err := st.peek(0)
+	if err != nil {
+		return err
+	}
+
+	lockTime, err := decodeNum(
+		b, e.hasFlag(VerifyMinimalData), locktimeScriptNumLen,
+	)
+	if err != nil {
+		return err
+	}
+
+	if lockTime < 0 {
+		return scriptError(ErrNegativeLocktime, fmt.Sprintf("negative "+
+			"locktime %d", lockTime))
+	}
+
+	if e.tx == nil {
+		return scriptError(ErrUnsatisfiedLocktime, "no tx to check "+
+			"the locktime against")
+	}
+
+	txLockTime := int64(e.tx.GetLocktime())
+
+	// The locktimes must both be block heights or both be timestamps.
+	if (txLockTime < lockTimeThreshold) != (lockTime < lockTimeThreshold) {
+		return scriptError(ErrUnsatisfiedLocktime, fmt.Sprintf("locktime "+
+			"type mismatch: tx %d, script %d", txLockTime, lockTime))
+	}
+
+	if lockTime > txLockTime {
+		return scriptError(ErrUnsatisfiedLocktime, fmt.Sprintf("locktime "+
+			"requirement not satisfied: tx %d, script %d",
+			txLockTime, lockTime))
+	}
+
+	// A final sequence disables the tx locktime so it can't be relied on.
+	if e.tx.GetSequence(e.inputIdx) == sequenceFinal {
+		return scriptError(ErrUnsatisfiedLocktime, "input sequence is "+
+			"final")
+	}
+
+	return nil
+}
+
+func (e *engine) checkSequence(st *stack) error {
+	b, err := st.peek(0)
+	if err != nil {
+		return err
+	}
+
+	seq, err := decodeNum(
+		b, e.hasFlag(VerifyMinimalData), locktimeScriptNumLen,
+	)
+	if err != nil {
+		return err
+	}
+
+	if seq < 0 {
+		return scriptError(ErrNegativeLocktime, fmt.Sprintf("negative "+
+			"sequence %d", seq))
+	}
+
+	// With the disable flag set the opcode behaves as a NOP.
+	if seq&sequenceLockTimeDisabled != 0 {
+		return nil
+	}
+
+	if e.tx == nil {
+		return scriptError(ErrUnsatisfiedLocktime, "no tx to check "+
+			"the sequence against")
+	}
+
+	if e.tx.GetVersion() < 2 {
+		return scriptError(ErrUnsatisfiedLocktime, fmt.Sprintf("tx "+
+			"version %d does not support relative locktimes",
+			e.tx.GetVersion()))
+	}
+
+	txSeq := int64(e.tx.GetSequence(e.inputIdx))
+	if txSeq&sequenceLockTimeDisabled != 0 {
+		return scriptError(ErrUnsatisfiedLocktime, "input sequence has "+
+			"relative locktimes disabled")
+	}
+
+	const mask = sequenceLockTimeIsSeconds | sequenceLockTimeMask
+	txSeq &= mask
+	seq &= mask
+
+	if (txSeq < sequenceLockTimeIsSeconds) !=
+		(seq < sequenceLockTimeIsSeconds) {
+
+		return scriptError(ErrUnsatisfiedLocktime, fmt.Sprintf("sequence "+
+			"type mismatch: tx %d, script %d", txSeq, seq))
+	}
+
+	if seq > txSeq {
+		return scriptError(ErrUnsatisfiedLocktime, fmt.Sprintf("relative "+
+			"locktime requirement not satisfied: tx %d, script %d",
+			txSeq, seq))
+	}
+
+	return nil
+}
+
+// checkMultiSig evaluates OP_CHECKMULTISIG leaving the stack with all of its
+// arguments removed.
+func (e *engine) checkMultiSig(s *execState) (bool, error) {
+	st := s.st
+	requireMinimal := e.hasFlag(VerifyMinimalData)
+
+	b, err := st.peek(0)
+	if err != nil {
+		return false, err
+	}
+
+	numKeys, err := decodeNum(b, requireMinimal, defaultScriptNumLen)
+	if err != nil {
+		return false, err
+	}
+
+	if numKeys < 0 || numKeys > maxPubKeysPerMultiSig {
+		return false, scriptError(ErrPubKeyCount, fmt.Sprintf("invalid "+
+			"number of public keys %d", numKeys))
+	}
+
+	s.opCount += int(numKeys)
+	if s.opCount > maxOpsPerScript {
+		return false, scriptError(ErrOpCount, fmt.Sprintf("exceeded "+
+			"max operation limit of %d", maxOpsPerScript))
+	}
+
+	keyIdx := 1
+	i := 1 + int(numKeys)
+
+	b, err = st.peek(i)
+	if err != nil {
+		return false, err
+	}
+
+	numSigs, err := decodeNum(b, requireMinimal, defaultScriptNumLen)
+	if err != nil {
+		return false, err
+	}
+
+	if numSigs < 0 || numSigs > numKeys {
+		return false, scriptError(ErrSigCount, fmt.Sprintf("invalid "+
+			"number of signatures %d", numSigs))
+	}
+
+	sigIdx := i + 1
+	i += int(numSigs) + 1
+
+	// The extra "dummy" element consumed due to an off by one error in the
+	// original implementation.
+	if err := st.checkSize(i + 1); err != nil {
+		return false, err
+	}
+
+	subScript := s.script[s.codeSep:]
+	if s.sigVersion == sigVersionBase {
+		for k := 0; k < int(numSigs); k++ {
+			sig, _ := st.peek(sigIdx + k)
+			subScript = subScript.FindAndDelete(sig)
+		}
+	}
+
+	var (
+		success       = true
+		sigsRemaining = int(numSigs)
+		keysRemaining = int(numKeys)
+	)
+	for success && sigsRemaining > 0 {
+		sig, _ := st.peek(sigIdx)
+		pubKey, _ := st.peek(keyIdx)
+
+		ok, err := e.checkSig(sig, pubKey, subScript, s.sigVersion)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			sigIdx++
+			sigsRemaining--
+		}
+		keyIdx++
+		keysRemaining--
+
+		// There are not enough keys left to satisfy the remaining
+		// signatures.
+		if sigsRemaining > keysRemaining {
+			success = false
+		}
+	}
+
+	if !success && e.hasFlag(VerifyNullFail) {
+		for k := 0; k < int(numSigs); k++ {
+			sig, _ := st.peek(i - int(numSigs) + k)
+			if len(sig) > 0 {
+				return false, scriptError(ErrSigNullFail,
+					"signatures must be empty if the check "+
+						"fails")
+			}
+		}
+	}
+
+	*st = (*st)[:len(*st)-i]
+
+	dummy, _ := st.pop()
+	if e.hasFlag(VerifyNullDummy) && len(dummy) != 0 {
+		return false, scriptError(ErrSigNullDummy, "OP_CHECKMULTISIG "+
+			"dummy argument is not empty")
+	}
+
+	return success, nil
+}
+
+// checkSig verifies a signature (with trailing sighash type byte) against
+// the given public key. Encoding errors are only returned when the flags
+// require them, otherwise a badly encoded signature simply fails to verify.
+func (e *engine) checkSig(sig, pubKey []byte, subScript Script,
+	sv sigVersion) (bool, error) {
+
+	if err := e.checkSignatureEncoding(sig); err != nil {
+		return false, err
+	}
+
+	if err := e.checkPubKeyEncoding(pubKey, sv); err != nil {
+		return false, err
+	}
+
+	// An empty signature has no sighash type and simply fails.
+	der, hashByte, err := signature.SplitHashType(sig)
+	if err != nil {
+		return false, nil
+	}
+	hashType := uint32(hashByte)
+
+	if e.tx == nil {
+		return false, scriptError(ErrSigHash, "no tx to compute the "+
+			"signature hash for")
+	}
+
+	var z []byte
+	switch sv {
+	case sigVersionBase:
+		z, err = e.tx.SigHash(e.inputIdx, subScript, hashType)
+	case sigVersionWitnessV0:
+		z, err = e.tx.SegwitSigHash(
+			e.inputIdx, subScript, e.amount, hashType,
+		)
+	}
+	if err != nil {
+		return false, scriptError(ErrSigHash, err.Error())
+	}
+
+	// Signatures that are not strict DER are only allowed when the flags
+	// don't require it, in which case they are parsed as OpenSSL did for
+	// the sake of historical transactions.
+	parsedSig, err := signature.Parse(der)
+	if err != nil {
+		parsedSig, err = signature.ParseLax(der)
+		if err != nil {
+			return false, nil
+		}
+	}
+
+	if !isValidPubKey(pubKey) {
+		return false, nil
+	}
+
+	p, err := s256point.Parse(pubKey)
+	if err != nil {
+		return false, nil
+	}
+
+	return (&s256point.S256Point{Point: p}).Verify(z, parsedSig)
+}
+
+func (e *engine) checkSignatureEncoding(sig []byte) error {
+	// An empty signature is allowed as a compact way of providing an
+	// invalid signature.
+	if len(sig) == 0 {
+		return nil
+	}
+
+	if (e.hasFlag(VerifyDERSignatures) || e.hasFlag(VerifyLowS) ||
+		e.hasFlag(VerifyStrictEncoding)) &&
+		!isValidSignatureEncoding(sig) {
+
+		return scriptError(ErrSigDER, "signature is not strict DER")
+	}
+
+	if e.hasFlag(VerifyLowS) {
+		parsed, err := signature.Parse(sig[:len(sig)-1])
+		if err != nil {
+			return scriptError(ErrSigDER, err.Error())
+		}
+
+		if !parsed.IsLowS() {
+			return scriptError(ErrSigHighS, "signature S value is "+
+				"not low")
+		}
+	}
+
+	if e.hasFlag(VerifyStrictEncoding) {
+		hashType := uint32(sig[len(sig)-1]) &^ 0x80
+		if hashType < 1 || hashType > 3 {
+			return scriptError(ErrSigHashType, fmt.Sprintf("invalid "+
+				"sighash type 0x%02x", sig[len(sig)-1]))
+		}
+	}
+
+	return nil
+}
+
+func (e *engine) checkPubKeyEncoding(pubKey []byte, sv sigVersion) error {
+	if e.hasFlag(VerifyStrictEncoding) && !isValidPubKey(pubKey) {
+		return scriptError(ErrPubKeyType, "public key is not validly "+
+			"encoded")
+	}
+
+	if sv == sigVersionWitnessV0 && e.hasFlag(VerifyWitnessPubKeyType) &&
+		len(pubKey) != s256point.PubKeyBytesLenCompressed {
+
+		return scriptError(ErrWitnessPubKeyType, "segwit public keys "+
+			"must be compressed")
+	}
+
+	return nil
+}
+
+func isValidPubKey(pubKey []byte) bool {
+	switch len(pubKey) {
+	case s256point.PubKeyBytesLenCompressed:
+		return pubKey[0] == 0x02 || pubKey[0] == 0x03
+	case s256point.PubKeyBytesLenUncompressed:
+		return pubKey[0] == 0x04
+	}
+
+	return false
+}
+
+// isValidSignatureEncoding checks that sig (including its trailing sighash
+// type byte) is a strict DER encoding as required by BIP66.
+func isValidSignatureEncoding(sig []byte) bool {
+	der, _, err := signature.SplitHashType(sig)
+	if err != nil {
+		return false
+	}
+
+	_, err = signature.Parse(der)
+
+	return err == nil
+}
+
+// IsPushOnly returns true if the script only contains push opcodes.
+func (s Script) IsPushOnly() bool {
+	for _, e := range s {
+		if e.Opcode > OP_16 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isP2SH returns true if the script is of the form
+// OP_HASH160 <20 bytes> OP_EQUAL.
+func (s Script) isP2SH() bool {
+	return len(s) == 3 &&
+		s[0].Opcode == OP_HASH160 &&
+		s[1].Opcode == 20 && len(s[1].Data) == 20 &&
+		s[2].Opcode == OP_EQUAL
+}
+
+// witnessProgram returns the version and program if the script is a
+// witness program: a version opcode followed by a direct push of 2 to 40
+// bytes.
+func (s Script) witnessProgram() (int, []byte, bool) {
+	if len(s) != 2 {
+		return 0, nil, false
+	}
+
+	version := s[0].Opcode
+	if version != OP_0 && (version < OP_1 || version > OP_16) {
+		return 0, nil, false
+	}
+
+	push := s[1]
+	if int(push.Opcode) != len(push.Data) || len(push.Data) < 2 ||
+		len(push.Data) > 40 {
+
+		return 0, nil, false
+	}
+
+	if version == OP_0 {
+		return 0, push.Data, true
+	}
+
+	return int(version-OP_1) + 1, push.Data, true
+}
+
+// rawLen returns the length of the serialized script without its length
+// prefix.
+func (s Script) rawLen() int {
+	n := 0
+	for _, e := range s {
+		n++
+		switch e.Opcode {
+		case OP_PUSHDATA1:
+			n++
+		case OP_PUSHDATA2:
+			n += 2
+		case OP_PUSHDATA4:
+			n += 4
+		}
+		n += len(e.Data)
+	}
+
+	return n
+}
@@ -0,0 +1,820 @@
+package script_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/taproot"
+	"github.com/ellemouton/btc/tx"
+	"github.com/stretchr/testify/require"
+)
+
+// parseRaw parses a hex encoded script that has no length prefix.
+func parseRaw(t *testing.T, s string) script.Script {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	res, err := script.ParseRaw(b)
+	require.NoError(t, err)
+
+	return res
+}
+
+func push(b []byte) string {
+	return hex.EncodeToString(append([]byte{byte(len(b))}, b...))
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		scriptSig    string
+		scriptPubKey string
+		flags        script.Flags
+		expectErr    bool
+		code         script.ErrorCode
+	}{
+		{
+			name:         "add",
+			scriptSig:    "5253",
+			scriptPubKey: "935587",
+		},
+		{
+			name:         "rot",
+			scriptSig:    "515253",
+			scriptPubKey: "7b" + "5188" + "5388" + "5287",
+		},
+		{
+			name:         "if else",
+			scriptSig:    "00",
+			scriptPubKey: "63" + "00" + "67" + "51" + "68",
+		},
+		{
+			name:         "notif",
+			scriptSig:    "00",
+			scriptPubKey: "64" + "51" + "67" + "00" + "68",
+		},
+		{
+			name:         "within",
+			scriptSig:    "53",
+			scriptPubKey: "52" + "54" + "a5",
+		},
+		{
+			name:         "pick and roll",
+			scriptSig:    "515253",
+			scriptPubKey: "5279" + "5188" + "527a" + "5188" + "5388" + "5287",
+		},
+		{
+			name:         "size and hash",
+			scriptSig:    "0161",
+			scriptPubKey: "82" + "5188" + "a8" + push(helpers.Sha256([]byte("a"))) + "87",
+		},
+		{
+			name:         "false",
+			scriptSig:    "51",
+			scriptPubKey: "00",
+			expectErr:    true,
+			code:         script.ErrEvalFalse,
+		},
+		{
+			name:         "op return",
+			scriptSig:    "51",
+			scriptPubKey: "6a",
+			expectErr:    true,
+			code:         script.ErrOpReturn,
+		},
+		{
+			name:         "unbalanced conditional",
+			scriptSig:    "51",
+			scriptPubKey: "63",
+			expectErr:    true,
+			code:         script.ErrUnbalancedConditional,
+		},
+		{
+			name:         "disabled opcode in unexecuted branch",
+			scriptSig:    "00",
+			scriptPubKey: "63" + "7e" + "68" + "51",
+			expectErr:    true,
+			code:         script.ErrDisabledOpcode,
+		},
+		{
+			name:         "bad opcode",
+			scriptSig:    "51",
+			scriptPubKey: "50",
+			expectErr:    true,
+			code:         script.ErrBadOpcode,
+		},
+		{
+			name:         "empty stack",
+			scriptSig:    "",
+			scriptPubKey: "75",
+			expectErr:    true,
+			code:         script.ErrInvalidStackOperation,
+		},
+		{
+			name:         "empty alt stack",
+			scriptSig:    "51",
+			scriptPubKey: "6c",
+			expectErr:    true,
+			code:         script.ErrInvalidAltStackOperation,
+		},
+		{
+			name:         "verify",
+			scriptSig:    "00",
+			scriptPubKey: "6951",
+			expectErr:    true,
+			code:         script.ErrVerify,
+		},
+		{
+			name:         "equal verify",
+			scriptSig:    "51",
+			scriptPubKey: "5288",
+			expectErr:    true,
+			code:         script.ErrEqualVerify,
+		},
+		{
+			name:         "non-minimal push",
+			scriptSig:    "0105",
+			scriptPubKey: "5587",
+			flags:        script.VerifyMinimalData,
+			expectErr:    true,
+			code:         script.ErrMinimalData,
+		},
+		{
+			name:         "non-minimal push allowed",
+			scriptSig:    "0105",
+			scriptPubKey: "5587",
+		},
+		{
+			name:         "number too big",
+			scriptSig:    "050000000001",
+			scriptPubKey: "8b",
+			expectErr:    true,
+			code:         script.ErrNumberTooBig,
+		},
+		{
+			name:         "sig push only",
+			scriptSig:    "5176",
+			scriptPubKey: "87",
+			flags:        script.VerifySigPushOnly,
+			expectErr:    true,
+			code:         script.ErrSigPushOnly,
+		},
+		{
+			name:         "clean stack",
+			scriptSig:    "5151",
+			scriptPubKey: "51",
+			flags:        script.VerifyCleanStack,
+			expectErr:    true,
+			code:         script.ErrCleanStack,
+		},
+		{
+			name:         "discouraged nop",
+			scriptSig:    "51",
+			scriptPubKey: "b0",
+			flags:        script.VerifyDiscourageUpgradableNops,
+			expectErr:    true,
+			code:         script.ErrDiscourageUpgradableNops,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := script.Evaluate(
+				parseRaw(t, test.scriptSig),
+				parseRaw(t, test.scriptPubKey), nil, nil, 0, 0,
+				test.flags,
+			)
+			if !test.expectErr {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.True(t, script.IsErrorCode(err, test.code), err)
+		})
+	}
+}
+
+const legacyTx = "0100000001813f79011acb80925dfe69b3def355fe914bd1d96a3f5f71bf8303c6a989c7d1000000006b483045022100ed81ff192e75a3fd2304004dcadb746fa5e24c5031ccfcf21320b0277457c98f02207a986d955c6e0cb35d446a89d3f56100f4d7f67801c31967743a9c8e10615bed01210349fc4e631e3624a545de3f89f5d8684c7b8138bd94bdd531d2e213bf016b278afeffffff02a135ef01000000001976a914bc3b654dca7e56b04dca18f2566cdaf02e8d9ada88ac99c39800000000001976a9141c4bc762dd5423e332166702cb75f40df79fea1288ac19430600"
+
+func TestEvaluateP2PKH(t *testing.T) {
+	transaction, err := tx.ParseString(legacyTx)
+	require.NoError(t, err)
+
+	spk := parseRaw(t, "76a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+
+	scriptSig, err := script.ParseRaw(transaction.TxIns[0].ScriptSig)
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.NoError(t, err)
+
+	// Changing the tx invalidates the signature.
+	transaction.TxOuts[0].Amount++
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0,
+		script.VerifyP2SH,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrEqualVerify) ||
+		script.IsErrorCode(err, script.ErrEvalFalse), err)
+
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSigNullFail), err)
+}
+
+const segwitTx = "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+
+func TestEvaluateP2WPKH(t *testing.T) {
+	transaction, err := tx.ParseString(segwitTx)
+	require.NoError(t, err)
+
+	spk := parseRaw(t, "00141d0f172a0ecb48aee1be1f2687d2963ae33f71a1")
+	in := transaction.TxIns[1]
+
+	scriptSig, err := script.ParseRaw(in.ScriptSig)
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		scriptSig, spk, in.Witness, transaction, 1, 600000000,
+		script.StandardVerifyFlags,
+	)
+	require.NoError(t, err)
+
+	// The amount is committed to by the signature.
+	err = script.Evaluate(
+		scriptSig, spk, in.Witness, transaction, 1, 600000001,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSigNullFail), err)
+
+	// Without the witness flag the program is anyone can spend.
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 1, 0, script.VerifyP2SH,
+	)
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		scriptSig, spk, in.Witness[:1], transaction, 1, 600000000,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrWitnessProgramMismatch), err)
+}
+
+func TestEvaluateP2SHAndP2WSH(t *testing.T) {
+	// OP_2 OP_3 OP_ADD OP_5 OP_EQUAL
+	redeem, err := hex.DecodeString("5253935587")
+	require.NoError(t, err)
+
+	p2sh := parseRaw(t, "a9"+push(helpers.Hash160(redeem))+"87")
+
+	err = script.Evaluate(
+		parseRaw(t, push(redeem)), p2sh, nil, nil, 0, 0,
+		script.VerifyP2SH|script.VerifyCleanStack|script.VerifyWitness,
+	)
+	require.NoError(t, err)
+
+	// A redeem script that evaluates to false.
+	badRedeem, err := hex.DecodeString("5253935487")
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		parseRaw(t, push(badRedeem)),
+		parseRaw(t, "a9"+push(helpers.Hash160(badRedeem))+"87"), nil,
+		nil, 0, 0, script.VerifyP2SH,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrEvalFalse), err)
+
+	// The same script as a P2WSH witness script.
+	p2wsh := parseRaw(t, "00"+push(helpers.Sha256(redeem)))
+
+	err = script.Evaluate(
+		script.Script{}, p2wsh, [][]byte{redeem}, nil, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		script.Script{}, p2wsh, [][]byte{badRedeem}, nil, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrWitnessProgramMismatch), err)
+
+	// Native witness programs must have an empty scriptSig.
+	err = script.Evaluate(
+		parseRaw(t, "00"), p2wsh, [][]byte{redeem}, nil, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrWitnessMalleated), err)
+
+	// Nested in P2SH.
+	p2wshBytes := append([]byte{0x00, 0x20}, helpers.Sha256(redeem)...)
+	p2shP2wsh := parseRaw(t, "a9"+push(helpers.Hash160(p2wshBytes))+"87")
+
+	err = script.Evaluate(
+		parseRaw(t, push(p2wshBytes)), p2shP2wsh, [][]byte{redeem}, nil,
+		0, 0, script.StandardVerifyFlags,
+	)
+	require.NoError(t, err)
+}
+
+func TestEvaluateMultisig(t *testing.T) {
+	priv1, err := privatekey.New(big.NewInt(1111))
+	require.NoError(t, err)
+
+	priv2, err := privatekey.New(big.NewInt(2222))
+	require.NoError(t, err)
+
+	// 1-of-2 bare multisig.
+	spk := parseRaw(t, "51"+push(priv1.PubKey.Sec(true))+
+		push(priv2.PubKey.Sec(true))+"52ae")
+
+	rawSpk, err := spk.SerializeRaw()
+	require.NoError(t, err)
+
+	transaction := &tx.Tx{
+		Version: 1,
+		TxIns: []*tx.TxIn{{
+			PrevTx:   make([]byte, 32),
+			Sequence: 0xffffffff,
+		}},
+		TxOuts: []*tx.TxOut{{
+			Amount:       1000,
+			ScriptPubKey: rawSpk,
+		}},
+	}
+
+	z, err := transaction.SigHash(0, spk, tx.SigHashAll)
+	require.NoError(t, err)
+
+	sig, err := priv2.Sign(z)
+	require.NoError(t, err)
+
+	scriptSig := parseRaw(t, "00"+push(append(sig.Der(), byte(tx.SigHashAll))))
+
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.NoError(t, err)
+
+	// A non-empty dummy element violates NULLDUMMY.
+	scriptSig = parseRaw(t, "51"+push(append(sig.Der(), byte(tx.SigHashAll))))
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSigNullDummy), err)
+
+	// Missing dummy element.
+	scriptSig = parseRaw(t, push(append(sig.Der(), byte(tx.SigHashAll))))
+	err = script.Evaluate(
+		scriptSig, spk, nil, transaction, 0, 0, script.VerifyNone,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrInvalidStackOperation), err)
+}
+
+func TestEvaluateLocktime(t *testing.T) {
+	transaction := &tx.Tx{
+		Version: 2,
+		TxIns: []*tx.TxIn{{
+			PrevTx:   make([]byte, 32),
+			Sequence: 10,
+		}},
+		Locktime: 500,
+	}
+
+	flags := script.VerifyCheckLockTimeVerify | script.VerifyCheckSequenceVerify
+
+	tests := []struct {
+		name         string
+		scriptPubKey string
+		code         script.ErrorCode
+		expectErr    bool
+	}{
+		{
+			name:         "cltv satisfied",
+			scriptPubKey: "02f401" + "b1",
+		},
+		{
+			name:         "cltv unsatisfied",
+			scriptPubKey: "02f501" + "b1",
+			expectErr:    true,
+			code:         script.ErrUnsatisfiedLocktime,
+		},
+		{
+			name:         "cltv type mismatch",
+			scriptPubKey: "040065cd1d" + "b1",
+			expectErr:    true,
+			code:         script.ErrUnsatisfiedLocktime,
+		},
+		{
+			name:         "cltv negative",
+			scriptPubKey: "4f" + "b1",
+			expectErr:    true,
+			code:         script.ErrNegativeLocktime,
+		},
+		{
+			name:         "csv satisfied",
+			scriptPubKey: "5a" + "b2",
+		},
+		{
+			name:         "csv unsatisfied",
+			scriptPubKey: "5b" + "b2",
+			expectErr:    true,
+			code:         script.ErrUnsatisfiedLocktime,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := script.Evaluate(
+				script.Script{}, parseRaw(t, test.scriptPubKey),
+				nil, transaction, 0, 0, flags,
+			)
+			if !test.expectErr {
+				require.NoError(t, err)
+				return
+			}
+
+			require.True(t, script.IsErrorCode(err, test.code), err)
+		})
+	}
+}
+
+// bip341Tx is the fully signed tx of the BIP341 key path spending test
+// vector. Its inputs spend bip341PrevOuts.
+const bip341Tx = "020000000001097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a41842000000006b4830450221008f3b8f8f0537c420654d2283673a761b7ee2ea3c130753103e08ce79201cf32a022079e7ab904a1980ef1c5890b648c8783f4d10103dd62f740d13daa79e298d50c201210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0141ed7c1647cb97379e76892be0cacff57ec4a7102aa24296ca39af7541246d8ff14d38958d4cc1e2e478e4d4a764bbfd835b16d4e314b72937b29833060b87276c030141052aedffc554b41f52b521071793a6b88d6dbca9dba94cf34c83696de0c1ec35ca9c5ed4ab28059bd606a4f3a657eec0bb96661d42921b5f50a95ad33675b54f83000141ff45f742a876139946a149ab4d9185574b98dc919d2eb6754f8abaa59d18b025637a3aa043b91817739554f4ed2026cf8022dbd83e351ce1fabc272841d2510a010140b4010dd48a617db09926f729e79c33ae0b4e94b79f04a1ae93ede6315eb3669de185a17d2b0ac9ee09fd4c64b678a0b61a0a86fa888a273c8511be83bfd6810f0247304402202b795e4de72646d76eab3f0ab27dfa30b810e856ff3a46c9a702df53bb0d8cc302203ccc4d822edab5f35caddb10af1be93583526ccfbade4b4ead350781e2f8adcd012102f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f90141a3785919a2ce3c4ce26f298c3d51619bc474ae24014bcdd31328cd8cfbab2eff3395fa0a16fe5f486d12f22a9cedded5ae74feb4bbe5351346508c5405bcfee0020141ea0c6ba90763c2d3a296ad82ba45881abb4f426b3f87af162dd24d5109edc1cdd11915095ba47c3a9963dc1e6c432939872bc49212fe34c632cd3ab9fed429c4820141bbc9584a11074e83bc8c6759ec55401f0ae7b03ef290c3139814f545b58a9f8127258000874f44bc46db7646322107d4d86aec8e73b8719a61fff761d75b5dd9810065cd1d"
+
+var bip341PrevOuts = []struct {
+	scriptPubKey string
+	amount       uint64
+}{
+	{"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", 420000000},
+	{"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", 462000000},
+	{"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac", 294000000},
+	{"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e", 504000000},
+	{"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605", 630000000},
+	{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc", 378000000},
+	{"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831", 672000000},
+	{"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5", 546000000},
+	{"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220", 588000000},
+}
+
+// bip341Spend parses the BIP341 test tx and returns it together with its
+// spend context.
+func bip341Spend(t *testing.T) (*tx.Tx, *tx.SpendContext) {
+	transaction, err := tx.ParseString(bip341Tx)
+	require.NoError(t, err)
+
+	prevOuts := make([]*tx.TxOut, len(bip341PrevOuts))
+	for i, p := range bip341PrevOuts {
+		spk, err := hex.DecodeString(p.scriptPubKey)
+		require.NoError(t, err)
+
+		prevOuts[i] = &tx.TxOut{Amount: p.amount, ScriptPubKey: spk}
+	}
+
+	ctx, err := tx.NewSpendContext(transaction, prevOuts)
+	require.NoError(t, err)
+
+	return transaction, ctx
+}
+
+func TestEvaluateTaprootKeyPath(t *testing.T) {
+	transaction, ctx := bip341Spend(t)
+
+	// The tx also spends a P2PKH and a P2WPKH output, which the context
+	// must sign in the same way as the tx itself.
+	for i, in := range transaction.TxIns {
+		scriptSig, err := script.ParseRaw(in.ScriptSig)
+		require.NoError(t, err)
+
+		err = script.Evaluate(
+			scriptSig, parseRaw(t, bip341PrevOuts[i].scriptPubKey),
+			in.Witness, ctx, i, bip341PrevOuts[i].amount,
+			script.StandardVerifyFlags,
+		)
+		require.NoError(t, err, "input %d", i)
+	}
+
+	in := transaction.TxIns[0]
+	spk := parseRaw(t, bip341PrevOuts[0].scriptPubKey)
+	amount := bip341PrevOuts[0].amount
+
+	// The signature hashes need the outputs spent by every input.
+	err := script.Evaluate(
+		script.Script{}, spk, in.Witness, transaction, 0, amount,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSigHash), err)
+
+	// A signature of another input.
+	witness := [][]byte{transaction.TxIns[1].Witness[0]}
+	err = script.Evaluate(
+		script.Script{}, spk, witness, ctx, 0, amount,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSchnorrSig), err)
+
+	// An explicit SIGHASH_DEFAULT must be left out instead.
+	witness = [][]byte{append(make([]byte, 64), 0x00)}
+	err = script.Evaluate(
+		script.Script{}, spk, witness, ctx, 0, amount,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSchnorrSigHashType), err)
+
+	witness = [][]byte{make([]byte, 63)}
+	err = script.Evaluate(
+		script.Script{}, spk, witness, ctx, 0, amount,
+		script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrSchnorrSigSize), err)
+
+	// Without the taproot flag the program is anyone can spend.
+	err = script.Evaluate(
+		script.Script{}, spk, witness, ctx, 0, amount,
+		script.StandardVerifyFlags&^script.VerifyTaproot,
+	)
+	require.NoError(t, err)
+}
+
+func TestEvaluateTaprootUnknownLeafVersion(t *testing.T) {
+	_, ctx := bip341Spend(t)
+
+	// The leaf with version 0xfa of the BIP341 script tree that is
+	// committed to by the output spent by input 7.
+	leafScript, err := hex.DecodeString("06424950333431")
+	require.NoError(t, err)
+
+	control, err := hex.DecodeString("faee4fe085983462a184015d1f782d6a5f8b9c2b60130aff050ce221ecf37865928ad69ec7cf41c2a4001fd1f738bf1e505ce2277acdcaa63fe4765192497f47a7")
+	require.NoError(t, err)
+
+	spk := parseRaw(t, bip341PrevOuts[7].scriptPubKey)
+	amount := bip341PrevOuts[7].amount
+	flags := script.StandardVerifyFlags &^
+		script.VerifyDiscourageUpgradableTaprootVersion
+
+	err = script.Evaluate(
+		script.Script{}, spk, [][]byte{leafScript, control}, ctx, 7,
+		amount, flags,
+	)
+	require.NoError(t, err)
+
+	err = script.Evaluate(
+		script.Script{}, spk, [][]byte{leafScript, control}, ctx, 7,
+		amount, script.StandardVerifyFlags,
+	)
+	require.True(t, script.IsErrorCode(
+		err, script.ErrDiscourageUpgradableTaprootVersion,
+	), err)
+
+	err = script.Evaluate(
+		script.Script{}, spk, [][]byte{leafScript, control[:64]}, ctx,
+		7, amount, flags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrTaprootWrongControlSize), err)
+
+	// The control block of another output.
+	err = script.Evaluate(
+		script.Script{}, parseRaw(t, bip341PrevOuts[6].scriptPubKey),
+		[][]byte{leafScript, control}, ctx, 6,
+		bip341PrevOuts[6].amount, flags,
+	)
+	require.True(t, script.IsErrorCode(err, script.ErrWitnessProgramMismatch), err)
+}
+
+func TestEvaluateTapscript(t *testing.T) {
+	internal, err := privatekey.New(big.NewInt(3333))
+	require.NoError(t, err)
+
+	priv1, err := privatekey.New(big.NewInt(1111))
+	require.NoError(t, err)
+
+	priv2, err := privatekey.New(big.NewInt(2222))
+	require.NoError(t, err)
+
+	pk1 := push(priv1.PubKey.XOnly())
+	pk2 := push(priv2.PubKey.XOnly())
+
+	// sig describes a signature in the witness. An unset key is an empty
+	// signature.
+	type sig struct {
+		key        *privatekey.PrivateKey
+		hashType   uint32
+		codeSepPos uint32
+	}
+
+	tests := []struct {
+		name      string
+		script    string
+		sigs      []sig
+		witness   [][]byte
+		annex     []byte
+		flags     script.Flags
+		expectErr bool
+		code      script.ErrorCode
+	}{
+		{
+			name:   "checksig",
+			script: pk1 + "ac",
+			sigs:   []sig{{key: priv1, codeSepPos: 0xffffffff}},
+		},
+		{
+			name:   "checksig explicit hash type",
+			script: pk1 + "ac",
+			sigs: []sig{{
+				key:        priv1,
+				hashType:   tx.SigHashAll,
+				codeSepPos: 0xffffffff,
+			}},
+		},
+		{
+			name:      "checksig wrong key",
+			script:    pk1 + "ac",
+			sigs:      []sig{{key: priv2, codeSepPos: 0xffffffff}},
+			expectErr: true,
+			code:      script.ErrSchnorrSig,
+		},
+		{
+			name:      "checksig empty signature",
+			script:    pk1 + "ac",
+			sigs:      []sig{{}},
+			expectErr: true,
+			code:      script.ErrEvalFalse,
+		},
+		{
+			name:   "checksig with annex",
+			script: pk1 + "ac",
+			sigs:   []sig{{key: priv1, codeSepPos: 0xffffffff}},
+			annex:  []byte{0x50, 0x01},
+		},
+		{
+			name:   "checksig after codeseparator",
+			script: "ab" + pk1 + "ac",
+			sigs:   []sig{{key: priv1, codeSepPos: 0}},
+		},
+		{
+			name:      "codeseparator not committed to",
+			script:    "ab" + pk1 + "ac",
+			sigs:      []sig{{key: priv1, codeSepPos: 0xffffffff}},
+			expectErr: true,
+			code:      script.ErrSchnorrSig,
+		},
+		{
+			name:   "checksigadd 2-of-2",
+			script: pk1 + "ac" + pk2 + "ba" + "52" + "87",
+			sigs: []sig{
+				{key: priv2, codeSepPos: 0xffffffff},
+				{key: priv1, codeSepPos: 0xffffffff},
+			},
+		},
+		{
+			name:   "checksigadd 1-of-2",
+			script: pk1 + "ac" + pk2 + "ba" + "51" + "87",
+			sigs: []sig{
+				{key: priv2, codeSepPos: 0xffffffff},
+				{},
+			},
+		},
+		{
+			name:      "checkmultisig disabled",
+			script:    "00" + "51" + pk1 + "51" + "ae",
+			sigs:      []sig{{key: priv1, codeSepPos: 0xffffffff}},
+			expectErr: true,
+			code:      script.ErrTapscriptCheckMultiSig,
+		},
+		{
+			name:   "unknown public key type",
+			script: "020101" + "ac",
+			sigs:   []sig{{key: priv1, codeSepPos: 0xffffffff}},
+			flags: script.StandardVerifyFlags &^
+				script.VerifyDiscourageUpgradablePubKeyType,
+		},
+		{
+			name:      "unknown public key type discouraged",
+			script:    "020101" + "ac",
+			sigs:      []sig{{key: priv1, codeSepPos: 0xffffffff}},
+			expectErr: true,
+			code:      script.ErrDiscourageUpgradablePubKeyType,
+		},
+		{
+			name:   "op_success",
+			script: "6a" + "50",
+			flags: script.StandardVerifyFlags &^
+				script.VerifyDiscourageOpSuccess,
+		},
+		{
+			name:      "op_success discouraged",
+			script:    "6a" + "50",
+			expectErr: true,
+			code:      script.ErrDiscourageOpSuccess,
+		},
+		{
+			name:      "minimal if",
+			script:    "63" + "51" + "67" + "00" + "68",
+			witness:   [][]byte{{0x02}},
+			expectErr: true,
+			code:      script.ErrMinimalIf,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			leaf := taproot.NewBaseLeaf(parseRaw(t, test.script))
+			other := taproot.NewBaseLeaf(parseRaw(t, "51"))
+
+			tree, err := taproot.AssembleTree(leaf, other)
+			require.NoError(t, err)
+
+			outputKey, err := taproot.ComputeOutputKey(
+				internal.PubKey, tree.RootHash(),
+			)
+			require.NoError(t, err)
+
+			spk := parseRaw(t, "51"+push(outputKey.XOnly()))
+			rawSpk, err := spk.SerializeRaw()
+			require.NoError(t, err)
+
+			transaction := &tx.Tx{
+				Version: 2,
+				TxIns: []*tx.TxIn{{
+					PrevTx:   make([]byte, 32),
+					Sequence: 0xffffffff,
+				}},
+				TxOuts: []*tx.TxOut{{
+					Amount:       900,
+					ScriptPubKey: rawSpk,
+				}},
+			}
+
+			ctx, err := tx.NewSpendContext(transaction, []*tx.TxOut{{
+				Amount:       1000,
+				ScriptPubKey: rawSpk,
+			}})
+			require.NoError(t, err)
+
+			leafHash, err := leaf.Hash()
+			require.NoError(t, err)
+
+			witness := test.witness
+			for _, s := range test.sigs {
+				if s.key == nil {
+					witness = append(witness, nil)
+					continue
+				}
+
+				z, err := ctx.TapscriptSigHash(
+					0, s.hashType, test.annex, leafHash,
+					s.codeSepPos,
+				)
+				require.NoError(t, err)
+
+				sig, err := s.key.SignSchnorr(z, make([]byte, 32))
+				require.NoError(t, err)
+
+				b := sig.Serialize()
+				if s.hashType != tx.SigHashDefault {
+					b = append(b, byte(s.hashType))
+				}
+				witness = append(witness, b)
+			}
+
+			leafScript, err := leaf.Script.SerializeRaw()
+			require.NoError(t, err)
+
+			control, err := tree.ControlBlock(internal.PubKey, 0)
+			require.NoError(t, err)
+
+			witness = append(witness, leafScript, control.Serialize())
+			if test.annex != nil {
+				witness = append(witness, test.annex)
+			}
+
+			flags := test.flags
+			if flags == 0 {
+				flags = script.StandardVerifyFlags
+			}
+
+			err = script.Evaluate(
+				script.Script{}, spk, witness, ctx, 0, 1000, flags,
+			)
+			if !test.expectErr {
+				require.NoError(t, err)
+				return
+			}
+
+			require.True(t, script.IsErrorCode(err, test.code), err)
+		})
+	}
+}
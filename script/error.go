@@ -0,0 +1,185 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode identifies the reason that script evaluation failed.
+type ErrorCode int
+
+const (
+	ErrUnknown ErrorCode = iota
+	ErrEvalFalse
+	ErrOpReturn
+
+	// Limits.
+	ErrScriptSize
+	ErrPushSize
+	ErrOpCount
+	ErrStackSize
+	ErrSigCount
+	ErrPubKeyCount
+
+	// Failed verify operations.
+	ErrVerify
+	ErrEqualVerify
+	ErrCheckMultiSigVerify
+	ErrCheckSigVerify
+	ErrNumEqualVerify
+
+	// Logical and encoding errors.
+	ErrBadOpcode
+	ErrDisabledOpcode
+	ErrInvalidStackOperation
+	ErrInvalidAltStackOperation
+	ErrUnbalancedConditional
+	ErrMalformedPush
+	ErrMalformedScript
+	ErrNumberTooBig
+
+	// Locktime.
+	ErrNegativeLocktime
+	ErrUnsatisfiedLocktime
+
+	// Malleability and policy.
+	ErrSigHashType
+	ErrSigDER
+	ErrMinimalData
+	ErrSigPushOnly
+	ErrSigHighS
+	ErrSigNullDummy
+	ErrPubKeyType
+	ErrCleanStack
+	ErrMinimalIf
+	ErrSigNullFail
+	ErrDiscourageUpgradableNops
+	ErrDiscourageUpgradableWitnessProgram
+	ErrWitnessPubKeyType
+
+	// Segregated witness.
+	ErrWitnessProgramWrongLength
+	ErrWitnessProgramWitnessEmpty
+	ErrWitnessProgramMismatch
+	ErrWitnessMalleated
+	ErrWitnessMalleatedP2SH
+	ErrWitnessUnexpected
+
+	// Signature checking.
+	ErrSigHash
+
+	// Taproot.
+	ErrTaprootWrongControlSize
+	ErrSchnorrSigSize
+	ErrSchnorrSigHashType
+	ErrSchnorrSig
+	ErrTapscriptValidationWeight
+	ErrTapscriptCheckMultiSig
+	ErrDiscourageUpgradableTaprootVersion
+	ErrDiscourageOpSuccess
+	ErrDiscourageUpgradablePubKeyType
+)
+
+var errorCodeStrings = map[ErrorCode]string{
+	ErrUnknown:                            "ErrUnknown",
+	ErrEvalFalse:                          "ErrEvalFalse",
+	ErrOpReturn:                           "ErrOpReturn",
+	ErrScriptSize:                         "ErrScriptSize",
+	ErrPushSize:                           "ErrPushSize",
+	ErrOpCount:                            "ErrOpCount",
+	ErrStackSize:                          "ErrStackSize",
+	ErrSigCount:                           "ErrSigCount",
+	ErrPubKeyCount:                        "ErrPubKeyCount",
+	ErrVerify:                             "ErrVerify",
+	ErrEqualVerify:                        "ErrEqualVerify",
+	ErrCheckMultiSigVerify:                "ErrCheckMultiSigVerify",
+	ErrCheckSigVerify:                     "ErrCheckSigVerify",
+	ErrNumEqualVerify:                     "ErrNumEqualVerify",
+	ErrBadOpcode:                          "ErrBadOpcode",
+	ErrDisabledOpcode:                     "ErrDisabledOpcode",
+	ErrInvalidStackOperation:              "ErrInvalidStackOperation",
+	ErrInvalidAltStackOperation:           "ErrInvalidAltStackOperation",
+	ErrUnbalancedConditional:              "ErrUnbalancedConditional",
+	ErrMalformedPush:                      "ErrMalformedPush",
+	ErrMalformedScript:                    "ErrMalformedScript",
+	ErrNumberTooBig:                       "ErrNumberTooBig",
+	ErrNegativeLocktime:                   "ErrNegativeLocktime",
+	ErrUnsatisfiedLocktime:                "ErrUnsatisfiedLocktime",
+	ErrSigHashType:                        "ErrSigHashType",
+	ErrSigDER:                             "ErrSigDER",
+	ErrMinimalData:                        "ErrMinimalData",
+	ErrSigPushOnly:                        "ErrSigPushOnly",
+	ErrSigHighS:                           "ErrSigHighS",
+	ErrSigNullDummy:                       "ErrSigNullDummy",
+	ErrPubKeyType:                         "ErrPubKeyType",
+	ErrCleanStack:                         "ErrCleanStack",
+	ErrMinimalIf:                          "ErrMinimalIf",
+	ErrSigNullFail:                        "ErrSigNullFail",
+	ErrDiscourageUpgradableNops:           "ErrDiscourageUpgradableNops",
+	ErrDiscourageUpgradableWitnessProgram: "ErrDiscourageUpgradableWitnessProgram",
+	ErrWitnessPubKeyType:                  "ErrWitnessPubKeyType",
+	ErrWitnessProgramWrongLength:          "ErrWitnessProgramWrongLength",
+	ErrWitnessProgramWitnessEmpty:         "ErrWitnessProgramWitnessEmpty",
+	ErrWitnessProgramMismatch:             "ErrWitnessProgramMismatch",
+	ErrWitnessMalleated:                   "ErrWitnessMalleated",
+	ErrWitnessMalleatedP2SH:               "ErrWitnessMalleatedP2SH",
+	ErrWitnessUnexpected:                  "ErrWitnessUnexpected",
+	ErrSigHash:                            "ErrSigHash",
+	ErrTaprootWrongControlSize:            "ErrTaprootWrongControlSize",
+	ErrSchnorrSigSize:                     "ErrSchnorrSigSize",
+	ErrSchnorrSigHashType:                 "ErrSchnorrSigHashType",
+	ErrSchnorrSig:                         "ErrSchnorrSig",
+	ErrTapscriptValidationWeight:          "ErrTapscriptValidationWeight",
+	ErrTapscriptCheckMultiSig:             "ErrTapscriptCheckMultiSig",
+	ErrDiscourageUpgradableTaprootVersion: "ErrDiscourageUpgradableTaprootVersion",
+	ErrDiscourageOpSuccess:                "ErrDiscourageOpSuccess",
+	ErrDiscourageUpgradablePubKeyType:     "ErrDiscourageUpgradablePubKeyType",
+}
+
+func (e ErrorCode) String() string {
+	if s, ok := errorCodeStrings[e]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown ErrorCode (%d)", int(e))
+}
+
+// Error is returned when script evaluation fails. Code identifies the kind
+// of failure and Description gives human readable detail.
+type Error struct {
+	Code        ErrorCode
+	Description string
+}
+
+func (e Error) Error() string {
+	return e.Description
+}
+
+func scriptError(c ErrorCode, desc string) Error {
+	return Error{Code: c, Description: desc}
+}
+
+// ParseError is returned when a serialized script can't be decoded. Offset is
+// the position in the input of the element that is malformed.
+type ParseError struct {
+	Offset int
+	Err    Error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("malformed script at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// IsErrorCode returns true if err is a script Error with the given code.
+func IsErrorCode(err error, c ErrorCode) bool {
+	var e Error
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	return e.Code == c
+}
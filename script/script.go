@@ -1,95 +1,122 @@
 package script
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 
 	"github.com/ellemouton/btc/varint"
 )
 
-type Script []elem
-
-type elem struct {
-	value byte
-	data  []byte
-}
-
-func read(b []byte, n int) ([]byte, []byte) {
-	res := b[:n]
-	b = b[n:]
-	return res, b
-}
-
-func Parse(b []byte) Script {
-	length, b := read(b, 1)
-	script := *new(Script)
-	count := 0
-	var (
-		current []byte
-		data    []byte
-	)
-
-	for count < int(length[0]) {
-		current, b = read(b, 1)
-		count += 1
-		if current[0] >= 0x01 && current[0] <= 0x4b {
-			n := current[0]
-			data, b = read(b, int(n))
-			script = append(script, elem{
-				value: n,
-				data:  data,
-			})
-			count += int(n)
-		} else if toOpcode(current[0]) == OP_PUSHDATA1 {
-			data, b = read(b, 1)
-			dataLen := binary.LittleEndian.Uint16(data)
-			data, b = read(b, int(dataLen))
-			script = append(script, elem{
-				value: current[0],
-				data:  data,
-			})
-			count += int(dataLen) + 1
-		} else if toOpcode(current[0]) == OP_PUSHDATA2 {
-			data, b = read(b, 2)
-			dataLen := binary.LittleEndian.Uint16(data)
-			data, b = read(b, int(dataLen))
-			script = append(script, elem{
-				value: current[0],
-				data:  data,
-			})
-			count += int(dataLen) + 2
-		} else if toOpcode(current[0]) == OP_PUSHDATA4 {
-			data, b = read(b, 4)
-			dataLen := binary.LittleEndian.Uint16(data)
-			data, b = read(b, int(dataLen))
-			script = append(script, elem{
-				value: current[0],
-				data:  data,
-			})
-			count += int(dataLen) + 4
-		} else {
-			script = append(script, elem{
-				value: current[0],
-			})
-		}
+// Script is a parsed bitcoin script made up of a sequence of elements.
+type Script []Elem
+
+// Elem is a single script element. Data is only set for push opcodes and
+// holds the bytes that the opcode pushes onto the stack.
+type Elem struct {
+	Opcode Opcode
+	Data   []byte
+}
+
+// Parse parses a script that is prefixed with its length as a varint, which
+// is how scripts are encoded in transactions. The length must match the
+// number of bytes that follow it.
+func Parse(b []byte) (Script, error) {
+	r := bytes.NewReader(b)
+	length, err := varint.ReadFrom(r)
+	if err != nil {
+		return nil, &ParseError{Offset: 0, Err: scriptError(
+			ErrMalformedScript, "truncated script length prefix")}
 	}
 
-	return script
+	prefixLen := len(b) - r.Len()
+	if uint64(r.Len()) != length {
+		return nil, &ParseError{Offset: 0, Err: scriptError(
+			ErrMalformedScript, fmt.Sprintf("script length prefix of %d "+
+				"does not match the %d bytes that follow it", length,
+				r.Len()))}
+	}
+
+	return parse(b[prefixLen:], prefixLen)
 }
 
-func (s Script) Serialize() ([]byte, error) {
-	b := *new([]byte)
+// ParseRaw parses a script that is not prefixed with its length, such as a
+// P2SH redeem script or a P2WSH witness script.
+func ParseRaw(b []byte) (Script, error) {
+	return parse(b, 0)
+}
 
-	for _, e := range s {
-		if toOpcode(e.value) == OP_PUSHDATA1 {
-			//TODO
-		} else if toOpcode(e.value) == OP_PUSHDATA2 {
-			//TODO
-		} else if toOpcode(e.value) == OP_PUSHDATA4 {
-			//TODO
-		} else {
-			b = append(b, e.value)
-			b = append(b, e.data...)
+// parse decodes the script in b. The base is added to the offsets reported in
+// errors so that they are relative to the start of the caller's input. Every
+// push keeps the opcode it was encoded with so that non-minimal pushes
+// serialize back to the same bytes.
+func parse(b []byte, base int) (Script, error) {
+	script := Script{}
+
+	for i := 0; i < len(b); {
+		start := i
+		op := Opcode(b[i])
+		i++
+
+		var n int
+		switch {
+		case op >= OP_DATA_1 && op <= OP_DATA_75:
+			n = int(op)
+
+		case op == OP_PUSHDATA1:
+			if len(b)-i < 1 {
+				return nil, malformedPush(op, base+start)
+			}
+			n = int(b[i])
+			i++
+
+		case op == OP_PUSHDATA2:
+			if len(b)-i < 2 {
+				return nil, malformedPush(op, base+start)
+			}
+			n = int(binary.LittleEndian.Uint16(b[i : i+2]))
+			i += 2
+
+		case op == OP_PUSHDATA4:
+			if len(b)-i < 4 {
+				return nil, malformedPush(op, base+start)
+			}
+			n64 := uint64(binary.LittleEndian.Uint32(b[i : i+4]))
+			i += 4
+
+			if n64 > uint64(len(b)-i) {
+				return nil, malformedPush(op, base+start)
+			}
+			n = int(n64)
+
+		default:
+			script = append(script, Elem{Opcode: op})
+			continue
+		}
+
+		if len(b)-i < n {
+			return nil, malformedPush(op, base+start)
 		}
+
+		data := make([]byte, n)
+		copy(data, b[i:i+n])
+		script = append(script, Elem{Opcode: op, Data: data})
+		i += n
+	}
+
+	return script, nil
+}
+
+func malformedPush(op Opcode, offset int) error {
+	return &ParseError{Offset: offset, Err: scriptError(ErrMalformedPush,
+		fmt.Sprintf("%v exceeds the script length", op))}
+}
+
+// Serialize returns the script prefixed with its length as a varint.
+func (s Script) Serialize() ([]byte, error) {
+	b, err := s.SerializeRaw()
+	if err != nil {
+		return nil, err
 	}
 
 	length, err := varint.Encode(uint64(len(b)))
@@ -100,65 +127,561 @@ func (s Script) Serialize() ([]byte, error) {
 	return append(length, b...), nil
 }
 
-type opcode byte
+// SerializeRaw returns the script without a length prefix. Each push is
+// encoded with the opcode held in its element, so a parsed script serializes
+// back to exactly the bytes it was parsed from. An error is returned if an
+// element's data can't be encoded by its opcode.
+func (s Script) SerializeRaw() ([]byte, error) {
+	b := make([]byte, 0, s.rawLen())
+
+	for i, e := range s {
+		var (
+			max    uint64
+			length []byte
+		)
+		switch {
+		case e.Opcode >= OP_DATA_1 && e.Opcode <= OP_DATA_75:
+			if len(e.Data) != int(e.Opcode) {
+				return nil, scriptError(ErrMalformedPush, fmt.Sprintf(
+					"element %d: %v can't push %d bytes", i,
+					e.Opcode, len(e.Data)))
+			}
+
+		case e.Opcode == OP_PUSHDATA1:
+			max = 0xff
+			length = []byte{byte(len(e.Data))}
+
+		case e.Opcode == OP_PUSHDATA2:
+			max = 0xffff
+			length = make([]byte, 2)
+			binary.LittleEndian.PutUint16(length, uint16(len(e.Data)))
+
+		case e.Opcode == OP_PUSHDATA4:
+			max = 0xffffffff
+			length = make([]byte, 4)
+			binary.LittleEndian.PutUint32(length, uint32(len(e.Data)))
+
+		case len(e.Data) != 0:
+			return nil, scriptError(ErrMalformedPush, fmt.Sprintf(
+				"element %d: %v can't push data", i, e.Opcode))
+		}
+
+		if length != nil && uint64(len(e.Data)) > max {
+			return nil, scriptError(ErrMalformedPush, fmt.Sprintf(
+				"element %d: %v can't push %d bytes", i, e.Opcode,
+				len(e.Data)))
+		}
+
+		b = append(b, byte(e.Opcode))
+		b = append(b, length...)
+		b = append(b, e.Data...)
+	}
+
+	return b, nil
+}
+
+// RemoveCodeSeparators returns a copy of the script with all
+// OP_CODESEPARATOR opcodes removed.
+func (s Script) RemoveCodeSeparators() Script {
+	res := make(Script, 0, len(s))
+	for _, e := range s {
+		if e.Opcode == OP_CODESEPARATOR && e.Data == nil {
+			continue
+		}
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// FindAndDelete returns a copy of the script with every push of exactly the
+// given data removed. This mirrors the legacy behaviour of removing a
+// signature from the script code before computing its signature hash.
+func (s Script) FindAndDelete(data []byte) Script {
+	target := pushElem(data)
 
-func toOpcode(b byte) opcode {
-	return opcode(b)
+	res := make(Script, 0, len(s))
+	for _, e := range s {
+		if e.Opcode == target.Opcode && bytes.Equal(e.Data, target.Data) {
+			continue
+		}
+		res = append(res, e)
+	}
+
+	return res
+}
+
+// PushData returns the element that pushes data onto the stack using the
+// smallest possible push opcode. Note that this pushes empty data with OP_0
+// but doesn't turn single byte numbers into OP_1 to OP_16.
+func PushData(data []byte) Elem {
+	return pushElem(data)
+}
+
+// pushElem returns the element that pushes data onto the stack using the
+// smallest possible push opcode.
+func pushElem(data []byte) Elem {
+	switch {
+	case len(data) <= 0x4b:
+		return Elem{Opcode: Opcode(len(data)), Data: data}
+	case len(data) <= 0xff:
+		return Elem{Opcode: OP_PUSHDATA1, Data: data}
+	case len(data) <= 0xffff:
+		return Elem{Opcode: OP_PUSHDATA2, Data: data}
+	default:
+		return Elem{Opcode: OP_PUSHDATA4, Data: data}
+	}
 }
 
+// Opcode is a single script operation. Opcodes 0x01 to 0x4b push that many
+// bytes of data onto the stack.
+type Opcode byte
+
 const (
-	OP_0         opcode = 0x00
-	OP_FALSE     opcode = OP_0
-	OP_PUSHDATA1 opcode = 0x4c
-	OP_PUSHDATA2 opcode = 0x4d
-	OP_PUSHDATA4 opcode = 0x4e
-	OP_1NEGATE   opcode = 0x4f
-	OP_RESERVED  opcode = 0x50
-	OP_1         opcode = 0x51
-	OP_TRUE      opcode = 0x51
-	OP_2         opcode = 0x52
-	OP_3         opcode = 0x53
-	OP_4         opcode = 0x54
-	OP_5         opcode = 0x55
-	OP_6         opcode = 0x56
-	OP_7         opcode = 0x57
-	OP_8         opcode = 0x58
-	OP_9         opcode = 0x59
-	OP_10        opcode = 0x5a
-	OP_11        opcode = 0x5b
-	OP_12        opcode = 0x5c
-	OP_13        opcode = 0x5d
-	OP_14        opcode = 0x5e
-	OP_15        opcode = 0x5f
-	OP_16        opcode = 0x60
-	OP_NOP       opcode = 0x61
-	OP_VER       opcode = 0x62
-	OP_IF        opcode = 0x63
+	OP_0                   Opcode = 0x00
+	OP_FALSE               Opcode = OP_0
+	OP_DATA_1              Opcode = 0x01
+	OP_DATA_2              Opcode = 0x02
+	OP_DATA_3              Opcode = 0x03
+	OP_DATA_4              Opcode = 0x04
+	OP_DATA_5              Opcode = 0x05
+	OP_DATA_6              Opcode = 0x06
+	OP_DATA_7              Opcode = 0x07
+	OP_DATA_8              Opcode = 0x08
+	OP_DATA_9              Opcode = 0x09
+	OP_DATA_10             Opcode = 0x0a
+	OP_DATA_11             Opcode = 0x0b
+	OP_DATA_12             Opcode = 0x0c
+	OP_DATA_13             Opcode = 0x0d
+	OP_DATA_14             Opcode = 0x0e
+	OP_DATA_15             Opcode = 0x0f
+	OP_DATA_16             Opcode = 0x10
+	OP_DATA_17             Opcode = 0x11
+	OP_DATA_18             Opcode = 0x12
+	OP_DATA_19             Opcode = 0x13
+	OP_DATA_20             Opcode = 0x14
+	OP_DATA_21             Opcode = 0x15
+	OP_DATA_22             Opcode = 0x16
+	OP_DATA_23             Opcode = 0x17
+	OP_DATA_24             Opcode = 0x18
+	OP_DATA_25             Opcode = 0x19
+	OP_DATA_26             Opcode = 0x1a
+	OP_DATA_27             Opcode = 0x1b
+	OP_DATA_28             Opcode = 0x1c
+	OP_DATA_29             Opcode = 0x1d
+	OP_DATA_30             Opcode = 0x1e
+	OP_DATA_31             Opcode = 0x1f
+	OP_DATA_32             Opcode = 0x20
+	OP_DATA_33             Opcode = 0x21
+	OP_DATA_34             Opcode = 0x22
+	OP_DATA_35             Opcode = 0x23
+	OP_DATA_36             Opcode = 0x24
+	OP_DATA_37             Opcode = 0x25
+	OP_DATA_38             Opcode = 0x26
+	OP_DATA_39             Opcode = 0x27
+	OP_DATA_40             Opcode = 0x28
+	OP_DATA_41             Opcode = 0x29
+	OP_DATA_42             Opcode = 0x2a
+	OP_DATA_43             Opcode = 0x2b
+	OP_DATA_44             Opcode = 0x2c
+	OP_DATA_45             Opcode = 0x2d
+	OP_DATA_46             Opcode = 0x2e
+	OP_DATA_47             Opcode = 0x2f
+	OP_DATA_48             Opcode = 0x30
+	OP_DATA_49             Opcode = 0x31
+	OP_DATA_50             Opcode = 0x32
+	OP_DATA_51             Opcode = 0x33
+	OP_DATA_52             Opcode = 0x34
+	OP_DATA_53             Opcode = 0x35
+	OP_DATA_54             Opcode = 0x36
+	OP_DATA_55             Opcode = 0x37
+	OP_DATA_56             Opcode = 0x38
+	OP_DATA_57             Opcode = 0x39
+	OP_DATA_58             Opcode = 0x3a
+	OP_DATA_59             Opcode = 0x3b
+	OP_DATA_60             Opcode = 0x3c
+	OP_DATA_61             Opcode = 0x3d
+	OP_DATA_62             Opcode = 0x3e
+	OP_DATA_63             Opcode = 0x3f
+	OP_DATA_64             Opcode = 0x40
+	OP_DATA_65             Opcode = 0x41
+	OP_DATA_66             Opcode = 0x42
+	OP_DATA_67             Opcode = 0x43
+	OP_DATA_68             Opcode = 0x44
+	OP_DATA_69             Opcode = 0x45
+	OP_DATA_70             Opcode = 0x46
+	OP_DATA_71             Opcode = 0x47
+	OP_DATA_72             Opcode = 0x48
+	OP_DATA_73             Opcode = 0x49
+	OP_DATA_74             Opcode = 0x4a
+	OP_DATA_75             Opcode = 0x4b
+	OP_PUSHDATA1           Opcode = 0x4c
+	OP_PUSHDATA2           Opcode = 0x4d
+	OP_PUSHDATA4           Opcode = 0x4e
+	OP_1NEGATE             Opcode = 0x4f
+	OP_RESERVED            Opcode = 0x50
+	OP_1                   Opcode = 0x51
+	OP_TRUE                Opcode = OP_1
+	OP_2                   Opcode = 0x52
+	OP_3                   Opcode = 0x53
+	OP_4                   Opcode = 0x54
+	OP_5                   Opcode = 0x55
+	OP_6                   Opcode = 0x56
+	OP_7                   Opcode = 0x57
+	OP_8                   Opcode = 0x58
+	OP_9                   Opcode = 0x59
+	OP_10                  Opcode = 0x5a
+	OP_11                  Opcode = 0x5b
+	OP_12                  Opcode = 0x5c
+	OP_13                  Opcode = 0x5d
+	OP_14                  Opcode = 0x5e
+	OP_15                  Opcode = 0x5f
+	OP_16                  Opcode = 0x60
+	OP_NOP                 Opcode = 0x61
+	OP_VER                 Opcode = 0x62
+	OP_IF                  Opcode = 0x63
+	OP_NOTIF               Opcode = 0x64
+	OP_VERIF               Opcode = 0x65
+	OP_VERNOTIF            Opcode = 0x66
+	OP_ELSE                Opcode = 0x67
+	OP_ENDIF               Opcode = 0x68
+	OP_VERIFY              Opcode = 0x69
+	OP_RETURN              Opcode = 0x6a
+	OP_TOALTSTACK          Opcode = 0x6b
+	OP_FROMALTSTACK        Opcode = 0x6c
+	OP_2DROP               Opcode = 0x6d
+	OP_2DUP                Opcode = 0x6e
+	OP_3DUP                Opcode = 0x6f
+	OP_2OVER               Opcode = 0x70
+	OP_2ROT                Opcode = 0x71
+	OP_2SWAP               Opcode = 0x72
+	OP_IFDUP               Opcode = 0x73
+	OP_DEPTH               Opcode = 0x74
+	OP_DROP                Opcode = 0x75
+	OP_DUP                 Opcode = 0x76
+	OP_NIP                 Opcode = 0x77
+	OP_OVER                Opcode = 0x78
+	OP_PICK                Opcode = 0x79
+	OP_ROLL                Opcode = 0x7a
+	OP_ROT                 Opcode = 0x7b
+	OP_SWAP                Opcode = 0x7c
+	OP_TUCK                Opcode = 0x7d
+	OP_CAT                 Opcode = 0x7e
+	OP_SUBSTR              Opcode = 0x7f
+	OP_LEFT                Opcode = 0x80
+	OP_RIGHT               Opcode = 0x81
+	OP_SIZE                Opcode = 0x82
+	OP_INVERT              Opcode = 0x83
+	OP_AND                 Opcode = 0x84
+	OP_OR                  Opcode = 0x85
+	OP_XOR                 Opcode = 0x86
+	OP_EQUAL               Opcode = 0x87
+	OP_EQUALVERIFY         Opcode = 0x88
+	OP_RESERVED1           Opcode = 0x89
+	OP_RESERVED2           Opcode = 0x8a
+	OP_1ADD                Opcode = 0x8b
+	OP_1SUB                Opcode = 0x8c
+	OP_2MUL                Opcode = 0x8d
+	OP_2DIV                Opcode = 0x8e
+	OP_NEGATE              Opcode = 0x8f
+	OP_ABS                 Opcode = 0x90
+	OP_NOT                 Opcode = 0x91
+	OP_0NOTEQUAL           Opcode = 0x92
+	OP_ADD                 Opcode = 0x93
+	OP_SUB                 Opcode = 0x94
+	OP_MUL                 Opcode = 0x95
+	OP_DIV                 Opcode = 0x96
+	OP_MOD                 Opcode = 0x97
+	OP_LSHIFT              Opcode = 0x98
+	OP_RSHIFT              Opcode = 0x99
+	OP_BOOLAND             Opcode = 0x9a
+	OP_BOOLOR              Opcode = 0x9b
+	OP_NUMEQUAL            Opcode = 0x9c
+	OP_NUMEQUALVERIFY      Opcode = 0x9d
+	OP_NUMNOTEQUAL         Opcode = 0x9e
+	OP_LESSTHAN            Opcode = 0x9f
+	OP_GREATERTHAN         Opcode = 0xa0
+	OP_LESSTHANOREQUAL     Opcode = 0xa1
+	OP_GREATERTHANOREQUAL  Opcode = 0xa2
+	OP_MIN                 Opcode = 0xa3
+	OP_MAX                 Opcode = 0xa4
+	OP_WITHIN              Opcode = 0xa5
+	OP_RIPEMD160           Opcode = 0xa6
+	OP_SHA1                Opcode = 0xa7
+	OP_SHA256              Opcode = 0xa8
+	OP_HASH160             Opcode = 0xa9
+	OP_HASH256             Opcode = 0xaa
+	OP_CODESEPARATOR       Opcode = 0xab
+	OP_CHECKSIG            Opcode = 0xac
+	OP_CHECKSIGVERIFY      Opcode = 0xad
+	OP_CHECKMULTISIG       Opcode = 0xae
+	OP_CHECKMULTISIGVERIFY Opcode = 0xaf
+	OP_NOP1                Opcode = 0xb0
+	OP_CHECKLOCKTIMEVERIFY Opcode = 0xb1
+	OP_NOP2                Opcode = OP_CHECKLOCKTIMEVERIFY
+	OP_CHECKSEQUENCEVERIFY Opcode = 0xb2
+	OP_NOP3                Opcode = OP_CHECKSEQUENCEVERIFY
+	OP_NOP4                Opcode = 0xb3
+	OP_NOP5                Opcode = 0xb4
+	OP_NOP6                Opcode = 0xb5
+	OP_NOP7                Opcode = 0xb6
+	OP_NOP8                Opcode = 0xb7
+	OP_NOP9                Opcode = 0xb8
+	OP_NOP10               Opcode = 0xb9
+	OP_CHECKSIGADD         Opcode = 0xba
+	OP_SUCCESS187          Opcode = 0xbb
+	OP_SUCCESS188          Opcode = 0xbc
+	OP_SUCCESS189          Opcode = 0xbd
+	OP_SUCCESS190          Opcode = 0xbe
+	OP_SUCCESS191          Opcode = 0xbf
+	OP_SUCCESS192          Opcode = 0xc0
+	OP_SUCCESS193          Opcode = 0xc1
+	OP_SUCCESS194          Opcode = 0xc2
+	OP_SUCCESS195          Opcode = 0xc3
+	OP_SUCCESS196          Opcode = 0xc4
+	OP_SUCCESS197          Opcode = 0xc5
+	OP_SUCCESS198          Opcode = 0xc6
+	OP_SUCCESS199          Opcode = 0xc7
+	OP_SUCCESS200          Opcode = 0xc8
+	OP_SUCCESS201          Opcode = 0xc9
+	OP_SUCCESS202          Opcode = 0xca
+	OP_SUCCESS203          Opcode = 0xcb
+	OP_SUCCESS204          Opcode = 0xcc
+	OP_SUCCESS205          Opcode = 0xcd
+	OP_SUCCESS206          Opcode = 0xce
+	OP_SUCCESS207          Opcode = 0xcf
+	OP_SUCCESS208          Opcode = 0xd0
+	OP_SUCCESS209          Opcode = 0xd1
+	OP_SUCCESS210          Opcode = 0xd2
+	OP_SUCCESS211          Opcode = 0xd3
+	OP_SUCCESS212          Opcode = 0xd4
+	OP_SUCCESS213          Opcode = 0xd5
+	OP_SUCCESS214          Opcode = 0xd6
+	OP_SUCCESS215          Opcode = 0xd7
+	OP_SUCCESS216          Opcode = 0xd8
+	OP_SUCCESS217          Opcode = 0xd9
+	OP_SUCCESS218          Opcode = 0xda
+	OP_SUCCESS219          Opcode = 0xdb
+	OP_SUCCESS220          Opcode = 0xdc
+	OP_SUCCESS221          Opcode = 0xdd
+	OP_SUCCESS222          Opcode = 0xde
+	OP_SUCCESS223          Opcode = 0xdf
+	OP_SUCCESS224          Opcode = 0xe0
+	OP_SUCCESS225          Opcode = 0xe1
+	OP_SUCCESS226          Opcode = 0xe2
+	OP_SUCCESS227          Opcode = 0xe3
+	OP_SUCCESS228          Opcode = 0xe4
+	OP_SUCCESS229          Opcode = 0xe5
+	OP_SUCCESS230          Opcode = 0xe6
+	OP_SUCCESS231          Opcode = 0xe7
+	OP_SUCCESS232          Opcode = 0xe8
+	OP_SUCCESS233          Opcode = 0xe9
+	OP_SUCCESS234          Opcode = 0xea
+	OP_SUCCESS235          Opcode = 0xeb
+	OP_SUCCESS236          Opcode = 0xec
+	OP_SUCCESS237          Opcode = 0xed
+	OP_SUCCESS238          Opcode = 0xee
+	OP_SUCCESS239          Opcode = 0xef
+	OP_SUCCESS240          Opcode = 0xf0
+	OP_SUCCESS241          Opcode = 0xf1
+	OP_SUCCESS242          Opcode = 0xf2
+	OP_SUCCESS243          Opcode = 0xf3
+	OP_SUCCESS244          Opcode = 0xf4
+	OP_SUCCESS245          Opcode = 0xf5
+	OP_SUCCESS246          Opcode = 0xf6
+	OP_SUCCESS247          Opcode = 0xf7
+	OP_SUCCESS248          Opcode = 0xf8
+	OP_SUCCESS249          Opcode = 0xf9
+	OP_SUCCESS250          Opcode = 0xfa
+	OP_SUCCESS251          Opcode = 0xfb
+	OP_SUCCESS252          Opcode = 0xfc
+	OP_SUCCESS253          Opcode = 0xfd
+	OP_SUCCESS254          Opcode = 0xfe
+	OP_INVALIDOPCODE       Opcode = 0xff
 )
 
-var opcodes = map[string]opcode{
-	"OP_0":         OP_0,
-	"OP_FALSE":     OP_FALSE,
-	"OP_PUSHDATA1": OP_PUSHDATA1,
-	"OP_PUSHDATA2": OP_PUSHDATA2,
-	"OP_PUSHDATA4": OP_PUSHDATA4,
-	"OP_1NEGATE":   OP_1NEGATE,
-	"OP_RESERVED":  OP_RESERVED,
-	"OP_1":         OP_1,
-	"OP_TRUE":      OP_TRUE,
-	"OP_2":         OP_2,
-	"OP_3":         OP_3,
-	"OP_4":         OP_4,
-	"OP_5":         OP_5,
-	"OP_6":         OP_6,
-	"OP_7":         OP_7,
-	"OP_8":         OP_8,
-	"OP_9":         OP_9,
-	"OP_10":        OP_10,
-	"OP_11":        OP_11,
-	"OP_12":        OP_12,
-	"OP_13":        OP_13,
-	"OP_14":        OP_14,
-	"OP_15":        OP_15,
-	"OP_16":        OP_16,
+// The following opcodes are redefined as OP_SUCCESSx by BIP342 and cause
+// a tapscript to succeed unconditionally.
+const (
+	OP_SUCCESS80  Opcode = OP_RESERVED
+	OP_SUCCESS98  Opcode = OP_VER
+	OP_SUCCESS126 Opcode = OP_CAT
+	OP_SUCCESS127 Opcode = OP_SUBSTR
+	OP_SUCCESS128 Opcode = OP_LEFT
+	OP_SUCCESS129 Opcode = OP_RIGHT
+	OP_SUCCESS131 Opcode = OP_INVERT
+	OP_SUCCESS132 Opcode = OP_AND
+	OP_SUCCESS133 Opcode = OP_OR
+	OP_SUCCESS134 Opcode = OP_XOR
+	OP_SUCCESS137 Opcode = OP_RESERVED1
+	OP_SUCCESS138 Opcode = OP_RESERVED2
+	OP_SUCCESS141 Opcode = OP_2MUL
+	OP_SUCCESS142 Opcode = OP_2DIV
+	OP_SUCCESS149 Opcode = OP_MUL
+	OP_SUCCESS150 Opcode = OP_DIV
+	OP_SUCCESS151 Opcode = OP_MOD
+	OP_SUCCESS152 Opcode = OP_LSHIFT
+	OP_SUCCESS153 Opcode = OP_RSHIFT
+)
+
+// opcodeNames maps each opcode with a dedicated name to that name. Direct
+// pushes and the unassigned OP_SUCCESSx range are named by String.
+var opcodeNames = map[Opcode]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_PUSHDATA4:           "OP_PUSHDATA4",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_RESERVED:            "OP_RESERVED",
+	OP_1:                   "OP_1",
+	OP_2:                   "OP_2",
+	OP_3:                   "OP_3",
+	OP_4:                   "OP_4",
+	OP_5:                   "OP_5",
+	OP_6:                   "OP_6",
+	OP_7:                   "OP_7",
+	OP_8:                   "OP_8",
+	OP_9:                   "OP_9",
+	OP_10:                  "OP_10",
+	OP_11:                  "OP_11",
+	OP_12:                  "OP_12",
+	OP_13:                  "OP_13",
+	OP_14:                  "OP_14",
+	OP_15:                  "OP_15",
+	OP_16:                  "OP_16",
+	OP_NOP:                 "OP_NOP",
+	OP_VER:                 "OP_VER",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_VERIF:               "OP_VERIF",
+	OP_VERNOTIF:            "OP_VERNOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_TOALTSTACK:          "OP_TOALTSTACK",
+	OP_FROMALTSTACK:        "OP_FROMALTSTACK",
+	OP_2DROP:               "OP_2DROP",
+	OP_2DUP:                "OP_2DUP",
+	OP_3DUP:                "OP_3DUP",
+	OP_2OVER:               "OP_2OVER",
+	OP_2ROT:                "OP_2ROT",
+	OP_2SWAP:               "OP_2SWAP",
+	OP_IFDUP:               "OP_IFDUP",
+	OP_DEPTH:               "OP_DEPTH",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_NIP:                 "OP_NIP",
+	OP_OVER:                "OP_OVER",
+	OP_PICK:                "OP_PICK",
+	OP_ROLL:                "OP_ROLL",
+	OP_ROT:                 "OP_ROT",
+	OP_SWAP:                "OP_SWAP",
+	OP_TUCK:                "OP_TUCK",
+	OP_CAT:                 "OP_CAT",
+	OP_SUBSTR:              "OP_SUBSTR",
+	OP_LEFT:                "OP_LEFT",
+	OP_RIGHT:               "OP_RIGHT",
+	OP_SIZE:                "OP_SIZE",
+	OP_INVERT:              "OP_INVERT",
+	OP_AND:                 "OP_AND",
+	OP_OR:                  "OP_OR",
+	OP_XOR:                 "OP_XOR",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_RESERVED1:           "OP_RESERVED1",
+	OP_RESERVED2:           "OP_RESERVED2",
+	OP_1ADD:                "OP_1ADD",
+	OP_1SUB:                "OP_1SUB",
+	OP_2MUL:                "OP_2MUL",
+	OP_2DIV:                "OP_2DIV",
+	OP_NEGATE:              "OP_NEGATE",
+	OP_ABS:                 "OP_ABS",
+	OP_NOT:                 "OP_NOT",
+	OP_0NOTEQUAL:           "OP_0NOTEQUAL",
+	OP_ADD:                 "OP_ADD",
+	OP_SUB:                 "OP_SUB",
+	OP_MUL:                 "OP_MUL",
+	OP_DIV:                 "OP_DIV",
+	OP_MOD:                 "OP_MOD",
+	OP_LSHIFT:              "OP_LSHIFT",
+	OP_RSHIFT:              "OP_RSHIFT",
+	OP_BOOLAND:             "OP_BOOLAND",
+	OP_BOOLOR:              "OP_BOOLOR",
+	OP_NUMEQUAL:            "OP_NUMEQUAL",
+	OP_NUMEQUALVERIFY:      "OP_NUMEQUALVERIFY",
+	OP_NUMNOTEQUAL:         "OP_NUMNOTEQUAL",
+	OP_LESSTHAN:            "OP_LESSTHAN",
+	OP_GREATERTHAN:         "OP_GREATERTHAN",
+	OP_LESSTHANOREQUAL:     "OP_LESSTHANOREQUAL",
+	OP_GREATERTHANOREQUAL:  "OP_GREATERTHANOREQUAL",
+	OP_MIN:                 "OP_MIN",
+	OP_MAX:                 "OP_MAX",
+	OP_WITHIN:              "OP_WITHIN",
+	OP_RIPEMD160:           "OP_RIPEMD160",
+	OP_SHA1:                "OP_SHA1",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_HASH256:             "OP_HASH256",
+	OP_CODESEPARATOR:       "OP_CODESEPARATOR",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_NOP1:                "OP_NOP1",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+	OP_NOP4:                "OP_NOP4",
+	OP_NOP5:                "OP_NOP5",
+	OP_NOP6:                "OP_NOP6",
+	OP_NOP7:                "OP_NOP7",
+	OP_NOP8:                "OP_NOP8",
+	OP_NOP9:                "OP_NOP9",
+	OP_NOP10:               "OP_NOP10",
+	OP_CHECKSIGADD:         "OP_CHECKSIGADD",
+	OP_INVALIDOPCODE:       "OP_INVALIDOPCODE",
+}
+
+// opcodes maps the name of every opcode, including aliases, to its value. It
+// is used when assembling scripts from text.
+var opcodes = map[string]Opcode{
+	"OP_FALSE": OP_FALSE,
+	"OP_TRUE":  OP_TRUE,
+	"OP_NOP2":  OP_NOP2,
+	"OP_NOP3":  OP_NOP3,
+}
+
+func init() {
+	for i := 0; i < 256; i++ {
+		op := Opcode(i)
+		opcodes[op.String()] = op
+	}
+}
+
+// String returns the name of the opcode, for example OP_CHECKSIG.
+func (o Opcode) String() string {
+	switch {
+	case o >= OP_DATA_1 && o <= OP_DATA_75:
+		return fmt.Sprintf("OP_DATA_%d", o)
+
+	case o >= OP_SUCCESS187 && o <= OP_SUCCESS254:
+		return fmt.Sprintf("OP_SUCCESS%d", o)
+	}
+
+	return opcodeNames[o]
+}
+
+// IsSuccess returns true if the opcode is one of the OP_SUCCESSx opcodes
+// defined by BIP342. Any of these make a tapscript succeed as soon as it is
+// decoded, which leaves them available for future soft forks.
+func (o Opcode) IsSuccess() bool {
+	switch {
+	case o == 80, o == 98, o >= 126 && o <= 129, o >= 131 && o <= 134,
+		o >= 137 && o <= 138, o >= 141 && o <= 142,
+		o >= 149 && o <= 153, o >= 187 && o <= 254:
+
+		return true
+	}
+
+	return false
 }
@@ -2,6 +2,7 @@ package script
 
 import (
 	"encoding/hex"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,12 +12,284 @@ func TestParse(t *testing.T) {
 	b, err := hex.DecodeString("6a47304402207899531a52d59a6de200179928ca900254a36b8dff8bb75f5f5d71b1cdc26125022008b422690b8461cb52c3cc30330b23d574351872b7c361e9aae3649071c1a7160121035d5c93d9ac96881f19ba1f686f15f009ded7c62efe85a872e6a19b43c15a2937")
 	require.NoError(t, err)
 
-	s := Parse(b)
+	s, err := Parse(b)
+	require.NoError(t, err)
 	require.Len(t, s, 2)
-	require.Equal(t, "304402207899531a52d59a6de200179928ca900254a36b8dff8bb75f5f5d71b1cdc26125022008b422690b8461cb52c3cc30330b23d574351872b7c361e9aae3649071c1a71601", hex.EncodeToString(s[0].data))
-	require.Equal(t, "035d5c93d9ac96881f19ba1f686f15f009ded7c62efe85a872e6a19b43c15a2937", hex.EncodeToString(s[1].data))
+	require.Equal(t, "304402207899531a52d59a6de200179928ca900254a36b8dff8bb75f5f5d71b1cdc26125022008b422690b8461cb52c3cc30330b23d574351872b7c361e9aae3649071c1a71601", hex.EncodeToString(s[0].Data))
+	require.Equal(t, "035d5c93d9ac96881f19ba1f686f15f009ded7c62efe85a872e6a19b43c15a2937", hex.EncodeToString(s[1].Data))
 
 	serBytes, err := s.Serialize()
 	require.NoError(t, err)
 	require.Equal(t, b, serBytes)
 }
+
+func TestFindAndDelete(t *testing.T) {
+	// <sig> OP_DUP <sig> OP_CODESEPARATOR <other>
+	b, err := hex.DecodeString("0d" + "03aabbcc" + "76" + "03aabbcc" + "ab" + "02aabb")
+	require.NoError(t, err)
+
+	s, err := Parse(b)
+	require.NoError(t, err)
+	require.Len(t, s, 5)
+
+	sig, err := hex.DecodeString("aabbcc")
+	require.NoError(t, err)
+
+	res := s.FindAndDelete(sig)
+	require.Len(t, res, 3)
+	require.Len(t, s, 5)
+
+	res = res.RemoveCodeSeparators()
+	ser, err := res.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, "047602aabb", hex.EncodeToString(ser))
+}
+
+func TestOpcodeNames(t *testing.T) {
+	var success int
+	for i := 0; i < 256; i++ {
+		op := Opcode(i)
+		name := op.String()
+		require.NotEmpty(t, name, "opcode 0x%02x", i)
+		require.Equal(t, op, opcodes[name])
+
+		if op.IsSuccess() {
+			success++
+		}
+	}
+
+	require.Equal(t, 87, success)
+	require.Equal(t, "OP_DATA_20", OP_DATA_20.String())
+	require.Equal(t, "OP_CHECKLOCKTIMEVERIFY", OP_NOP2.String())
+	require.Equal(t, "OP_CHECKSIGADD", OP_CHECKSIGADD.String())
+	require.Equal(t, "OP_SUCCESS200", Opcode(200).String())
+	require.Equal(t, "OP_INVALIDOPCODE", Opcode(0xff).String())
+	require.True(t, OP_CAT.IsSuccess())
+	require.False(t, OP_CHECKSIGADD.IsSuccess())
+}
+
+func TestASM(t *testing.T) {
+	b, err := hex.DecodeString("6a47304402207899531a52d59a6de200179928ca900254a36b8dff8bb75f5f5d71b1cdc26125022008b422690b8461cb52c3cc30330b23d574351872b7c361e9aae3649071c1a7160121035d5c93d9ac96881f19ba1f686f15f009ded7c62efe85a872e6a19b43c15a2937")
+	require.NoError(t, err)
+
+	s, err := Parse(b)
+	require.NoError(t, err)
+	asm := "304402207899531a52d59a6de200179928ca900254a36b8dff8bb75f5f5d71b1cdc26125022008b422690b8461cb52c3cc30330b23d574351872b7c361e9aae3649071c1a71601 035d5c93d9ac96881f19ba1f686f15f009ded7c62efe85a872e6a19b43c15a2937"
+	require.Equal(t, asm, s.String())
+
+	parsed, err := ParseASM(asm)
+	require.NoError(t, err)
+	require.Equal(t, s, parsed)
+
+	tests := []struct {
+		name string
+		asm  string
+		ser  string
+		out  string
+	}{
+		{
+			name: "p2pkh",
+			asm:  "OP_DUP OP_HASH160 d0c2e1d6d1e0d1f6f7f9fd3e4b8a8bd8f0c0d0e0 OP_EQUALVERIFY OP_CHECKSIG",
+			ser:  "76a914d0c2e1d6d1e0d1f6f7f9fd3e4b8a8bd8f0c0d0e088ac",
+		},
+		{
+			name: "small numbers",
+			asm:  "0 -1 1 16 17 -17 1000 2147483647",
+			ser:  "004f51600111019102e80304ffffff7f",
+		},
+		{
+			name: "names without prefix and aliases",
+			asm:  "DUP OP_TRUE OP_FALSE OP_NOP2 CHECKSIGADD",
+			ser:  "765100b1ba",
+			out:  "OP_DUP 1 0 OP_CHECKLOCKTIMEVERIFY OP_CHECKSIGADD",
+		},
+		{
+			name: "short pushes shown as numbers",
+			asm:  "aabbcc",
+			ser:  "03aabbcc",
+			out:  "-5028778",
+		},
+		{
+			name: "numbers with leading zeros are hex",
+			asm:  "0000000000",
+			ser:  "050000000000",
+			out:  "0000000000",
+		},
+		{
+			name: "numbers out of range are hex",
+			asm:  "2147483648",
+			ser:  "052147483648",
+		},
+		{
+			name: "explicit push opcodes",
+			asm:  "OP_DATA_5 0102030405 OP_PUSHDATA1 0102030405 PUSHDATA2 00",
+			ser:  "0501020304054c0501020304054d010000",
+			out:  "0102030405 0102030405 0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseASM(test.asm)
+			require.NoError(t, err)
+
+			ser, err := s.Serialize()
+			require.NoError(t, err)
+			require.Equal(t, test.ser, hex.EncodeToString(ser[1:]))
+
+			out := test.out
+			if out == "" {
+				out = test.asm
+			}
+			require.Equal(t, out, s.String())
+		})
+	}
+
+	// Short pushes that are not the smallest push of their number don't
+	// survive a round trip.
+	for raw, want := range map[string]string{
+		"0100":   "00",
+		"020100": "51",
+		"0181":   "4f",
+		"0180":   "00",
+	} {
+		b, err := hex.DecodeString(raw)
+		require.NoError(t, err)
+
+		s, err := ParseRaw(b)
+		require.NoError(t, err)
+
+		parsed, err := ParseASM(s.String())
+		require.NoError(t, err)
+
+		ser, err := parsed.SerializeRaw()
+		require.NoError(t, err)
+		require.Equal(t, want, hex.EncodeToString(ser), raw)
+	}
+
+	for _, bad := range []string{
+		"OP_FOO", "abc", "0xzz", "+5", "-0", "OP_DATA_5", "OP_DATA_2 aabbcc",
+		"OP_PUSHDATA1 zz", "OP_DATA_1 OP_DUP",
+	} {
+		_, err := ParseASM(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		len  int
+	}{
+		{name: "empty", raw: "", len: 0},
+		{name: "direct push", raw: "03aabbcc", len: 1},
+		{name: "non-minimal pushdata1", raw: "4c03aabbcc", len: 1},
+		{name: "non-minimal pushdata2", raw: "4d0300aabbcc", len: 1},
+		{name: "non-minimal pushdata4", raw: "4e03000000aabbcc", len: 1},
+		{name: "empty pushdata1", raw: "4c00", len: 1},
+		{name: "mixed", raw: "0076a94c0101ac", len: 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := hex.DecodeString(test.raw)
+			require.NoError(t, err)
+
+			s, err := ParseRaw(b)
+			require.NoError(t, err)
+			require.Len(t, s, test.len)
+
+			ser, err := s.SerializeRaw()
+			require.NoError(t, err)
+			require.Equal(t, b, ser)
+
+			prefixed := append([]byte{byte(len(b))}, b...)
+			s2, err := Parse(prefixed)
+			require.NoError(t, err)
+			require.Equal(t, s, s2)
+
+			ser, err = s2.Serialize()
+			require.NoError(t, err)
+			require.Equal(t, prefixed, ser)
+		})
+	}
+
+	// A push that needs PUSHDATA2 and a varint length prefix.
+	data := make([]byte, 300)
+	s := Script{{Opcode: OP_PUSHDATA2, Data: data}}
+	ser, err := s.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xfd, 0x2f, 0x01, 0x4d, 0x2c, 0x01}, ser[:6])
+
+	s2, err := Parse(ser)
+	require.NoError(t, err)
+	require.Equal(t, s, s2)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		b      string
+		raw    bool
+		code   ErrorCode
+		offset int
+	}{
+		{name: "empty input", b: "", code: ErrMalformedScript},
+		{name: "truncated prefix", b: "fd01", code: ErrMalformedScript},
+		{name: "short", b: "0376", code: ErrMalformedScript},
+		{name: "trailing", b: "017676", code: ErrMalformedScript},
+		{
+			name: "truncated direct push", b: "047603aabb",
+			code: ErrMalformedPush, offset: 2,
+		},
+		{
+			name: "pushdata1 missing length", b: "76764c", raw: true,
+			code: ErrMalformedPush, offset: 2,
+		},
+		{
+			name: "pushdata1 truncated", b: "4c02aa", raw: true,
+			code: ErrMalformedPush,
+		},
+		{
+			name: "pushdata2 truncated length", b: "4d01", raw: true,
+			code: ErrMalformedPush,
+		},
+		{
+			name: "pushdata4 huge length", b: "4effffffffaa",
+			raw: true, code: ErrMalformedPush,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := hex.DecodeString(test.b)
+			require.NoError(t, err)
+
+			if test.raw {
+				_, err = ParseRaw(b)
+			} else {
+				_, err = Parse(b)
+			}
+			require.True(t, IsErrorCode(err, test.code), err)
+
+			var perr *ParseError
+			require.True(t, errors.As(err, &perr))
+			require.Equal(t, test.offset, perr.Offset)
+		})
+	}
+}
+
+func TestSerializeErrors(t *testing.T) {
+	tests := []Script{
+		{{Opcode: OP_DATA_2, Data: []byte{0x01}}},
+		{{Opcode: OP_DUP, Data: []byte{0x01}}},
+		{{Opcode: OP_0, Data: []byte{0x01}}},
+		{{Opcode: OP_PUSHDATA1, Data: make([]byte, 256)}},
+	}
+
+	for _, s := range tests {
+		_, err := s.Serialize()
+		require.True(t, IsErrorCode(err, ErrMalformedPush), err)
+	}
+}
@@ -0,0 +1,111 @@
+package script
+
+import "fmt"
+
+const (
+	// defaultScriptNumLen is the maximum number of bytes an encoded number
+	// may have when used as an argument to an arithmetic opcode.
+	defaultScriptNumLen = 4
+
+	// locktimeScriptNumLen is the maximum length allowed for the arguments
+	// of the locktime opcodes which need to represent values up to 2^39.
+	locktimeScriptNumLen = 5
+)
+
+// encodeNum encodes n as a minimally encoded little endian number with the
+// sign held in the most significant bit of the final byte.
+func encodeNum(n int64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+
+	neg := n < 0
+	abs := uint64(n)
+	if neg {
+		abs = uint64(-n)
+	}
+
+	var res []byte
+	for abs > 0 {
+		res = append(res, byte(abs&0xff))
+		abs >>= 8
+	}
+
+	// If the most significant byte already has its high bit set then an
+	// extra byte is needed to hold the sign.
+	if res[len(res)-1]&0x80 != 0 {
+		extra := byte(0x00)
+		if neg {
+			extra = 0x80
+		}
+		res = append(res, extra)
+	} else if neg {
+		res[len(res)-1] |= 0x80
+	}
+
+	return res
+}
+
+// decodeNum interprets b as a script number. Numbers longer than maxLen
+// bytes are rejected and, if requireMinimal is set, so are numbers that are
+// not minimally encoded.
+func decodeNum(b []byte, requireMinimal bool, maxLen int) (int64, error) {
+	if len(b) > maxLen {
+		return 0, scriptError(ErrNumberTooBig, fmt.Sprintf(
+			"numeric value encoded as %x is %d bytes which exceeds "+
+				"the max allowed of %d", b, len(b), maxLen))
+	}
+
+	if requireMinimal && len(b) > 0 {
+		// The most significant byte may only be zero (ignoring the
+		// sign bit) if the next byte has its high bit set, meaning the
+		// extra byte is needed to hold the sign.
+		if b[len(b)-1]&0x7f == 0 {
+			if len(b) == 1 || b[len(b)-2]&0x80 == 0 {
+				return 0, scriptError(ErrMinimalData, fmt.Sprintf(
+					"numeric value encoded as %x is not "+
+						"minimally encoded", b))
+			}
+		}
+	}
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	var n int64
+	for i, v := range b {
+		n |= int64(v) << uint(8*i)
+	}
+
+	if b[len(b)-1]&0x80 != 0 {
+		n &= ^(int64(0x80) << uint(8*(len(b)-1)))
+		return -n, nil
+	}
+
+	return n, nil
+}
+
+// asBool interprets b as a boolean. Any non-zero value is true except for
+// negative zero.
+func asBool(b []byte) bool {
+	for i, v := range b {
+		if v != 0 {
+			// Negative zero is false.
+			if i == len(b)-1 && v == 0x80 {
+				return false
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+func fromBool(v bool) []byte {
+	if v {
+		return []byte{0x01}
+	}
+
+	return []byte{}
+}
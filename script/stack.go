@@ -0,0 +1,99 @@
+package script
+
+import "fmt"
+
+// stack is the main or alt stack used during script evaluation. The top of
+// the stack is the last element of the slice.
+type stack [][]byte
+
+func (s *stack) push(b []byte) {
+	*s = append(*s, b)
+}
+
+func (s *stack) pushNum(n int64) {
+	s.push(encodeNum(n))
+}
+
+func (s *stack) pushBool(v bool) {
+	s.push(fromBool(v))
+}
+
+// peek returns the element n places from the top of the stack without
+// removing it.
+func (s stack) peek(n int) ([]byte, error) {
+	if n < 0 || n >= len(s) {
+		return nil, scriptError(ErrInvalidStackOperation, fmt.Sprintf(
+			"index %d is invalid for stack size %d", n, len(s)))
+	}
+
+	return s[len(s)-1-n], nil
+}
+
+func (s *stack) pop() ([]byte, error) {
+	b, err := s.peek(0)
+	if err != nil {
+		return nil, err
+	}
+
+	*s = (*s)[:len(*s)-1]
+
+	return b, nil
+}
+
+func (s *stack) popNum(requireMinimal bool) (int64, error) {
+	b, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+
+	return decodeNum(b, requireMinimal, defaultScriptNumLen)
+}
+
+func (s *stack) popBool() (bool, error) {
+	b, err := s.pop()
+	if err != nil {
+		return false, err
+	}
+
+	return asBool(b), nil
+}
+
+// remove removes and returns the element n places from the top.
+func (s *stack) remove(n int) ([]byte, error) {
+	b, err := s.peek(n)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := len(*s) - 1 - n
+	*s = append((*s)[:idx], (*s)[idx+1:]...)
+
+	return b, nil
+}
+
+// dupN duplicates the top n elements of the stack.
+func (s *stack) dupN(n int) error {
+	if n > len(*s) {
+		return scriptError(ErrInvalidStackOperation, fmt.Sprintf(
+			"attempt to duplicate %d elements of stack with size %d",
+			n, len(*s)))
+	}
+
+	for i := 0; i < n; i++ {
+		b, _ := s.peek(n - 1)
+		s.push(b)
+	}
+
+	return nil
+}
+
+// checkSize ensures the stack has at least n elements.
+func (s stack) checkSize(n int) error {
+	if len(s) < n {
+		return scriptError(ErrInvalidStackOperation, fmt.Sprintf(
+			"operation requires %d stack elements but stack has %d",
+			n, len(s)))
+	}
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/schnorr"
+	"github.com/ellemouton/btc/varint"
+)
+
+const (
+	// annexTag is the first byte of the annex, an optional last witness
+	// element of a taproot spend that is reserved for future use.
+	annexTag = 0x50
+
+	// baseLeafVersion is the leaf version of tapscripts, the only leaves
+	// whose scripts are executed.
+	baseLeafVersion = 0xc0
+	leafVersionMask = 0xfe
+
+	controlBlockBaseSize     = 33
+	controlBlockNodeSize     = 32
+	controlBlockMaxNodeCount = 128
+
+	// Every signature check in a tapscript uses up
+	// validationWeightPerSigOp of a budget that is the size of the
+	// witness plus validationWeightOffset.
+	validationWeightPerSigOp = 50
+	validationWeightOffset   = 50
+
+	// noCodeSeparator is the code separator position committed to when no
+	// OP_CODESEPARATOR has been executed.
+	noCodeSeparator uint32 = 0xffffffff
+
+	tagTapLeaf   = "TapLeaf"
+	tagTapBranch = "TapBranch"
+	tagTapTweak  = "TapTweak"
+)
+
+// TaprootTxContext extends TxContext with the BIP341 signature hashes, which
+// commit to the outputs spent by every input of the tx. It is needed to check
+// the signatures of taproot spends and is implemented by *tx.SpendContext.
+type TaprootTxContext interface {
+	TxContext
+
+	// TaprootSigHash computes the signature hash of a key path spend. A
+	// nil annex means that the witness has no annex.
+	TaprootSigHash(inputIndex int, hashType uint32, annex []byte) ([]byte,
+		error)
+
+	// TapscriptSigHash computes the signature hash of a script path spend
+	// of the leaf with the given tapleaf hash.
+	TapscriptSigHash(inputIndex int, hashType uint32, annex,
+		leafHash []byte, codeSepPos uint32) ([]byte, error)
+}
+
+// taprootExec holds the state of a taproot spend that is shared by all of
+// its signature checks.
+type taprootExec struct {
+	annex []byte
+
+	// leafHash is the tapleaf hash of the executed script and is nil for
+	// key path spends.
+	leafHash []byte
+
+	// weightLeft is what remains of the validation weight budget.
+	weightLeft int
+}
+
+// verifyTaproot evaluates the witness of a taproot output with the given
+// x-only output key.
+func (e *engine) verifyTaproot(witness [][]byte, program []byte) error {
+	if len(witness) == 0 {
+		return scriptError(ErrWitnessProgramWitnessEmpty,
+			"taproot witness is empty")
+	}
+
+	st := append(stack{}, witness...)
+	e.taproot = &taprootExec{}
+
+	if last := st[len(st)-1]; len(st) >= 2 && len(last) > 0 &&
+		last[0] == annexTag {
+
+		e.taproot.annex = last
+		st = st[:len(st)-1]
+	}
+
+	// A single element is a signature for the output key.
+	if len(st) == 1 {
+		return e.checkSchnorrSig(st[0], program, noCodeSeparator)
+	}
+
+	control, _ := st.pop()
+	leafScript, _ := st.pop()
+
+	if len(control) < controlBlockBaseSize ||
+		(len(control)-controlBlockBaseSize)%controlBlockNodeSize != 0 ||
+		(len(control)-controlBlockBaseSize)/controlBlockNodeSize >
+			controlBlockMaxNodeCount {
+
+		return scriptError(ErrTaprootWrongControlSize, fmt.Sprintf(
+			"invalid control block size %d", len(control)))
+	}
+
+	leafVersion := control[0] & leafVersionMask
+	leafHash, err := tapLeafHash(leafVersion, leafScript)
+	if err != nil {
+		return err
+	}
+
+	if !verifyTaprootCommitment(control, program, leafHash) {
+		return scriptError(ErrWitnessProgramMismatch, "control block "+
+			"does not commit to the output key")
+	}
+
+	if leafVersion != baseLeafVersion {
+		if e.hasFlag(VerifyDiscourageUpgradableTaprootVersion) {
+			return scriptError(
+				ErrDiscourageUpgradableTaprootVersion,
+				fmt.Sprintf("leaf version 0x%02x is reserved "+
+					"for soft forks", leafVersion),
+			)
+		}
+
+		return nil
+	}
+
+	weight, err := witnessSize(witness)
+	if err != nil {
+		return err
+	}
+	e.taproot.leafHash = leafHash
+	e.taproot.weightLeft = weight + validationWeightOffset
+
+	return e.executeTapscript(leafScript, st)
+}
+
+// executeTapscript runs a tapscript leaf. A script that contains an
+// OP_SUCCESSx opcode succeeds without being executed, even if it fails to
+// parse after that opcode.
+func (e *engine) executeTapscript(leafScript []byte, st stack) error {
+	s, parseErr := ParseRaw(leafScript)
+	if parseErr != nil {
+		var pErr *ParseError
+		if !errors.As(parseErr, &pErr) {
+			return parseErr
+		}
+
+		var err error
+		s, err = ParseRaw(leafScript[:pErr.Offset])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, el := range s {
+		if !isOpSuccess(el.Opcode) {
+			continue
+		}
+
+		if e.hasFlag(VerifyDiscourageOpSuccess) {
+			return scriptError(ErrDiscourageOpSuccess, fmt.Sprintf(
+				"opcode 0x%02x is reserved for soft forks",
+				byte(el.Opcode)))
+		}
+
+		return nil
+	}
+
+	if parseErr != nil {
+		return parseErr
+	}
+
+	if len(st) > maxStackSize {
+		return scriptError(ErrStackSize, fmt.Sprintf("witness stack "+
+			"size %d exceeds the max of %d", len(st), maxStackSize))
+	}
+
+	return e.executeWitnessScript(s, st, sigVersionTapscript)
+}
+
+// checkSigTapscript evaluates a tapscript signature check as done by
+// OP_CHECKSIG, OP_CHECKSIGVERIFY and OP_CHECKSIGADD. An empty signature
+// fails the check, while any other signature must be valid.
+func (e *engine) checkSigTapscript(sig, pubKey []byte,
+	codeSepPos uint32) (bool, error) {
+
+	success := len(sig) > 0
+	if success {
+		e.taproot.weightLeft -= validationWeightPerSigOp
+		if e.taproot.weightLeft < 0 {
+			return false, scriptError(ErrTapscriptValidationWeight,
+				"validation weight budget exceeded")
+		}
+	}
+
+	switch {
+	case len(pubKey) == 0:
+		return false, scriptError(ErrPubKeyType, "tapscript public "+
+			"key is empty")
+
+	case len(pubKey) == 32:
+		if success {
+			err := e.checkSchnorrSig(sig, pubKey, codeSepPos)
+			if err != nil {
+				return false, err
+			}
+		}
+
+	// Other key types are reserved for soft forks and are treated as
+	// valid.
+	case e.hasFlag(VerifyDiscourageUpgradablePubKeyType):
+		return false, scriptError(ErrDiscourageUpgradablePubKeyType,
+			fmt.Sprintf("public key of size %d is reserved for "+
+				"soft forks", len(pubKey)))
+	}
+
+	return success, nil
+}
+
+// checkSchnorrSig verifies a BIP340 signature, optionally followed by its
+// hash type, against an x-only public key. The codeSepPos is only used for
+// script path spends.
+func (e *engine) checkSchnorrSig(sig, pubKey []byte,
+	codeSepPos uint32) error {
+
+	var hashType uint32
+	switch len(sig) {
+	case schnorr.SignatureSize:
+
+	case schnorr.SignatureSize + 1:
+		// The default hash type must be implied by leaving it out.
+		hashType = uint32(sig[schnorr.SignatureSize])
+		if hashType == 0 {
+			return scriptError(ErrSchnorrSigHashType, "explicit "+
+				"SIGHASH_DEFAULT hash type")
+		}
+		sig = sig[:schnorr.SignatureSize]
+
+	default:
+		return scriptError(ErrSchnorrSigSize, fmt.Sprintf("invalid "+
+			"schnorr signature size %d", len(sig)))
+	}
+
+	tx, ok := e.tx.(TaprootTxContext)
+	if !ok {
+		return scriptError(ErrSigHash, "taproot signature hashes "+
+			"need the outputs spent by the tx")
+	}
+
+	var (
+		z   []byte
+		err error
+	)
+	if e.taproot.leafHash == nil {
+		z, err = tx.TaprootSigHash(
+			e.inputIdx, hashType, e.taproot.annex,
+		)
+	} else {
+		z, err = tx.TapscriptSigHash(
+			e.inputIdx, hashType, e.taproot.annex,
+			e.taproot.leafHash, codeSepPos,
+		)
+	}
+	if err != nil {
+		return scriptError(ErrSchnorrSigHashType, err.Error())
+	}
+
+	pk, err := s256point.ParseXOnly(pubKey)
+	if err != nil {
+		return scriptError(ErrSchnorrSig, err.Error())
+	}
+
+	parsed, err := schnorr.Parse(sig)
+	if err != nil {
+		return scriptError(ErrSchnorrSig, err.Error())
+	}
+
+	valid, err := pk.VerifySchnorr(z, parsed)
+	if err != nil || !valid {
+		return scriptError(ErrSchnorrSig, "invalid schnorr signature")
+	}
+
+	return nil
+}
+
+// tapLeafHash returns the TapLeaf hash of the leaf version and the length
+// prefixed script.
+func tapLeafHash(leafVersion byte, leafScript []byte) ([]byte, error) {
+	length, err := varint.Encode(uint64(len(leafScript)))
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.TaggedHash(
+		tagTapLeaf, []byte{leafVersion}, length, leafScript,
+	), nil
+}
+
+// verifyTaprootCommitment checks that the output key commits to the leaf
+// through the merkle path and internal key of the control block.
+func verifyTaprootCommitment(control, outputKey, leafHash []byte) bool {
+	internalKey := control[1:controlBlockBaseSize]
+	p, err := s256point.ParseXOnly(internalKey)
+	if err != nil {
+		return false
+	}
+
+	k := leafHash
+	proof := control[controlBlockBaseSize:]
+	for i := 0; i < len(proof); i += controlBlockNodeSize {
+		node := proof[i : i+controlBlockNodeSize]
+		if bytes.Compare(k, node) < 0 {
+			k = helpers.TaggedHash(tagTapBranch, k, node)
+		} else {
+			k = helpers.TaggedHash(tagTapBranch, node, k)
+		}
+	}
+
+	tweak := helpers.TaggedHash(tagTapTweak, internalKey, k)
+	t := new(big.Int).SetBytes(tweak)
+	if t.Cmp(s256point.N) >= 0 {
+		return false
+	}
+
+	tG, err := s256point.G.Mul(t)
+	if err != nil {
+		return false
+	}
+
+	q, err := p.Add(tG)
+	if err != nil || q.IsInfinity() {
+		return false
+	}
+
+	qp := q.(*s256point.S256Point)
+
+	return bytes.Equal(qp.XOnly(), outputKey) &&
+		qp.HasEvenY() == (control[0]&1 == 0)
+}
+
+// witnessSize returns the serialized size of the witness.
+func witnessSize(witness [][]byte) (int, error) {
+	n, err := varint.Encode(uint64(len(witness)))
+	if err != nil {
+		return 0, err
+	}
+
+	size := len(n)
+	for _, item := range witness {
+		n, err := varint.Encode(uint64(len(item)))
+		if err != nil {
+			return 0, err
+		}
+		size += len(n) + len(item)
+	}
+
+	return size, nil
+}
+
+// isOpSuccess returns true if the opcode is one of the OP_SUCCESSx opcodes
+// that BIP342 reserves for soft forks.
+func isOpSuccess(op Opcode) bool {
+	return op == 80 || op == 98 || (op >= 126 && op <= 129) ||
+		(op >= 131 && op <= 134) || (op >= 137 && op <= 138) ||
+		(op >= 141 && op <= 142) || (op >= 149 && op <= 153) ||
+		(op >= 187 && op <= 254)
+}
@@ -0,0 +1,352 @@
+package script
+
+import (
+	"fmt"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/s256point"
+)
+
+const (
+	hash160Len = 20
+	sha256Len  = 32
+
+	// maxMultisigPubKeys is the largest number of keys that can be used
+	// in a bare or P2SH multisig script built with small integer opcodes.
+	maxMultisigPubKeys = 16
+)
+
+// Type identifies a standard script template.
+type Type int
+
+const (
+	TypeNonStandard Type = iota
+	TypeP2PK
+	TypeP2PKH
+	TypeP2SH
+	TypeP2WPKH
+	TypeP2WSH
+	TypeP2TR
+	TypeWitnessUnknown
+	TypeMultisig
+	TypeNullData
+)
+
+var typeStrings = map[Type]string{
+	TypeNonStandard:    "nonstandard",
+	TypeP2PK:           "pubkey",
+	TypeP2PKH:          "pubkeyhash",
+	TypeP2SH:           "scripthash",
+	TypeP2WPKH:         "witness_v0_keyhash",
+	TypeP2WSH:          "witness_v0_scripthash",
+	TypeP2TR:           "witness_v1_taproot",
+	TypeWitnessUnknown: "witness_unknown",
+	TypeMultisig:       "multisig",
+	TypeNullData:       "nulldata",
+}
+
+// String returns the name Bitcoin Core uses for the script type.
+func (t Type) String() string {
+	if s, ok := typeStrings[t]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("unknown type (%d)", int(t))
+}
+
+// P2PK returns the script <pubkey> OP_CHECKSIG.
+func P2PK(pubKey *s256point.S256Point, compressed bool) Script {
+	return Script{pushElem(pubKey.Sec(compressed)), {Opcode: OP_CHECKSIG}}
+}
+
+// P2PKH returns the script
+// OP_DUP OP_HASH160 <hash160> OP_EQUALVERIFY OP_CHECKSIG.
+func P2PKH(hash160 []byte) (Script, error) {
+	if len(hash160) != hash160Len {
+		return nil, fmt.Errorf("pubkey hash must be %d bytes, got %d",
+			hash160Len, len(hash160))
+	}
+
+	return Script{
+		{Opcode: OP_DUP},
+		{Opcode: OP_HASH160},
+		pushElem(hash160),
+		{Opcode: OP_EQUALVERIFY},
+		{Opcode: OP_CHECKSIG},
+	}, nil
+}
+
+// P2SH returns the P2SH script that commits to the given redeem script.
+func P2SH(redeemScript Script) (Script, error) {
+	b, err := redeemScript.SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return P2SHFromHash(helpers.Hash160(b))
+}
+
+// P2SHFromHash returns the script OP_HASH160 <hash160> OP_EQUAL.
+func P2SHFromHash(hash160 []byte) (Script, error) {
+	if len(hash160) != hash160Len {
+		return nil, fmt.Errorf("script hash must be %d bytes, got %d",
+			hash160Len, len(hash160))
+	}
+
+	return Script{
+		{Opcode: OP_HASH160},
+		pushElem(hash160),
+		{Opcode: OP_EQUAL},
+	}, nil
+}
+
+// WitnessProgram returns the script made up of the version opcode followed by
+// a push of the witness program.
+func WitnessProgram(version int, program []byte) (Script, error) {
+	if version < 0 || version > 16 {
+		return nil, fmt.Errorf("invalid witness version %d", version)
+	}
+
+	if len(program) < 2 || len(program) > 40 {
+		return nil, fmt.Errorf("witness program must be 2 to 40 bytes, "+
+			"got %d", len(program))
+	}
+
+	return Script{numElem(int64(version)), pushElem(program)}, nil
+}
+
+// P2WPKH returns the script OP_0 <hash160>.
+func P2WPKH(hash160 []byte) (Script, error) {
+	if len(hash160) != hash160Len {
+		return nil, fmt.Errorf("pubkey hash must be %d bytes, got %d",
+			hash160Len, len(hash160))
+	}
+
+	return WitnessProgram(0, hash160)
+}
+
+// P2WSH returns the P2WSH script that commits to the given witness script.
+func P2WSH(witnessScript Script) (Script, error) {
+	b, err := witnessScript.SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return P2WSHFromHash(helpers.Sha256(b))
+}
+
+// P2WSHFromHash returns the script OP_0 <sha256>.
+func P2WSHFromHash(hash []byte) (Script, error) {
+	if len(hash) != sha256Len {
+		return nil, fmt.Errorf("script hash must be %d bytes, got %d",
+			sha256Len, len(hash))
+	}
+
+	return WitnessProgram(0, hash)
+}
+
+// P2TR returns the script OP_1 <output key> where the output key is the 32
+// byte x-only encoding of the tweaked taproot key.
+func P2TR(outputKey []byte) (Script, error) {
+	if len(outputKey) != 32 {
+		return nil, fmt.Errorf("taproot output key must be 32 bytes, "+
+			"got %d", len(outputKey))
+	}
+
+	return WitnessProgram(1, outputKey)
+}
+
+// Multisig returns the script
+// OP_m <pubkey 1> ... <pubkey n> OP_n OP_CHECKMULTISIG using the compressed
+// encoding of each key. Up to 16 keys are allowed, which is the limit for
+// P2SH and P2WSH redeem scripts, but bare multisig outputs with more than 3
+// keys are not relayed by default.
+func Multisig(m int, pubKeys []*s256point.S256Point) (Script, error) {
+	n := len(pubKeys)
+	if n < 1 || n > maxMultisigPubKeys {
+		return nil, fmt.Errorf("multisig requires 1 to %d keys, got %d",
+			maxMultisigPubKeys, n)
+	}
+
+	if m < 1 || m > n {
+		return nil, fmt.Errorf("invalid number of required signatures "+
+			"%d for %d keys", m, n)
+	}
+
+	s := Script{numElem(int64(m))}
+	for _, pk := range pubKeys {
+		s = append(s, pushElem(pk.Sec(true)))
+	}
+
+	return append(s, numElem(int64(n)), Elem{Opcode: OP_CHECKMULTISIG}), nil
+}
+
+// NullData returns the provably unspendable script OP_RETURN <data>. Outputs
+// with more than 80 bytes of data are not relayed by default.
+func NullData(data []byte) Script {
+	s := Script{{Opcode: OP_RETURN}}
+	if len(data) == 0 {
+		return s
+	}
+
+	return append(s, pushElem(data))
+}
+
+// Classification is the result of matching a script against the standard
+// templates. Only the fields relevant to the Type are set.
+type Classification struct {
+	Type Type
+
+	// Hash is the hash committed to by P2PKH, P2SH, P2WPKH and P2WSH
+	// scripts.
+	Hash []byte
+
+	// PubKeys holds the key of a P2PK script or the keys of a multisig
+	// script as they are encoded in the script.
+	PubKeys [][]byte
+
+	// RequiredSigs is the number of signatures required by a multisig
+	// script.
+	RequiredSigs int
+
+	// WitnessVersion and WitnessProgram are set for all witness
+	// programs. For P2TR the program is the x-only output key.
+	WitnessVersion int
+	WitnessProgram []byte
+
+	// Data holds the pushes that follow OP_RETURN in a null data script.
+	Data [][]byte
+}
+
+// Classify matches the script against the standard templates and extracts the
+// data they hold.
+func Classify(s Script) *Classification {
+	if version, program, ok := s.witnessProgram(); ok {
+		c := &Classification{
+			Type:           TypeWitnessUnknown,
+			WitnessVersion: version,
+			WitnessProgram: program,
+		}
+
+		switch {
+		case version == 0 && len(program) == hash160Len:
+			c.Type = TypeP2WPKH
+			c.Hash = program
+
+		case version == 0 && len(program) == sha256Len:
+			c.Type = TypeP2WSH
+			c.Hash = program
+
+		case version == 0:
+			c.Type = TypeNonStandard
+
+		case version == 1 && len(program) == 32:
+			c.Type = TypeP2TR
+		}
+
+		return c
+	}
+
+	switch {
+	case s.isP2SH():
+		return &Classification{Type: TypeP2SH, Hash: s[1].Data}
+
+	case isP2PKH(s):
+		return &Classification{Type: TypeP2PKH, Hash: s[2].Data}
+
+	case len(s) == 2 && isPubKeyPush(s[0]) && s[1].Opcode == OP_CHECKSIG:
+		return &Classification{
+			Type:    TypeP2PK,
+			PubKeys: [][]byte{s[0].Data},
+		}
+	}
+
+	// Like Bitcoin Core's Solver, this matches multisig scripts of up to
+	// 16 keys. The standardness limit of 3 keys for bare multisig outputs
+	// is left to the caller.
+	if m, keys, ok := matchMultisig(s); ok {
+		return &Classification{
+			Type:         TypeMultisig,
+			PubKeys:      keys,
+			RequiredSigs: m,
+		}
+	}
+
+	if len(s) > 0 && s[0].Opcode == OP_RETURN && s[1:].IsPushOnly() {
+		c := &Classification{Type: TypeNullData}
+		for _, e := range s[1:] {
+			c.Data = append(c.Data, e.Data)
+		}
+
+		return c
+	}
+
+	return &Classification{Type: TypeNonStandard}
+}
+
+func isP2PKH(s Script) bool {
+	return len(s) == 5 &&
+		s[0].Opcode == OP_DUP &&
+		s[1].Opcode == OP_HASH160 &&
+		s[2].Opcode == OP_DATA_20 && len(s[2].Data) == hash160Len &&
+		s[3].Opcode == OP_EQUALVERIFY &&
+		s[4].Opcode == OP_CHECKSIG
+}
+
+// isPubKeyPush returns true if the element directly pushes data that looks
+// like a SEC encoded public key: 33 bytes starting with 0x02 or 0x03, or 65
+// bytes starting with 0x04 or one of the hybrid prefixes 0x06 and 0x07. The
+// key is not checked to be on the curve.
+func isPubKeyPush(e Elem) bool {
+	if int(e.Opcode) != len(e.Data) || len(e.Data) == 0 {
+		return false
+	}
+
+	switch e.Data[0] {
+	case 0x02, 0x03:
+		return len(e.Data) == s256point.PubKeyBytesLenCompressed
+	case 0x04, 0x06, 0x07:
+		return len(e.Data) == s256point.PubKeyBytesLenUncompressed
+	}
+
+	return false
+}
+
+// matchMultisig matches OP_m <pubkeys> OP_n OP_CHECKMULTISIG.
+func matchMultisig(s Script) (int, [][]byte, bool) {
+	if len(s) < 4 || s[len(s)-1].Opcode != OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+
+	m, ok := smallInt(s[0])
+	if !ok || m < 1 {
+		return 0, nil, false
+	}
+
+	n, ok := smallInt(s[len(s)-2])
+	if !ok || n < m || n != len(s)-3 {
+		return 0, nil, false
+	}
+
+	keys := make([][]byte, 0, n)
+	for _, e := range s[1 : len(s)-2] {
+		if !isPubKeyPush(e) {
+			return 0, nil, false
+		}
+		keys = append(keys, e.Data)
+	}
+
+	return m, keys, true
+}
+
+// smallInt returns the value of an OP_0 to OP_16 opcode.
+func smallInt(e Elem) (int, bool) {
+	switch {
+	case e.Opcode == OP_0:
+		return 0, true
+	case e.Opcode >= OP_1 && e.Opcode <= OP_16:
+		return int(e.Opcode-OP_1) + 1, true
+	}
+
+	return 0, false
+}
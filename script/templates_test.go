@@ -0,0 +1,202 @@
+package script_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/stretchr/testify/require"
+)
+
+func rawHex(t *testing.T, s script.Script) string {
+	b, err := s.SerializeRaw()
+	require.NoError(t, err)
+
+	return hex.EncodeToString(b)
+}
+
+func TestTemplates(t *testing.T) {
+	var keys []*s256point.S256Point
+	for i := int64(1); i <= 3; i++ {
+		priv, err := privatekey.New(big.NewInt(i))
+		require.NoError(t, err)
+		keys = append(keys, priv.PubKey)
+	}
+
+	const (
+		g    = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+		g2   = "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+		gh   = "751e76e8199196d454941c45d1b3a323f1433bd6"
+		wsh  = "1863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"
+		tapk = "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+	)
+
+	hash, err := hex.DecodeString(gh)
+	require.NoError(t, err)
+	require.Equal(t, hash, helpers.Hash160(keys[0].Sec(true)))
+
+	p2pk := script.P2PK(keys[0], true)
+	require.Equal(t, "21"+g+"ac", rawHex(t, p2pk))
+
+	p2pkh, err := script.P2PKH(hash)
+	require.NoError(t, err)
+	require.Equal(t, "76a914"+gh+"88ac", rawHex(t, p2pkh))
+
+	p2wpkh, err := script.P2WPKH(hash)
+	require.NoError(t, err)
+	require.Equal(t, "0014"+gh, rawHex(t, p2wpkh))
+
+	// The BIP173 P2WSH test vector commits to the P2PK script of G.
+	p2wsh, err := script.P2WSH(p2pk)
+	require.NoError(t, err)
+	require.Equal(t, "0020"+wsh, rawHex(t, p2wsh))
+
+	multisig, err := script.Multisig(1, keys[:2])
+	require.NoError(t, err)
+	require.Equal(t, "5121"+g+"21"+g2+"52ae", rawHex(t, multisig))
+
+	p2sh, err := script.P2SH(multisig)
+	require.NoError(t, err)
+	ms, err := multisig.SerializeRaw()
+	require.NoError(t, err)
+	require.Equal(t, "a914"+hex.EncodeToString(helpers.Hash160(ms))+"87",
+		rawHex(t, p2sh))
+
+	outputKey, err := hex.DecodeString(tapk)
+	require.NoError(t, err)
+	p2tr, err := script.P2TR(outputKey)
+	require.NoError(t, err)
+	require.Equal(t, "5120"+tapk, rawHex(t, p2tr))
+
+	nullData := script.NullData([]byte("hello"))
+	require.Equal(t, "6a0568656c6c6f", rawHex(t, nullData))
+
+	tests := []struct {
+		name   string
+		script script.Script
+		want   script.Classification
+	}{
+		{
+			name:   "p2pk",
+			script: p2pk,
+			want: script.Classification{
+				Type:    script.TypeP2PK,
+				PubKeys: [][]byte{keys[0].Sec(true)},
+			},
+		},
+		{
+			name:   "p2pk uncompressed",
+			script: script.P2PK(keys[1], false),
+			want: script.Classification{
+				Type:    script.TypeP2PK,
+				PubKeys: [][]byte{keys[1].Sec(false)},
+			},
+		},
+		{
+			name:   "p2pkh",
+			script: p2pkh,
+			want: script.Classification{
+				Type: script.TypeP2PKH,
+				Hash: hash,
+			},
+		},
+		{
+			name:   "p2sh",
+			script: p2sh,
+			want: script.Classification{
+				Type: script.TypeP2SH,
+				Hash: helpers.Hash160(ms),
+			},
+		},
+		{
+			name:   "p2wpkh",
+			script: p2wpkh,
+			want: script.Classification{
+				Type:           script.TypeP2WPKH,
+				Hash:           hash,
+				WitnessProgram: hash,
+			},
+		},
+		{
+			name:   "p2wsh",
+			script: p2wsh,
+			want: script.Classification{
+				Type:           script.TypeP2WSH,
+				Hash:           p2wsh[1].Data,
+				WitnessProgram: p2wsh[1].Data,
+			},
+		},
+		{
+			name:   "p2tr",
+			script: p2tr,
+			want: script.Classification{
+				Type:           script.TypeP2TR,
+				WitnessVersion: 1,
+				WitnessProgram: outputKey,
+			},
+		},
+		{
+			name:   "future witness version",
+			script: parseRaw(t, "5202aabb"),
+			want: script.Classification{
+				Type:           script.TypeWitnessUnknown,
+				WitnessVersion: 2,
+				WitnessProgram: []byte{0xaa, 0xbb},
+			},
+		},
+		{
+			name:   "multisig",
+			script: multisig,
+			want: script.Classification{
+				Type:         script.TypeMultisig,
+				PubKeys:      [][]byte{keys[0].Sec(true), keys[1].Sec(true)},
+				RequiredSigs: 1,
+			},
+		},
+		{
+			name:   "null data",
+			script: nullData,
+			want: script.Classification{
+				Type: script.TypeNullData,
+				Data: [][]byte{[]byte("hello")},
+			},
+		},
+		{
+			name:   "multisig with too few keys",
+			script: parseRaw(t, "5221"+g+"52ae"),
+			want:   script.Classification{Type: script.TypeNonStandard},
+		},
+		{
+			name:   "op_return followed by non-push",
+			script: parseRaw(t, "6a76"),
+			want:   script.Classification{Type: script.TypeNonStandard},
+		},
+		{
+			name:   "v0 program of bad length",
+			script: parseRaw(t, "0003aabbcc"),
+			want: script.Classification{
+				Type:           script.TypeNonStandard,
+				WitnessProgram: []byte{0xaa, 0xbb, 0xcc},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, &test.want, script.Classify(test.script))
+		})
+	}
+
+	_, err = script.P2PKH(hash[1:])
+	require.Error(t, err)
+	_, err = script.Multisig(3, keys[:2])
+	require.Error(t, err)
+	_, err = script.Multisig(0, keys)
+	require.Error(t, err)
+	_, err = script.WitnessProgram(17, hash)
+	require.Error(t, err)
+}
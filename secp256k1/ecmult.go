@@ -0,0 +1,325 @@
+package secp256k1
+
+import "math/bits"
+
+// The functions in this file run in variable time and must only be used with
+// public scalars and points, such as when verifying signatures.
+
+const (
+	// gWindow is the wNAF window width used for the generator, whose
+	// tables are computed once. pointWindow is used for arbitrary points
+	// whose tables are built on every call.
+	gWindow     = 8
+	pointWindow = 5
+)
+
+// secp256k1 has an efficiently computable endomorphism: for the cube root of
+// unity beta modulo P and the matching cube root of unity lambda modulo N,
+// lambda*(x, y) = (beta*x, y). The GLV method uses it to split a scalar k into
+// two halves of about 128 bits each with k = k1 + k2*lambda, which halves the
+// number of doublings needed to compute k*P.
+var (
+	glvLambda = Scalar{n: [4]uint64{
+		0xdf02967c1b23bd72, 0x122e22ea20816678, 0xa5261c028812645a,
+		0x5363ad4cc05c30e0,
+	}}
+	glvBeta = FieldVal{n: [4]uint64{
+		0xc1396c28719501ee, 0x9cf0497512f58995, 0x6e64479eac3434e9,
+		0x7ae96a2b657c0710,
+	}}
+
+	// glvMinusB1 and glvMinusB2 are -b1 and -b2 modulo N for the short
+	// lattice basis (a1, b1), (a2, b2) of the kernel of
+	// (i, j) -> i + j*lambda.
+	glvMinusB1 = Scalar{n: [4]uint64{
+		0x6f547fa90abfe4c3, 0xe4437ed6010e8828, 0, 0,
+	}}
+	glvMinusB2 = Scalar{n: [4]uint64{
+		0xd765cda83db1562c, 0x8a280ac50774346d, 0xfffffffffffffffe,
+		0xffffffffffffffff,
+	}}
+
+	// glvG1 and glvG2 are round(2^384 * b2 / N) and
+	// round(2^384 * -b1 / N), which turn the divisions by N needed to
+	// split a scalar into multiplications.
+	glvG1 = [4]uint64{
+		0xe893209a45dbb031, 0x3daa8a1471e8ca7f, 0xe86c90e49284eb15,
+		0x3086d221a7d46bcd,
+	}
+	glvG2 = [4]uint64{
+		0x1571b4ae8ac47f71, 0x221208ac9df506c6, 0x6f547fa90abfe4c4,
+		0xe4437ed6010e8828,
+	}
+)
+
+// splitScalar returns k1 and k2 such that k = k1 + k2*lambda mod N. Both are
+// small in absolute value, so either may be the negation of a number below
+// 2^128.
+func splitScalar(k *Scalar) (Scalar, Scalar) {
+	// With c1 = round(b2*k/N) and c2 = round(-b1*k/N),
+	// k2 = -c1*b1 - c2*b2 and k1 = k - k2*lambda.
+	c1 := mulShift384(k, &glvG1)
+	c2 := mulShift384(k, &glvG2)
+
+	var k1, k2 Scalar
+	c1.Mul(&c1, &glvMinusB1)
+	c2.Mul(&c2, &glvMinusB2)
+	k2.Add(&c1, &c2)
+
+	k1.Mul(&k2, &glvLambda)
+	k1.Sub(k, &k1)
+
+	return k1, k2
+}
+
+// mulShift384 returns k*g / 2^384 rounded to the nearest integer.
+func mulShift384(k *Scalar, g *[4]uint64) Scalar {
+	t := mul512(&k.n, g)
+
+	var (
+		r Scalar
+		c uint64
+	)
+	r.n[0], c = bits.Add64(t[6], t[5]>>63, 0)
+	r.n[1] = t[7] + c
+
+	return r
+}
+
+// endomorphism sets p to lambda*a.
+func (p *JacobianPoint) endomorphism(a *JacobianPoint) *JacobianPoint {
+	p.X.Mul(&a.X, &glvBeta)
+	p.Y.Set(&a.Y)
+	p.Z.Set(&a.Z)
+
+	return p
+}
+
+// wnaf returns the width w non-adjacent form of k, least significant digit
+// first. Every non-zero digit is odd and less than 2^(w-1) in absolute value,
+// and any w consecutive digits contain at most one non-zero digit.
+func wnaf(k *Scalar, w uint) []int8 {
+	// One extra limb holds the carry out of the top bit when a negative
+	// digit is taken off.
+	var n [5]uint64
+	copy(n[:4], k.n[:])
+
+	naf := make([]int8, 0, 257)
+	for n != [5]uint64{} {
+		var d int64
+		if n[0]&1 == 1 {
+			d = int64(n[0] & (1<<w - 1))
+			if d >= 1<<(w-1) {
+				d -= 1 << w
+			}
+
+			// Subtract the digit, which leaves at least w low zero
+			// bits.
+			if d > 0 {
+				var b uint64
+				n[0], b = bits.Sub64(n[0], uint64(d), 0)
+				for i := 1; i < len(n); i++ {
+					n[i], b = bits.Sub64(n[i], 0, b)
+				}
+			} else {
+				var c uint64
+				n[0], c = bits.Add64(n[0], uint64(-d), 0)
+				for i := 1; i < len(n); i++ {
+					n[i], c = bits.Add64(n[i], 0, c)
+				}
+			}
+		}
+		naf = append(naf, int8(d))
+
+		for i := 0; i < len(n)-1; i++ {
+			n[i] = n[i]>>1 | n[i+1]<<63
+		}
+		n[len(n)-1] >>= 1
+	}
+
+	return naf
+}
+
+// oddMultiples returns a, 3a, 5a, ..., (2^(w-1) - 1)a.
+func oddMultiples(a *JacobianPoint, w uint) []JacobianPoint {
+	table := make([]JacobianPoint, 1<<(w-2))
+	table[0] = *a
+
+	var a2 JacobianPoint
+	a2.Double(a)
+	for i := 1; i < len(table); i++ {
+		table[i].addVarTime(&table[i-1], &a2)
+	}
+
+	return table
+}
+
+// wnafTerm is one k*P term of a multi-scalar multiplication. The table holds
+// the odd multiples of P, and the whole term is negated if neg is set.
+type wnafTerm struct {
+	naf   []int8
+	table []JacobianPoint
+	neg   bool
+}
+
+// glvTerms splits k*a into the two terms k1*a + k2*(lambda*a), whose scalars
+// are half the length of k. The tables are the odd multiples of a and of
+// lambda*a.
+func glvTerms(k *Scalar, table, lambdaTable []JacobianPoint,
+	w uint) [2]wnafTerm {
+
+	k1, k2 := splitScalar(k)
+
+	neg1, neg2 := k1.IsHigh(), k2.IsHigh()
+	k1.CondNeg(neg1)
+	k2.CondNeg(neg2)
+
+	return [2]wnafTerm{
+		{naf: wnaf(&k1, w), table: table, neg: neg1},
+		{naf: wnaf(&k2, w), table: lambdaTable, neg: neg2},
+	}
+}
+
+// DoubleScalarMultVarTime sets p to u*G + v*a. It is used to verify
+// signatures and is not constant time.
+func (p *JacobianPoint) DoubleScalarMultVarTime(u, v *Scalar,
+	a *JacobianPoint) *JacobianPoint {
+
+	return p.MultiScalarMultVarTime(u, []Scalar{*v}, []JacobianPoint{*a})
+}
+
+// MultiScalarMultVarTime sets p to u*G + scalars[0]*points[0] + ... +
+// scalars[n]*points[n] using Strauss' method: every scalar is split in two
+// with the GLV endomorphism and written in wNAF, and all of the terms then
+// share a single chain of about 128 doublings. The multiples of G come from
+// precomputed tables. It is not constant time and panics if the number of
+// scalars and points differ.
+func (p *JacobianPoint) MultiScalarMultVarTime(u *Scalar, scalars []Scalar,
+	points []JacobianPoint) *JacobianPoint {
+
+	if len(scalars) != len(points) {
+		panic("secp256k1: number of scalars and points must match")
+	}
+
+	tables := precomputed()
+
+	terms := make([]wnafTerm, 0, 2*len(points)+2)
+	if !u.IsZero() {
+		t := glvTerms(u, tables.gOdd[:], tables.gLambdaOdd[:], gWindow)
+		terms = append(terms, t[:]...)
+	}
+
+	for i := range points {
+		if scalars[i].IsZero() || points[i].IsInfinity() {
+			continue
+		}
+
+		table := oddMultiples(&points[i], pointWindow)
+		lambdaTable := make([]JacobianPoint, len(table))
+		for j := range table {
+			lambdaTable[j].endomorphism(&table[j])
+		}
+
+		t := glvTerms(&scalars[i], table, lambdaTable, pointWindow)
+		terms = append(terms, t[:]...)
+	}
+
+	var maxLen int
+	for _, t := range terms {
+		if len(t.naf) > maxLen {
+			maxLen = len(t.naf)
+		}
+	}
+
+	var acc, neg JacobianPoint
+	for i := maxLen - 1; i >= 0; i-- {
+		acc.Double(&acc)
+
+		for _, t := range terms {
+			if i >= len(t.naf) || t.naf[i] == 0 {
+				continue
+			}
+
+			d := t.naf[i]
+			q := &t.table[abs8(d)/2]
+			if (d < 0) != t.neg {
+				q = neg.Neg(q)
+			}
+			acc.addVarTime(&acc, q)
+		}
+	}
+
+	*p = acc
+
+	return p
+}
+
+// addVarTime sets p to a + b like Add, but branches on the special cases
+// instead of computing every outcome.
+func (p *JacobianPoint) addVarTime(a, b *JacobianPoint) *JacobianPoint {
+	switch {
+	case a.IsInfinity():
+		*p = *b
+		return p
+
+	case b.IsInfinity():
+		*p = *a
+		return p
+	}
+
+	var z1z1, z2z2, u1, u2, s1, s2, h, i, j, r, v, t FieldVal
+	z1z1.Square(&a.Z)
+	z2z2.Square(&b.Z)
+	u1.Mul(&a.X, &z2z2)
+	u2.Mul(&b.X, &z1z1)
+	s1.Mul(&a.Y, &b.Z)
+	s1.Mul(&s1, &z2z2)
+	s2.Mul(&b.Y, &a.Z)
+	s2.Mul(&s2, &z1z1)
+
+	h.Sub(&u2, &u1)
+	r.Sub(&s2, &s1)
+	if h.IsZero() {
+		if r.IsZero() {
+			return p.Double(a)
+		}
+
+		*p = JacobianPoint{}
+		return p
+	}
+
+	i.Add(&h, &h)
+	i.Square(&i)
+	j.Mul(&h, &i)
+	r.Add(&r, &r)
+	v.Mul(&u1, &i)
+
+	var sum JacobianPoint
+	sum.X.Square(&r)
+	sum.X.Sub(&sum.X, &j)
+	sum.X.Sub(&sum.X, t.Add(&v, &v))
+
+	sum.Y.Sub(&v, &sum.X)
+	sum.Y.Mul(&sum.Y, &r)
+	t.Mul(&s1, &j)
+	t.Add(&t, &t)
+	sum.Y.Sub(&sum.Y, &t)
+
+	sum.Z.Add(&a.Z, &b.Z)
+	sum.Z.Square(&sum.Z)
+	sum.Z.Sub(&sum.Z, &z1z1)
+	sum.Z.Sub(&sum.Z, &z2z2)
+	sum.Z.Mul(&sum.Z, &h)
+
+	*p = sum
+
+	return p
+}
+
+func abs8(d int8) int {
+	if d < 0 {
+		return -int(d)
+	}
+
+	return int(d)
+}
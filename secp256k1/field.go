@@ -0,0 +1,297 @@
+package secp256k1
+
+import "math/bits"
+
+// FieldVal is an element of the field of integers modulo the secp256k1 prime
+// P = 2^256 - 2^32 - 977. It is stored as four 64 bit limbs in little endian
+// order and is always fully reduced. None of the operations branch on or
+// index memory with the value, so they are safe to use on secrets.
+//
+// Methods follow the conventions of math/big: the receiver holds the result
+// and is returned so that calls can be chained. The zero value is zero.
+type FieldVal struct {
+	n [4]uint64
+}
+
+// fieldP holds the limbs of P.
+var fieldP = [4]uint64{
+	0xfffffffefffffc2f, 0xffffffffffffffff, 0xffffffffffffffff,
+	0xffffffffffffffff,
+}
+
+// fieldC is 2^256 mod P, which is used to fold the high limbs of a product
+// back into the low ones.
+const fieldC = 0x1000003d1
+
+// fieldPMinus2 and fieldSqrtExp are the exponents used for inversion and
+// square roots.
+var (
+	fieldPMinus2 = [4]uint64{
+		0xfffffffefffffc2d, 0xffffffffffffffff, 0xffffffffffffffff,
+		0xffffffffffffffff,
+	}
+	fieldSqrtExp = [4]uint64{
+		0xffffffffbfffff0c, 0xffffffffffffffff, 0xffffffffffffffff,
+		0x3fffffffffffffff,
+	}
+)
+
+// SetInt sets f to v.
+func (f *FieldVal) SetInt(v uint64) *FieldVal {
+	f.n = [4]uint64{v, 0, 0, 0}
+	return f
+}
+
+// Set sets f to a.
+func (f *FieldVal) Set(a *FieldVal) *FieldVal {
+	f.n = a.n
+	return f
+}
+
+// SetBytes sets f to the 32 byte big endian integer b reduced modulo P. It
+// returns true if b was not less than P.
+func (f *FieldVal) SetBytes(b *[32]byte) bool {
+	f.n = loadBytes(b)
+	return f.reduce(0) != 0
+}
+
+// SetByteSlice is like SetBytes but accepts up to 32 bytes. Shorter inputs
+// are treated as if they were padded with leading zeros.
+func (f *FieldVal) SetByteSlice(b []byte) bool {
+	var buf [32]byte
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	copy(buf[32-len(b):], b)
+
+	return f.SetBytes(&buf)
+}
+
+// Bytes returns f as a 32 byte big endian integer.
+func (f *FieldVal) Bytes() [32]byte {
+	return storeBytes(&f.n)
+}
+
+// IsZero returns true if f is zero.
+func (f *FieldVal) IsZero() bool {
+	return isZeroMask(&f.n) != 0
+}
+
+// IsOdd returns true if f is odd.
+func (f *FieldVal) IsOdd() bool {
+	return f.n[0]&1 == 1
+}
+
+// Equals returns true if f and a are equal.
+func (f *FieldVal) Equals(a *FieldVal) bool {
+	return equalMask(&f.n, &a.n) != 0
+}
+
+// Add sets f to a + b.
+func (f *FieldVal) Add(a, b *FieldVal) *FieldVal {
+	var c uint64
+	f.n[0], c = bits.Add64(a.n[0], b.n[0], 0)
+	f.n[1], c = bits.Add64(a.n[1], b.n[1], c)
+	f.n[2], c = bits.Add64(a.n[2], b.n[2], c)
+	f.n[3], c = bits.Add64(a.n[3], b.n[3], c)
+	f.reduce(c)
+
+	return f
+}
+
+// Sub sets f to a - b.
+func (f *FieldVal) Sub(a, b *FieldVal) *FieldVal {
+	var borrow uint64
+	f.n[0], borrow = bits.Sub64(a.n[0], b.n[0], 0)
+	f.n[1], borrow = bits.Sub64(a.n[1], b.n[1], borrow)
+	f.n[2], borrow = bits.Sub64(a.n[2], b.n[2], borrow)
+	f.n[3], borrow = bits.Sub64(a.n[3], b.n[3], borrow)
+
+	// Add P back if the subtraction wrapped around.
+	mask := -borrow
+	var c uint64
+	f.n[0], c = bits.Add64(f.n[0], fieldP[0]&mask, 0)
+	f.n[1], c = bits.Add64(f.n[1], fieldP[1]&mask, c)
+	f.n[2], c = bits.Add64(f.n[2], fieldP[2]&mask, c)
+	f.n[3], _ = bits.Add64(f.n[3], fieldP[3]&mask, c)
+
+	return f
+}
+
+// Neg sets f to -a.
+func (f *FieldVal) Neg(a *FieldVal) *FieldVal {
+	var zero FieldVal
+	return f.Sub(&zero, a)
+}
+
+// Mul sets f to a * b.
+func (f *FieldVal) Mul(a, b *FieldVal) *FieldVal {
+	t := mul512(&a.n, &b.n)
+
+	// t = lo + hi*2^256 = lo + hi*fieldC (mod P). The result has at most
+	// 256 + 34 bits.
+	var r [4]uint64
+	var carry uint64
+	for i := 0; i < 4; i++ {
+		hi, lo := bits.Mul64(t[4+i], fieldC)
+
+		var c uint64
+		lo, c = bits.Add64(lo, t[i], 0)
+		hi += c
+		lo, c = bits.Add64(lo, carry, 0)
+		hi += c
+
+		r[i] = lo
+		carry = hi
+	}
+
+	// Fold the top limb in the same way.
+	hi, lo := bits.Mul64(carry, fieldC)
+	var c uint64
+	r[0], c = bits.Add64(r[0], lo, 0)
+	r[1], c = bits.Add64(r[1], hi, c)
+	r[2], c = bits.Add64(r[2], 0, c)
+	r[3], c = bits.Add64(r[3], 0, c)
+
+	f.n = r
+	f.reduce(c)
+
+	return f
+}
+
+// Square sets f to a^2.
+func (f *FieldVal) Square(a *FieldVal) *FieldVal {
+	return f.Mul(a, a)
+}
+
+// Inverse sets f to the multiplicative inverse of a, computed as a^(P-2).
+// The inverse of zero is zero.
+func (f *FieldVal) Inverse(a *FieldVal) *FieldVal {
+	return f.pow(a, &fieldPMinus2)
+}
+
+// Sqrt sets f to a square root of a and returns true if one exists. Since
+// P = 3 mod 4 the root is a^((P+1)/4). If a is not a square f is left with
+// an unspecified value.
+func (f *FieldVal) Sqrt(a *FieldVal) bool {
+	var r, check FieldVal
+	r.pow(a, &fieldSqrtExp)
+	check.Square(&r)
+	f.Set(&r)
+
+	return check.Equals(a)
+}
+
+// pow sets f to a^e. The exponent is public so the sequence of operations
+// only depends on it and not on a.
+func (f *FieldVal) pow(a *FieldVal, e *[4]uint64) *FieldVal {
+	var base, res FieldVal
+	base.Set(a)
+	res.SetInt(1)
+
+	for i := 255; i >= 0; i-- {
+		res.Square(&res)
+		if (e[i/64]>>(uint(i)%64))&1 == 1 {
+			res.Mul(&res, &base)
+		}
+	}
+
+	return f.Set(&res)
+}
+
+// reduce reduces f + carry*2^256 modulo P, where the value is less than 2P.
+// It returns 1 if P was subtracted.
+func (f *FieldVal) reduce(carry uint64) uint64 {
+	// Fold the carry in as carry*fieldC. This can't overflow since the
+	// value was less than 2P.
+	var c uint64
+	f.n[0], c = bits.Add64(f.n[0], carry*fieldC, 0)
+	f.n[1], c = bits.Add64(f.n[1], 0, c)
+	f.n[2], c = bits.Add64(f.n[2], 0, c)
+	f.n[3], _ = bits.Add64(f.n[3], 0, c)
+
+	return condSub(&f.n, &fieldP) | carry
+}
+
+// cmov sets f to a if flag is 1 and leaves it unchanged if flag is 0.
+func (f *FieldVal) cmov(a *FieldVal, flag uint64) {
+	mask := -flag
+	for i := range f.n {
+		f.n[i] ^= (f.n[i] ^ a.n[i]) & mask
+	}
+}
+
+// mul512 returns the 512 bit product of a and b.
+func mul512(a, b *[4]uint64) [8]uint64 {
+	var t [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(a[i], b[j])
+
+			var c uint64
+			lo, c = bits.Add64(lo, t[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+
+			t[i+j] = lo
+			carry = hi
+		}
+		t[i+4] = carry
+	}
+
+	return t
+}
+
+// condSub subtracts m from n if n >= m and returns 1 if it did.
+func condSub(n, m *[4]uint64) uint64 {
+	var t [4]uint64
+	var borrow uint64
+	t[0], borrow = bits.Sub64(n[0], m[0], 0)
+	t[1], borrow = bits.Sub64(n[1], m[1], borrow)
+	t[2], borrow = bits.Sub64(n[2], m[2], borrow)
+	t[3], borrow = bits.Sub64(n[3], m[3], borrow)
+
+	// Keep the difference if there was no borrow.
+	mask := borrow - 1
+	for i := range n {
+		n[i] ^= (n[i] ^ t[i]) & mask
+	}
+
+	return 1 ^ borrow
+}
+
+// isZeroMask returns 1 if all limbs are zero and 0 otherwise.
+func isZeroMask(n *[4]uint64) uint64 {
+	x := n[0] | n[1] | n[2] | n[3]
+	return 1 ^ ((x | -x) >> 63)
+}
+
+// equalMask returns 1 if a and b are equal and 0 otherwise.
+func equalMask(a, b *[4]uint64) uint64 {
+	d := [4]uint64{a[0] ^ b[0], a[1] ^ b[1], a[2] ^ b[2], a[3] ^ b[3]}
+	return isZeroMask(&d)
+}
+
+func loadBytes(b *[32]byte) [4]uint64 {
+	var n [4]uint64
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			n[3-i] = n[3-i]<<8 | uint64(b[i*8+j])
+		}
+	}
+
+	return n
+}
+
+func storeBytes(n *[4]uint64) [32]byte {
+	var b [32]byte
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			b[i*8+j] = byte(n[3-i] >> uint(56-8*j))
+		}
+	}
+
+	return b
+}
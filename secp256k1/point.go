@@ -0,0 +1,259 @@
+package secp256k1
+
+// JacobianPoint is a point on the secp256k1 curve y^2 = x^3 + 7 in Jacobian
+// coordinates, where (X, Y, Z) represents the affine point (X/Z^2, Y/Z^3).
+// Points with Z = 0 are the point at infinity. Working in these coordinates
+// avoids a field inversion for every addition.
+type JacobianPoint struct {
+	X, Y, Z FieldVal
+}
+
+// G is the generator of the secp256k1 group.
+var G = func() JacobianPoint {
+	var p JacobianPoint
+	p.X.SetBytes(&[32]byte{
+		0x79, 0xbe, 0x66, 0x7e, 0xf9, 0xdc, 0xbb, 0xac,
+		0x55, 0xa0, 0x62, 0x95, 0xce, 0x87, 0x0b, 0x07,
+		0x02, 0x9b, 0xfc, 0xdb, 0x2d, 0xce, 0x28, 0xd9,
+		0x59, 0xf2, 0x81, 0x5b, 0x16, 0xf8, 0x17, 0x98,
+	})
+	p.Y.SetBytes(&[32]byte{
+		0x48, 0x3a, 0xda, 0x77, 0x26, 0xa3, 0xc4, 0x65,
+		0x5d, 0xa4, 0xfb, 0xfc, 0x0e, 0x11, 0x08, 0xa8,
+		0xfd, 0x17, 0xb4, 0x48, 0xa6, 0x85, 0x54, 0x19,
+		0x9c, 0x47, 0xd0, 0x8f, 0xfb, 0x10, 0xd4, 0xb8,
+	})
+	p.Z.SetInt(1)
+
+	return p
+}()
+
+// NewAffinePoint returns the point with the given affine coordinates. It
+// returns false if the coordinates are not on the curve.
+func NewAffinePoint(x, y *FieldVal) (*JacobianPoint, bool) {
+	p := &JacobianPoint{}
+	p.X.Set(x)
+	p.Y.Set(y)
+	p.Z.SetInt(1)
+
+	return p, p.isOnCurve()
+}
+
+// isOnCurve checks y^2 = x^3 + 7 for a point with Z = 1.
+func (p *JacobianPoint) isOnCurve() bool {
+	var lhs, rhs, seven FieldVal
+	lhs.Square(&p.Y)
+	rhs.Square(&p.X)
+	rhs.Mul(&rhs, &p.X)
+	rhs.Add(&rhs, seven.SetInt(7))
+
+	return lhs.Equals(&rhs)
+}
+
+// IsInfinity returns true if p is the point at infinity.
+func (p *JacobianPoint) IsInfinity() bool {
+	return p.Z.IsZero()
+}
+
+// ToAffine converts p in place so that Z = 1 and returns false if p is the
+// point at infinity, in which case it is left unchanged.
+func (p *JacobianPoint) ToAffine() bool {
+	if p.IsInfinity() {
+		return false
+	}
+
+	var zInv, zInv2 FieldVal
+	zInv.Inverse(&p.Z)
+	zInv2.Square(&zInv)
+
+	p.X.Mul(&p.X, &zInv2)
+	p.Y.Mul(&p.Y, zInv2.Mul(&zInv2, &zInv))
+	p.Z.SetInt(1)
+
+	return true
+}
+
+// Double sets p to 2a.
+func (p *JacobianPoint) Double(a *JacobianPoint) *JacobianPoint {
+	// dbl-2009-l from the Explicit-Formulas Database, which only needs
+	// the curve's a coefficient to be zero. Doubling the point at
+	// infinity gives Z = 0 again, and there are no points with Y = 0.
+	var A, B, C, D, E, F, t FieldVal
+	A.Square(&a.X)
+	B.Square(&a.Y)
+	C.Square(&B)
+
+	// D = 2*((X+B)^2 - A - C)
+	D.Add(&a.X, &B)
+	D.Square(&D)
+	D.Sub(&D, &A)
+	D.Sub(&D, &C)
+	D.Add(&D, &D)
+
+	// E = 3*A, F = E^2
+	E.Add(&A, &A)
+	E.Add(&E, &A)
+	F.Square(&E)
+
+	var x3, y3, z3 FieldVal
+
+	// X3 = F - 2*D
+	x3.Sub(&F, t.Add(&D, &D))
+
+	// Y3 = E*(D - X3) - 8*C
+	y3.Sub(&D, &x3)
+	y3.Mul(&y3, &E)
+	t.Add(&C, &C)
+	t.Add(&t, &t)
+	t.Add(&t, &t)
+	y3.Sub(&y3, &t)
+
+	// Z3 = 2*Y*Z
+	z3.Mul(&a.Y, &a.Z)
+	z3.Add(&z3, &z3)
+
+	p.X, p.Y, p.Z = x3, y3, z3
+
+	return p
+}
+
+// Add sets p to a + b. All special cases, such as either point being the
+// point at infinity or the points being equal, are handled without
+// branching on the coordinates.
+func (p *JacobianPoint) Add(a, b *JacobianPoint) *JacobianPoint {
+	// add-2007-bl from the Explicit-Formulas Database.
+	var z1z1, z2z2, u1, u2, s1, s2, h, i, j, r, v, t FieldVal
+	z1z1.Square(&a.Z)
+	z2z2.Square(&b.Z)
+	u1.Mul(&a.X, &z2z2)
+	u2.Mul(&b.X, &z1z1)
+	s1.Mul(&a.Y, &b.Z)
+	s1.Mul(&s1, &z2z2)
+	s2.Mul(&b.Y, &a.Z)
+	s2.Mul(&s2, &z1z1)
+
+	// H = U2 - U1, I = (2*H)^2, J = H*I
+	h.Sub(&u2, &u1)
+	i.Add(&h, &h)
+	i.Square(&i)
+	j.Mul(&h, &i)
+
+	// r = 2*(S2 - S1), V = U1*I
+	r.Sub(&s2, &s1)
+	r.Add(&r, &r)
+	v.Mul(&u1, &i)
+
+	var sum JacobianPoint
+
+	// X3 = r^2 - J - 2*V
+	sum.X.Square(&r)
+	sum.X.Sub(&sum.X, &j)
+	sum.X.Sub(&sum.X, t.Add(&v, &v))
+
+	// Y3 = r*(V - X3) - 2*S1*J
+	sum.Y.Sub(&v, &sum.X)
+	sum.Y.Mul(&sum.Y, &r)
+	t.Mul(&s1, &j)
+	t.Add(&t, &t)
+	sum.Y.Sub(&sum.Y, &t)
+
+	// Z3 = ((Z1 + Z2)^2 - Z1Z1 - Z2Z2)*H
+	sum.Z.Add(&a.Z, &b.Z)
+	sum.Z.Square(&sum.Z)
+	sum.Z.Sub(&sum.Z, &z1z1)
+	sum.Z.Sub(&sum.Z, &z2z2)
+	sum.Z.Mul(&sum.Z, &h)
+
+	// If H and r are both zero the points are equal and the formula
+	// breaks down, so the double is used. If only H is zero then the
+	// points are inverses and Z3 is already zero.
+	var dbl JacobianPoint
+	dbl.Double(a)
+	sum.cmov(&dbl, isZeroMask(&h.n)&isZeroMask(&r.n))
+
+	// Adding the point at infinity returns the other point.
+	sum.cmov(b, isZeroMask(&a.Z.n))
+	sum.cmov(a, isZeroMask(&b.Z.n))
+
+	*p = sum
+
+	return p
+}
+
+// Neg sets p to -a.
+func (p *JacobianPoint) Neg(a *JacobianPoint) *JacobianPoint {
+	p.X.Set(&a.X)
+	p.Y.Neg(&a.Y)
+	p.Z.Set(&a.Z)
+
+	return p
+}
+
+// cmov sets p to a if flag is 1 and leaves it unchanged if flag is 0.
+func (p *JacobianPoint) cmov(a *JacobianPoint, flag uint64) {
+	p.X.cmov(&a.X, flag)
+	p.Y.cmov(&a.Y, flag)
+	p.Z.cmov(&a.Z, flag)
+}
+
+// ScalarMult sets p to k*a in constant time. The scalar is processed four
+// bits at a time, and the multiple of a for each group of bits is read from
+// a table by touching every entry so that the memory access pattern doesn't
+// depend on k.
+func (p *JacobianPoint) ScalarMult(k *Scalar, a *JacobianPoint) *JacobianPoint {
+	var table [16]JacobianPoint
+	table[1] = *a
+	for i := 2; i < 16; i++ {
+		table[i].Add(&table[i-1], a)
+	}
+
+	var acc JacobianPoint
+	for i := 63; i >= 0; i-- {
+		for j := 0; j < 4; j++ {
+			acc.Double(&acc)
+		}
+
+		d := k.nibble(i)
+
+		var q JacobianPoint
+		for idx := range table {
+			q.cmov(&table[idx], equalWord(uint64(idx), d))
+		}
+
+		acc.Add(&acc, &q)
+	}
+
+	*p = acc
+
+	return p
+}
+
+// ScalarBaseMult sets p to k*G in constant time. It adds up one entry of
+// the precomputed table for each group of four bits of k, so no doublings
+// are needed. Every entry of a row is touched when reading it, as in
+// ScalarMult.
+func (p *JacobianPoint) ScalarBaseMult(k *Scalar) *JacobianPoint {
+	tables := precomputed()
+
+	var acc JacobianPoint
+	for i := range tables.comb {
+		d := k.nibble(i)
+
+		var q JacobianPoint
+		for idx := range tables.comb[i] {
+			q.cmov(&tables.comb[i][idx], equalWord(uint64(idx), d))
+		}
+
+		acc.Add(&acc, &q)
+	}
+
+	*p = acc
+
+	return p
+}
+
+// equalWord returns 1 if a and b are equal and 0 otherwise.
+func equalWord(a, b uint64) uint64 {
+	x := a ^ b
+	return 1 ^ ((x | -x) >> 63)
+}
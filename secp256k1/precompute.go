@@ -0,0 +1,87 @@
+package secp256k1
+
+import "sync"
+
+// baseTables holds the multiples of G that are computed once on first use.
+type baseTables struct {
+	// comb[i][d] is d*16^i*G, so k*G is the sum of comb[i][nibble i of k]
+	// and needs no doublings at all. comb[i][0] is the point at infinity.
+	comb [64][16]JacobianPoint
+
+	// gOdd and gLambdaOdd are the odd multiples of G and lambda*G used
+	// by MultiScalarMultVarTime.
+	gOdd       [1 << (gWindow - 2)]JacobianPoint
+	gLambdaOdd [1 << (gWindow - 2)]JacobianPoint
+}
+
+var (
+	baseTablesOnce sync.Once
+	baseTablesVal  *baseTables
+)
+
+// precomputed returns the generator tables, computing them if needed. Every
+// point in them other than the point at infinity has Z = 1.
+func precomputed() *baseTables {
+	baseTablesOnce.Do(func() {
+		t := &baseTables{}
+
+		base := G
+		for i := range t.comb {
+			t.comb[i][1] = base
+			for d := 2; d < 16; d++ {
+				t.comb[i][d].addVarTime(&t.comb[i][d-1], &base)
+			}
+			base.addVarTime(&t.comb[i][15], &base)
+		}
+
+		copy(t.gOdd[:], oddMultiples(&G, gWindow))
+
+		pts := make([]*JacobianPoint, 0, 64*15+len(t.gOdd))
+		for i := range t.comb {
+			for d := 1; d < 16; d++ {
+				pts = append(pts, &t.comb[i][d])
+			}
+		}
+		for i := range t.gOdd {
+			pts = append(pts, &t.gOdd[i])
+		}
+		normalizeBatch(pts)
+
+		for i := range t.gOdd {
+			t.gLambdaOdd[i].endomorphism(&t.gOdd[i])
+		}
+
+		baseTablesVal = t
+	})
+
+	return baseTablesVal
+}
+
+// normalizeBatch converts all of the points to affine coordinates with a
+// single field inversion using Montgomery's trick. None of the points may be
+// the point at infinity.
+func normalizeBatch(pts []*JacobianPoint) {
+	// prods[i] is the product of the Z coordinates before point i.
+	prods := make([]FieldVal, len(pts))
+
+	var acc FieldVal
+	acc.SetInt(1)
+	for i, p := range pts {
+		prods[i] = acc
+		acc.Mul(&acc, &p.Z)
+	}
+	acc.Inverse(&acc)
+
+	for i := len(pts) - 1; i >= 0; i-- {
+		p := pts[i]
+
+		var zInv, zInv2 FieldVal
+		zInv.Mul(&acc, &prods[i])
+		acc.Mul(&acc, &p.Z)
+
+		zInv2.Square(&zInv)
+		p.X.Mul(&p.X, &zInv2)
+		p.Y.Mul(&p.Y, zInv2.Mul(&zInv2, &zInv))
+		p.Z.SetInt(1)
+	}
+}
@@ -0,0 +1,238 @@
+package secp256k1
+
+import "math/bits"
+
+// Scalar is an integer modulo the order N of the secp256k1 group, stored as
+// four 64 bit limbs in little endian order and always fully reduced. Like
+// FieldVal, none of its operations depend on the value in a way that leaks
+// timing, so it is used for private keys and nonces.
+type Scalar struct {
+	n [4]uint64
+}
+
+// orderN holds the limbs of N.
+var orderN = [4]uint64{
+	0xbfd25e8cd0364141, 0xbaaedce6af48a03b, 0xfffffffffffffffe,
+	0xffffffffffffffff,
+}
+
+// orderC holds the limbs of 2^256 - N, which is 2^256 mod N.
+var orderC = [3]uint64{0x402da1732fc9bebf, 0x4551231950b75fc4, 0x1}
+
+var (
+	orderNMinus2 = [4]uint64{
+		0xbfd25e8cd036413f, 0xbaaedce6af48a03b, 0xfffffffffffffffe,
+		0xffffffffffffffff,
+	}
+	orderHalf = [4]uint64{
+		0xdfe92f46681b20a0, 0x5d576e7357a4501d, 0xffffffffffffffff,
+		0x7fffffffffffffff,
+	}
+)
+
+// SetInt sets s to v.
+func (s *Scalar) SetInt(v uint64) *Scalar {
+	s.n = [4]uint64{v, 0, 0, 0}
+	return s
+}
+
+// Set sets s to a.
+func (s *Scalar) Set(a *Scalar) *Scalar {
+	s.n = a.n
+	return s
+}
+
+// SetBytes sets s to the 32 byte big endian integer b reduced modulo N. It
+// returns true if b was not less than N.
+func (s *Scalar) SetBytes(b *[32]byte) bool {
+	s.n = loadBytes(b)
+	return condSub(&s.n, &orderN) != 0
+}
+
+// SetByteSlice is like SetBytes but accepts up to 32 bytes. Shorter inputs
+// are treated as if they were padded with leading zeros.
+func (s *Scalar) SetByteSlice(b []byte) bool {
+	var buf [32]byte
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	copy(buf[32-len(b):], b)
+
+	return s.SetBytes(&buf)
+}
+
+// Bytes returns s as a 32 byte big endian integer.
+func (s *Scalar) Bytes() [32]byte {
+	return storeBytes(&s.n)
+}
+
+// IsZero returns true if s is zero.
+func (s *Scalar) IsZero() bool {
+	return isZeroMask(&s.n) != 0
+}
+
+// Equals returns true if s and a are equal.
+func (s *Scalar) Equals(a *Scalar) bool {
+	return equalMask(&s.n, &a.n) != 0
+}
+
+// IsHigh returns true if s is greater than N/2.
+func (s *Scalar) IsHigh() bool {
+	return s.isHighMask() != 0
+}
+
+func (s *Scalar) isHighMask() uint64 {
+	// N/2 - s borrows if s > N/2.
+	var borrow uint64
+	_, borrow = bits.Sub64(orderHalf[0], s.n[0], 0)
+	_, borrow = bits.Sub64(orderHalf[1], s.n[1], borrow)
+	_, borrow = bits.Sub64(orderHalf[2], s.n[2], borrow)
+	_, borrow = bits.Sub64(orderHalf[3], s.n[3], borrow)
+
+	return borrow
+}
+
+// Add sets s to a + b.
+func (s *Scalar) Add(a, b *Scalar) *Scalar {
+	var c uint64
+	s.n[0], c = bits.Add64(a.n[0], b.n[0], 0)
+	s.n[1], c = bits.Add64(a.n[1], b.n[1], c)
+	s.n[2], c = bits.Add64(a.n[2], b.n[2], c)
+	s.n[3], c = bits.Add64(a.n[3], b.n[3], c)
+
+	// Fold a carry in as 2^256 mod N. This can't overflow again since the
+	// sum was less than 2N.
+	var c2 uint64
+	s.n[0], c2 = bits.Add64(s.n[0], orderC[0]&-c, 0)
+	s.n[1], c2 = bits.Add64(s.n[1], orderC[1]&-c, c2)
+	s.n[2], c2 = bits.Add64(s.n[2], orderC[2]&-c, c2)
+	s.n[3], _ = bits.Add64(s.n[3], 0, c2)
+
+	condSub(&s.n, &orderN)
+
+	return s
+}
+
+// Neg sets s to -a.
+func (s *Scalar) Neg(a *Scalar) *Scalar {
+	var t [4]uint64
+	var borrow uint64
+	t[0], borrow = bits.Sub64(orderN[0], a.n[0], 0)
+	t[1], borrow = bits.Sub64(orderN[1], a.n[1], borrow)
+	t[2], borrow = bits.Sub64(orderN[2], a.n[2], borrow)
+	t[3], _ = bits.Sub64(orderN[3], a.n[3], borrow)
+
+	// N - 0 is N, which must be reduced to zero.
+	mask := -(1 ^ isZeroMask(&a.n))
+	for i := range t {
+		s.n[i] = t[i] & mask
+	}
+
+	return s
+}
+
+// CondNeg negates s if flag is true.
+func (s *Scalar) CondNeg(flag bool) *Scalar {
+	var neg Scalar
+	neg.Neg(s)
+	s.cmov(&neg, boolToUint(flag))
+
+	return s
+}
+
+// Sub sets s to a - b.
+func (s *Scalar) Sub(a, b *Scalar) *Scalar {
+	var neg Scalar
+	neg.Neg(b)
+
+	return s.Add(a, &neg)
+}
+
+// Mul sets s to a * b.
+func (s *Scalar) Mul(a, b *Scalar) *Scalar {
+	t := mul512(&a.n, &b.n)
+
+	// Repeatedly replace lo + hi*2^256 with lo + hi*(2^256 mod N). The
+	// high part shrinks by 127 bits each time, so after four rounds it is
+	// zero.
+	for round := 0; round < 4; round++ {
+		var r [8]uint64
+		copy(r[:4], t[:4])
+		for i := 0; i < 4; i++ {
+			mulAddWord(&r, i, t[4+i])
+		}
+		t = r
+	}
+
+	s.n = [4]uint64{t[0], t[1], t[2], t[3]}
+	condSub(&s.n, &orderN)
+
+	return s
+}
+
+// mulAddWord adds w * orderC * 2^(64*offset) to r.
+func mulAddWord(r *[8]uint64, offset int, w uint64) {
+	var carry uint64
+	for j := 0; j < 3; j++ {
+		hi, lo := bits.Mul64(w, orderC[j])
+
+		var c uint64
+		lo, c = bits.Add64(lo, r[offset+j], 0)
+		hi += c
+		lo, c = bits.Add64(lo, carry, 0)
+		hi += c
+
+		r[offset+j] = lo
+		carry = hi
+	}
+
+	for k := offset + 3; k < 8; k++ {
+		r[k], carry = bits.Add64(r[k], carry, 0)
+	}
+}
+
+// Square sets s to a^2.
+func (s *Scalar) Square(a *Scalar) *Scalar {
+	return s.Mul(a, a)
+}
+
+// Inverse sets s to the multiplicative inverse of a, computed as a^(N-2).
+// The inverse of zero is zero.
+func (s *Scalar) Inverse(a *Scalar) *Scalar {
+	var base, res Scalar
+	base.Set(a)
+	res.SetInt(1)
+
+	// The exponent is public so branching on its bits is fine.
+	for i := 255; i >= 0; i-- {
+		res.Square(&res)
+		if (orderNMinus2[i/64]>>(uint(i)%64))&1 == 1 {
+			res.Mul(&res, &base)
+		}
+	}
+
+	return s.Set(&res)
+}
+
+// cmov sets s to a if flag is 1 and leaves it unchanged if flag is 0.
+func (s *Scalar) cmov(a *Scalar, flag uint64) {
+	mask := -flag
+	for i := range s.n {
+		s.n[i] ^= (s.n[i] ^ a.n[i]) & mask
+	}
+}
+
+// nibble returns the i'th group of four bits of s, counting from the least
+// significant.
+func (s *Scalar) nibble(i int) uint64 {
+	return (s.n[i/16] >> (uint(i%16) * 4)) & 0xf
+}
+
+func boolToUint(b bool) uint64 {
+	var r uint64
+	if b {
+		r = 1
+	}
+
+	return r
+}
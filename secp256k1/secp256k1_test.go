@@ -0,0 +1,413 @@
+package secp256k1
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ellemouton/btc/point"
+	"github.com/ellemouton/btc/s256field"
+	"github.com/stretchr/testify/require"
+)
+
+// curveN is the group order. The s256point package builds on this one, so
+// its curve parameters can't be used here.
+var curveN, _ = new(big.Int).SetString(
+	"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16,
+)
+
+// refG returns the generator in the generic affine implementation in the
+// point package, which the results here are checked against.
+func refG(t *testing.T) point.Point {
+	a, err := s256field.New(big.NewInt(0))
+	require.NoError(t, err)
+
+	b, err := s256field.New(big.NewInt(7))
+	require.NoError(t, err)
+
+	g := G
+	x, y := affine(t, &g)
+
+	xf, err := s256field.New(x)
+	require.NoError(t, err)
+
+	yf, err := s256field.New(y)
+	require.NoError(t, err)
+
+	p, err := point.New(xf, yf, a, b)
+	require.NoError(t, err)
+
+	return p
+}
+
+// testValues returns random values below mod along with the edge cases
+// around zero and the modulus.
+func testValues(r *rand.Rand, mod *big.Int) []*big.Int {
+	one := big.NewInt(1)
+	vals := []*big.Int{
+		big.NewInt(0), big.NewInt(1), big.NewInt(2),
+		new(big.Int).Sub(mod, one),
+		new(big.Int).Sub(mod, big.NewInt(2)),
+		new(big.Int).Rsh(mod, 1),
+		new(big.Int).Add(new(big.Int).Rsh(mod, 1), one),
+	}
+
+	for i := 0; i < 50; i++ {
+		vals = append(vals, new(big.Int).Rand(r, mod))
+	}
+
+	return vals
+}
+
+func bytes32(i *big.Int) *[32]byte {
+	var b [32]byte
+	ib := i.Bytes()
+	copy(b[32-len(ib):], ib)
+
+	return &b
+}
+
+func requireBig(t *testing.T, expected, actual *big.Int) {
+	require.True(t, expected.Cmp(actual) == 0, "expected %v, got %v",
+		expected, actual)
+}
+
+func fieldFromBig(i *big.Int) *FieldVal {
+	var f FieldVal
+	f.SetBytes(bytes32(i))
+
+	return &f
+}
+
+func fieldToBig(f *FieldVal) *big.Int {
+	b := f.Bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+func scalarFromBig(i *big.Int) *Scalar {
+	var s Scalar
+	s.SetBytes(bytes32(i))
+
+	return &s
+}
+
+func scalarToBig(s *Scalar) *big.Int {
+	b := s.Bytes()
+	return new(big.Int).SetBytes(b[:])
+}
+
+func TestField(t *testing.T) {
+	p := s256field.P
+	r := rand.New(rand.NewSource(1))
+	vals := testValues(r, p)
+
+	mod := func(i *big.Int) *big.Int {
+		return i.Mod(i, p)
+	}
+
+	for _, a := range vals {
+		fa := fieldFromBig(a)
+		requireBig(t, a, fieldToBig(fa))
+
+		var res FieldVal
+		requireBig(t, mod(new(big.Int).Neg(a)), fieldToBig(res.Neg(fa)))
+		requireBig(t, mod(new(big.Int).Mul(a, a)),
+			fieldToBig(res.Square(fa)))
+
+		if a.Sign() != 0 {
+			requireBig(t, new(big.Int).ModInverse(a, p),
+				fieldToBig(res.Inverse(fa)))
+		}
+
+		ok := res.Sqrt(fa)
+		require.Equal(t, new(big.Int).ModSqrt(a, p) != nil, ok)
+		if ok {
+			var sq FieldVal
+			require.True(t, sq.Square(&res).Equals(fa))
+		}
+
+		for _, b := range vals[:20] {
+			fb := fieldFromBig(b)
+
+			requireBig(t, mod(new(big.Int).Add(a, b)),
+				fieldToBig(res.Add(fa, fb)))
+			requireBig(t, mod(new(big.Int).Sub(a, b)),
+				fieldToBig(res.Sub(fa, fb)))
+			requireBig(t, mod(new(big.Int).Mul(a, b)),
+				fieldToBig(res.Mul(fa, fb)))
+			require.Equal(t, a.Cmp(b) == 0, fa.Equals(fb))
+		}
+	}
+
+	// Values that aren't less than P are reduced and reported.
+	var f FieldVal
+	require.True(t, f.SetBytes(bytes32(new(big.Int).Add(p, big.NewInt(5)))))
+	requireBig(t, big.NewInt(5), fieldToBig(&f))
+	require.False(t, f.SetByteSlice([]byte{1, 2}))
+	requireBig(t, big.NewInt(0x102), fieldToBig(&f))
+}
+
+func TestScalar(t *testing.T) {
+	n := curveN
+	r := rand.New(rand.NewSource(2))
+	vals := testValues(r, n)
+	half := new(big.Int).Rsh(n, 1)
+
+	mod := func(i *big.Int) *big.Int {
+		return i.Mod(i, n)
+	}
+
+	for _, a := range vals {
+		sa := scalarFromBig(a)
+		requireBig(t, a, scalarToBig(sa))
+		require.Equal(t, a.Cmp(half) > 0, sa.IsHigh())
+
+		var res Scalar
+		requireBig(t, mod(new(big.Int).Neg(a)), scalarToBig(res.Neg(sa)))
+
+		if a.Sign() != 0 {
+			requireBig(t, new(big.Int).ModInverse(a, n),
+				scalarToBig(res.Inverse(sa)))
+		}
+
+		res.Set(sa).CondNeg(false)
+		require.True(t, res.Equals(sa))
+		res.CondNeg(true)
+		requireBig(t, mod(new(big.Int).Neg(a)), scalarToBig(&res))
+
+		for _, b := range vals[:20] {
+			sb := scalarFromBig(b)
+
+			requireBig(t, mod(new(big.Int).Add(a, b)),
+				scalarToBig(res.Add(sa, sb)))
+			requireBig(t, mod(new(big.Int).Sub(a, b)),
+				scalarToBig(res.Sub(sa, sb)))
+			requireBig(t, mod(new(big.Int).Mul(a, b)),
+				scalarToBig(res.Mul(sa, sb)))
+		}
+	}
+
+	var s Scalar
+	require.True(t, s.SetBytes(bytes32(n)))
+	require.True(t, s.IsZero())
+}
+
+func affine(t *testing.T, p *JacobianPoint) (*big.Int, *big.Int) {
+	q := *p
+	require.True(t, q.ToAffine())
+
+	return fieldToBig(&q.X), fieldToBig(&q.Y)
+}
+
+func TestScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+
+	g := refG(t)
+	for _, k := range testValues(r, curveN)[:20] {
+		var p JacobianPoint
+		p.ScalarBaseMult(scalarFromBig(k))
+
+		expected, err := g.Mul(k)
+		require.NoError(t, err)
+
+		if expected.GetX() == nil {
+			require.True(t, p.IsInfinity())
+			continue
+		}
+
+		x, y := affine(t, &p)
+		requireBig(t, expected.GetX().GetNum(), x)
+		requireBig(t, expected.GetY().GetNum(), y)
+
+		// Multiplying an arbitrary point works too.
+		var q JacobianPoint
+		q.ScalarMult(scalarFromBig(big.NewInt(3)), &p)
+
+		expected, err = expected.Mul(big.NewInt(3))
+		require.NoError(t, err)
+
+		x, y = affine(t, &q)
+		requireBig(t, expected.GetX().GetNum(), x)
+		requireBig(t, expected.GetY().GetNum(), y)
+	}
+}
+
+func TestPointAdd(t *testing.T) {
+	var two, three, sum, neg, inf JacobianPoint
+	two.Double(&G)
+	three.ScalarBaseMult(scalarFromBig(big.NewInt(3)))
+
+	// Adding equal points doubles them, even when their Z coordinates
+	// differ.
+	sum.Add(&G, &G)
+	require.Equal(t, affineOf(t, &two), affineOf(t, &sum))
+
+	sum.Add(&two, &G)
+	require.Equal(t, affineOf(t, &three), affineOf(t, &sum))
+
+	sum.Add(&sum, &two)
+	sum.Add(&sum, neg.Neg(&two))
+	require.Equal(t, affineOf(t, &three), affineOf(t, &sum))
+
+	// P + -P is the point at infinity and infinity is the identity.
+	sum.Add(&three, neg.Neg(&three))
+	require.True(t, sum.IsInfinity())
+
+	sum.Add(&inf, &three)
+	require.Equal(t, affineOf(t, &three), affineOf(t, &sum))
+
+	sum.Add(&three, &inf)
+	require.Equal(t, affineOf(t, &three), affineOf(t, &sum))
+
+	sum.Add(&inf, &inf)
+	require.True(t, sum.IsInfinity())
+
+	// G is on the curve but a point with a modified y is not.
+	_, ok := NewAffinePoint(&G.X, &G.Y)
+	require.True(t, ok)
+
+	var y FieldVal
+	_, ok = NewAffinePoint(&G.X, y.Add(&G.Y, new(FieldVal).SetInt(1)))
+	require.False(t, ok)
+}
+
+func affineOf(t *testing.T, p *JacobianPoint) [2]*big.Int {
+	x, y := affine(t, p)
+	return [2]*big.Int{x, y}
+}
+
+func BenchmarkScalarBaseMult(b *testing.B) {
+	k := scalarFromBig(new(big.Int).Sub(curveN, big.NewInt(12345)))
+
+	var p JacobianPoint
+	for i := 0; i < b.N; i++ {
+		p.ScalarBaseMult(k)
+	}
+}
+
+func TestGLV(t *testing.T) {
+	// lambda and beta are nontrivial cube roots of unity and lambda*G is
+	// (beta*x, y).
+	var b3 FieldVal
+	b3.Square(&glvBeta)
+	b3.Mul(&b3, &glvBeta)
+	require.Equal(t, new(FieldVal).SetInt(1), &b3)
+
+	var s3 Scalar
+	s3.Square(&glvLambda)
+	s3.Mul(&s3, &glvLambda)
+	require.True(t, s3.Equals(new(Scalar).SetInt(1)))
+
+	var lg, endo JacobianPoint
+	lg.ScalarMult(&glvLambda, &G)
+	endo.endomorphism(&G)
+	require.Equal(t, affineOf(t, &lg), affineOf(t, &endo))
+
+	// Both halves of a split scalar fit in 128 bits, up to sign.
+	r := rand.New(rand.NewSource(4))
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	lambda := scalarToBig(&glvLambda)
+	for _, k := range testValues(r, curveN) {
+		k1, k2 := splitScalar(scalarFromBig(k))
+
+		sum := new(big.Int).Mul(scalarToBig(&k2), lambda)
+		sum.Add(sum, scalarToBig(&k1))
+		requireBig(t, k, sum.Mod(sum, curveN))
+
+		for _, half := range []Scalar{k1, k2} {
+			half.CondNeg(half.IsHigh())
+			require.True(t, scalarToBig(&half).Cmp(limit) < 0)
+		}
+	}
+}
+
+func TestWNAF(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+
+	for _, w := range []uint{pointWindow, gWindow} {
+		for _, k := range testValues(r, curveN) {
+			naf := wnaf(scalarFromBig(k), w)
+
+			sum := new(big.Int)
+			for i := len(naf) - 1; i >= 0; i-- {
+				sum.Lsh(sum, 1)
+				sum.Add(sum, big.NewInt(int64(naf[i])))
+
+				d := naf[i]
+				if d == 0 {
+					continue
+				}
+				require.True(t, d%2 != 0)
+				require.True(t, abs8(d) < 1<<(w-1))
+
+				// The next w-1 digits are zero.
+				for j := i + 1; j < i+int(w) && j < len(naf); j++ {
+					require.Zero(t, naf[j])
+				}
+			}
+			requireBig(t, k, sum)
+		}
+	}
+}
+
+func TestMultiScalarMultVarTime(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	vals := testValues(r, curveN)
+
+	var points []JacobianPoint
+	for i := 0; i < 3; i++ {
+		var p JacobianPoint
+		p.ScalarBaseMult(scalarFromBig(vals[len(vals)-1-i]))
+		points = append(points, p)
+	}
+
+	for i := 0; i+3 < len(vals); i += 4 {
+		u := scalarFromBig(vals[i])
+		scalars := []Scalar{
+			*scalarFromBig(vals[i+1]), *scalarFromBig(vals[i+2]),
+			*scalarFromBig(vals[i+3]),
+		}
+
+		var expected, term JacobianPoint
+		expected.ScalarBaseMult(u)
+		for j := range points {
+			term.ScalarMult(&scalars[j], &points[j])
+			expected.Add(&expected, &term)
+		}
+
+		var got JacobianPoint
+		got.MultiScalarMultVarTime(u, scalars, points)
+		if expected.IsInfinity() {
+			require.True(t, got.IsInfinity())
+			continue
+		}
+		require.Equal(t, affineOf(t, &expected), affineOf(t, &got))
+
+		got.DoubleScalarMultVarTime(u, &scalars[0], &points[0])
+		expected.ScalarBaseMult(u)
+		expected.Add(&expected, term.ScalarMult(&scalars[0], &points[0]))
+		require.Equal(t, affineOf(t, &expected), affineOf(t, &got))
+	}
+
+	// u*G - u*G is the point at infinity.
+	var neg, got JacobianPoint
+	u := scalarFromBig(vals[10])
+	got.MultiScalarMultVarTime(u, []Scalar{*u}, []JacobianPoint{
+		*neg.Neg(&G),
+	})
+	require.True(t, got.IsInfinity())
+
+	got.MultiScalarMultVarTime(new(Scalar), nil, nil)
+	require.True(t, got.IsInfinity())
+}
+
+func BenchmarkDoubleScalarMultVarTime(b *testing.B) {
+	u := scalarFromBig(new(big.Int).Sub(curveN, big.NewInt(12345)))
+	v := scalarFromBig(new(big.Int).Sub(curveN, big.NewInt(67890)))
+
+	var a, p JacobianPoint
+	a.ScalarBaseMult(v)
+	for i := 0; i < b.N; i++ {
+		p.DoubleScalarMultVarTime(u, v, &a)
+	}
+}
@@ -0,0 +1,71 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// CompactSize is the size of a compact recoverable signature: a header
+	// byte followed by r and s as 32 byte big endian integers.
+	CompactSize = 65
+
+	// compactMagic is the offset of the header byte. The recovery id is
+	// added to it along with compactCompressed if the public key is
+	// compressed.
+	compactMagic      = 27
+	compactCompressed = 4
+)
+
+// Compact returns the 65 byte compact encoding of the signature used for
+// signed messages. The header byte holds the recovery id, which is needed to
+// recover the public key, and whether the key is compressed. An error is
+// returned if the recovery id is above 3 or r or s don't fit in 32 bytes.
+func (s *Signature) Compact(recID byte, compressed bool) ([]byte, error) {
+	if recID > 3 {
+		return nil, fmt.Errorf("recovery id must be between 0 and 3, "+
+			"got %d", recID)
+	}
+
+	if s.Rx.Sign() < 0 || s.Rx.BitLen() > 256 ||
+		s.S.Sign() < 0 || s.S.BitLen() > 256 {
+
+		return nil, errors.New("signature values must fit in 32 bytes")
+	}
+
+	header := compactMagic + recID
+	if compressed {
+		header += compactCompressed
+	}
+
+	b := make([]byte, CompactSize)
+	b[0] = header
+	copy(b[33-len(s.Rx.Bytes()):33], s.Rx.Bytes())
+	copy(b[65-len(s.S.Bytes()):], s.S.Bytes())
+
+	return b, nil
+}
+
+// ParseCompact parses a 65 byte compact signature and returns the signature
+// along with the recovery id and whether the public key is compressed.
+func ParseCompact(b []byte) (*Signature, byte, bool, error) {
+	if len(b) != CompactSize {
+		return nil, 0, false, fmt.Errorf("compact signature must be %d "+
+			"bytes, got %d", CompactSize, len(b))
+	}
+
+	header := b[0]
+	if header < compactMagic || header >= compactMagic+8 {
+		return nil, 0, false, errors.New("invalid compact signature " +
+			"header")
+	}
+
+	recID := (header - compactMagic) & 3
+	compressed := (header-compactMagic)&compactCompressed != 0
+
+	return &Signature{
+		Rx: new(big.Int).SetBytes(b[1:33]),
+		S:  new(big.Int).SetBytes(b[33:]),
+	}, recID, compressed, nil
+}
@@ -0,0 +1,203 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// minDERLen and maxDERLen bound the size of a strict DER signature
+	// without its sighash type byte.
+	minDERLen = 8
+	maxDERLen = 72
+
+	asn1Sequence byte = 0x30
+	asn1Integer  byte = 0x02
+)
+
+var (
+	// ErrInvalidDER is returned when a signature is not encoded as
+	// required.
+	ErrInvalidDER = errors.New("invalid DER signature")
+
+	// ErrMissingHashType is returned when splitting the sighash type off
+	// an empty signature.
+	ErrMissingHashType = errors.New("signature has no sighash type")
+)
+
+func derError(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidDER, fmt.Sprintf(format, a...))
+}
+
+// Parse parses a signature in the strict DER encoding required by BIP66:
+//
+//	0x30 [total-length] 0x02 [R-length] [R] 0x02 [S-length] [S]
+//
+// Both integers must be positive and minimally encoded, and there may be no
+// trailing bytes. The values of R and S aren't checked against the curve
+// order since that is part of verification rather than encoding.
+func Parse(sig []byte) (*Signature, error) {
+	if len(sig) < minDERLen || len(sig) > maxDERLen {
+		return nil, derError("length %d out of range", len(sig))
+	}
+
+	if sig[0] != asn1Sequence {
+		return nil, derError("missing sequence tag")
+	}
+
+	if int(sig[1]) != len(sig)-2 {
+		return nil, derError("sequence length %d does not match %d "+
+			"remaining bytes", sig[1], len(sig)-2)
+	}
+
+	r, rest, err := parseStrictInt(sig[2:], "R")
+	if err != nil {
+		return nil, err
+	}
+
+	s, rest, err := parseStrictInt(rest, "S")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) != 0 {
+		return nil, derError("%d trailing bytes", len(rest))
+	}
+
+	return &Signature{Rx: r, S: s}, nil
+}
+
+// parseStrictInt parses a DER integer from the start of b and returns the
+// bytes that follow it.
+func parseStrictInt(b []byte, name string) (*big.Int, []byte, error) {
+	if len(b) < 2 || b[0] != asn1Integer {
+		return nil, nil, derError("missing %s integer tag", name)
+	}
+
+	l := int(b[1])
+	if l == 0 {
+		return nil, nil, derError("%s has zero length", name)
+	}
+
+	if l > len(b)-2 {
+		return nil, nil, derError("%s length %d exceeds %d remaining "+
+			"bytes", name, l, len(b)-2)
+	}
+
+	v := b[2 : 2+l]
+	if v[0]&0x80 != 0 {
+		return nil, nil, derError("%s is negative", name)
+	}
+
+	if l > 1 && v[0] == 0x00 && v[1]&0x80 == 0 {
+		return nil, nil, derError("%s has excess padding", name)
+	}
+
+	return new(big.Int).SetBytes(v), b[2+l:], nil
+}
+
+// ParseLax parses a signature the way OpenSSL did before BIP66, which is
+// needed to verify some historical transactions. It accepts long form
+// lengths, excess padding, negative integers (read as unsigned) and trailing
+// garbage. As in Bitcoin Core, values that don't fit in 32 bytes are
+// rejected.
+func ParseLax(sig []byte) (*Signature, error) {
+	pos := 0
+
+	if pos == len(sig) || sig[pos] != asn1Sequence {
+		return nil, derError("missing sequence tag")
+	}
+	pos++
+
+	// The sequence length is skipped over without being checked.
+	if pos == len(sig) {
+		return nil, derError("missing sequence length")
+	}
+	lenByte := int(sig[pos])
+	pos++
+	if lenByte&0x80 != 0 {
+		lenByte -= 0x80
+		if lenByte > len(sig)-pos {
+			return nil, derError("sequence length overflows")
+		}
+		pos += lenByte
+	}
+
+	r, pos, err := parseLaxInt(sig, pos, "R")
+	if err != nil {
+		return nil, err
+	}
+
+	s, _, err := parseLaxInt(sig, pos, "S")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signature{Rx: r, S: s}, nil
+}
+
+// parseLaxInt parses an integer starting at pos and returns the position of
+// the byte that follows it.
+func parseLaxInt(sig []byte, pos int, name string) (*big.Int, int, error) {
+	if pos == len(sig) || sig[pos] != asn1Integer {
+		return nil, 0, derError("missing %s integer tag", name)
+	}
+	pos++
+
+	if pos == len(sig) {
+		return nil, 0, derError("missing %s length", name)
+	}
+	lenByte := int(sig[pos])
+	pos++
+
+	l := lenByte
+	if lenByte&0x80 != 0 {
+		lenByte -= 0x80
+		if lenByte > len(sig)-pos {
+			return nil, 0, derError("%s length overflows", name)
+		}
+
+		for lenByte > 0 && sig[pos] == 0 {
+			pos++
+			lenByte--
+		}
+
+		// The length itself must fit in a machine word.
+		if lenByte >= 4 {
+			return nil, 0, derError("%s length overflows", name)
+		}
+
+		l = 0
+		for ; lenByte > 0; lenByte-- {
+			l = l<<8 | int(sig[pos])
+			pos++
+		}
+	}
+
+	if l > len(sig)-pos {
+		return nil, 0, derError("%s length %d exceeds %d remaining "+
+			"bytes", name, l, len(sig)-pos)
+	}
+
+	v := sig[pos : pos+l]
+	for len(v) > 0 && v[0] == 0 {
+		v = v[1:]
+	}
+
+	if len(v) > 32 {
+		return nil, 0, derError("%s overflows 32 bytes", name)
+	}
+
+	return new(big.Int).SetBytes(v), pos + l, nil
+}
+
+// SplitHashType splits a signature taken from a scriptSig or witness into its
+// DER encoding and the trailing sighash type byte.
+func SplitHashType(sig []byte) ([]byte, byte, error) {
+	if len(sig) == 0 {
+		return nil, 0, ErrMissingHashType
+	}
+
+	return sig[:len(sig)-1], sig[len(sig)-1], nil
+}
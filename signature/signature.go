@@ -2,10 +2,18 @@ package signature
 
 import (
 	"encoding/hex"
-	"errors"
 	"math/big"
 )
 
+// order is the order of the secp256k1 group. It is defined here rather than
+// taken from s256point since that package depends on this one.
+const orderHex = "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141"
+
+var (
+	order     *big.Int
+	halfOrder *big.Int
+)
+
 type Signature struct {
 	Rx *big.Int
 	S  *big.Int
@@ -27,78 +35,66 @@ func New(r, s *big.Int) *Signature {
 - s: as big endian. but prepend with 0x00 if s's 1st byte >= 0x80. prepend resulting len to s.
 */
 func (s *Signature) Der() []byte {
-	rbin := s.Rx.Bytes()
-	if (rbin[0] & 0x80) >= 1 {
-		rbin = append([]byte{0}, rbin...)
-	}
-	rbin = append([]byte{0x02, byte(len(rbin))}, rbin...)
-
-	sbin := s.S.Bytes()
-	if (sbin[0] & 0x80) >= 1 {
-		sbin = append([]byte{0}, sbin...)
-	}
-	sbin = append([]byte{0x02, byte(len(sbin))}, sbin...)
+	rbin := derInt(s.Rx)
+	sbin := derInt(s.S)
 
 	res := append(rbin, sbin...)
 
 	return append([]byte{0x30, byte(len(res))}, res...)
 }
 
-func (s *Signature) DerString() string {
-	return hex.EncodeToString(s.Der())
-}
-
-func ParseFromString(s string) (*Signature, error) {
-	b, err := hex.DecodeString(s)
-	if err != nil {
-		return nil, err
+// derInt encodes i as a DER integer. Zero is encoded as a single zero byte
+// rather than with an empty body, which isn't valid DER.
+func derInt(i *big.Int) []byte {
+	b := i.Bytes()
+	if len(b) == 0 || (b[0]&0x80) >= 1 {
+		b = append([]byte{0}, b...)
 	}
 
-	return Parse(b)
+	return append([]byte{0x02, byte(len(b))}, b...)
 }
 
-func Parse(sig []byte) (*Signature, error) {
-	b := make([]byte, len(sig))
-	copy(b, sig)
+func (s *Signature) DerString() string {
+	return hex.EncodeToString(s.Der())
+}
 
-	if b[0] != 0x30 {
-		return nil, errors.New("Bad signature 1")
-	}
-	b = b[1:]
+// SerializeWithHashType returns the DER encoding followed by the sighash type
+// byte, which is the form signatures take in scriptSigs and witnesses.
+func (s *Signature) SerializeWithHashType(hashType byte) []byte {
+	return append(s.Der(), hashType)
+}
 
-	length := b[0]
-	b = b[1:]
-	if int(length)+2 != len(sig) {
-		return nil, errors.New("Bad signature Length")
-	}
+// IsLowS returns true if S is at most half the curve order. BIP62 requires
+// this so that a third party can't change the txid of a transaction by
+// replacing S with N-S, which is equally valid.
+func (s *Signature) IsLowS() bool {
+	return s.S.Cmp(halfOrder) <= 0
+}
 
-	if b[0] != 0x02 {
-		return nil, errors.New("Bad signature 2")
+// Normalize returns the signature with a low S value, which verifies for the
+// same key and hash.
+func (s *Signature) Normalize() *Signature {
+	if s.IsLowS() {
+		return &Signature{Rx: s.Rx, S: s.S}
 	}
-	b = b[1:]
 
-	rlength := b[0]
-	b = b[1:]
-
-	r := (&big.Int{}).SetBytes(b[:rlength])
-	b = b[rlength:]
+	return &Signature{Rx: s.Rx, S: new(big.Int).Sub(order, s.S)}
+}
 
-	if b[0] != 0x02 {
-		return nil, errors.New("Bad signature 3")
+func ParseFromString(s string) (*Signature, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
 	}
-	b = b[1:]
 
-	slength := b[0]
-	b = b[1:]
+	return Parse(b)
+}
 
-	s := (&big.Int{}).SetBytes(b[:slength])
-	b = b[slength:]
-	if len(b) != 0 {
-		return nil, errors.New("Signature too long")
+func init() {
+	n, ok := new(big.Int).SetString(orderHex, 16)
+	if !ok {
+		panic("invalid hex: " + orderHex)
 	}
-
-	return &Signature{
-		S:  s,
-		Rx: r,
-	}, nil
+	order = n
+	halfOrder = new(big.Int).Rsh(n, 1)
 }
@@ -1,6 +1,8 @@
 package signature
 
 import (
+	"encoding/hex"
+	"errors"
 	"math/big"
 	"testing"
 
@@ -28,3 +30,160 @@ func TestParse(t *testing.T) {
 	require.True(t, sig.Rx.Cmp(expectR) == 0)
 	require.True(t, sig.S.Cmp(expectS) == 0)
 }
+
+func TestCompact(t *testing.T) {
+	r, _ := new(big.Int).SetString("37206a0610995c58074999cb9767b87af4c4978db68c06e8e6e81d282047a7c6", 16)
+	s := big.NewInt(1)
+
+	for recID := byte(0); recID < 4; recID++ {
+		for _, compressed := range []bool{false, true} {
+			b, err := New(r, s).Compact(recID, compressed)
+			require.NoError(t, err)
+			require.Len(t, b, CompactSize)
+
+			sig, id, c, err := ParseCompact(b)
+			require.NoError(t, err)
+			require.Equal(t, recID, id)
+			require.Equal(t, compressed, c)
+			require.True(t, sig.Rx.Cmp(r) == 0)
+			require.True(t, sig.S.Cmp(s) == 0)
+		}
+	}
+
+	b, err := New(r, s).Compact(1, true)
+	require.NoError(t, err)
+	require.Equal(t, byte(32), b[0])
+
+	_, err = New(r, s).Compact(4, true)
+	require.Error(t, err)
+
+	tooBig := new(big.Int).Lsh(big.NewInt(1), 256)
+	_, err = New(tooBig, s).Compact(0, true)
+	require.Error(t, err)
+
+	_, err = New(r, tooBig).Compact(0, true)
+	require.Error(t, err)
+
+	b[0] = 35
+	_, _, _, err = ParseCompact(b)
+	require.Error(t, err)
+
+	_, _, _, err = ParseCompact(b[:64])
+	require.Error(t, err)
+}
+
+func TestParseStrict(t *testing.T) {
+	valid := "3045022037206a0610995c58074999cb9767b87af4c4978db68c06e8e6e81d282047a7c60221008ca63759c1157ebeaec0d03cecca119fc9a75bf8e6d0fa65c841c8e2738cdaec"
+
+	tests := []struct {
+		name string
+		sig  string
+	}{
+		{name: "empty", sig: ""},
+		{name: "short", sig: "3006020101"},
+		{name: "wrong tag", sig: "3106020101020101"},
+		{name: "wrong length", sig: "3007020101020101"},
+		{name: "trailing bytes", sig: "300602010102010100"},
+		{name: "missing S", sig: "30060204010101010000"},
+		{name: "zero length R", sig: "3006020002020101"},
+		{name: "R length overflows", sig: "3006020901020101"},
+		{name: "negative R", sig: "3006020181020101"},
+		{name: "padded R", sig: "300702020001020101"},
+		{name: "negative S", sig: "3006020101020181"},
+		{name: "padded S", sig: "300702010102020001"},
+		{name: "wrong S tag", sig: "3006020101030101"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFromString(test.sig)
+			require.True(t, errors.Is(err, ErrInvalidDER), err)
+		})
+	}
+
+	_, err := ParseFromString(valid)
+	require.NoError(t, err)
+
+	// Padding is required when the high bit is set.
+	sig, err := ParseFromString("30070202008002010a")
+	require.NoError(t, err)
+	require.Equal(t, int64(0x80), sig.Rx.Int64())
+	require.Equal(t, int64(10), sig.S.Int64())
+}
+
+func TestParseLax(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  string
+		r    int64
+		s    int64
+	}{
+		{name: "strict", sig: "3006020101020102", r: 1, s: 2},
+		{name: "padded", sig: "30080203000001020102", r: 1, s: 2},
+		{name: "negative", sig: "3006020181020102", r: 0x81, s: 2},
+		{name: "long form lengths", sig: "308107028101010281010200", r: 1, s: 2},
+		{name: "wrong sequence length", sig: "3000020101020102", r: 1, s: 2},
+		{name: "trailing garbage", sig: "3006020101020102ffff", r: 1, s: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := hex.DecodeString(test.sig)
+			require.NoError(t, err)
+
+			sig, err := ParseLax(b)
+			require.NoError(t, err)
+			require.Equal(t, test.r, sig.Rx.Int64())
+			require.Equal(t, test.s, sig.S.Int64())
+		})
+	}
+
+	// Values must still fit in 32 bytes.
+	b := append([]byte{0x30, 0x26, 0x02, 0x21, 0x01}, make([]byte, 32)...)
+	b = append(b, 0x02, 0x01, 0x01)
+	_, err := ParseLax(b)
+	require.True(t, errors.Is(err, ErrInvalidDER))
+
+	// Leading zeros don't count towards the size.
+	b = append([]byte{0x30, 0x26, 0x02, 0x21, 0x00, 0x01}, make([]byte, 31)...)
+	b = append(b, 0x02, 0x01, 0x01)
+	_, err = ParseLax(b)
+	require.NoError(t, err)
+
+	_, err = ParseLax([]byte{0x30, 0x06, 0x02})
+	require.True(t, errors.Is(err, ErrInvalidDER))
+}
+
+func TestNormalize(t *testing.T) {
+	highS := new(big.Int).Sub(order, big.NewInt(5))
+
+	sig := New(big.NewInt(1), highS)
+	require.False(t, sig.IsLowS())
+
+	norm := sig.Normalize()
+	require.True(t, norm.IsLowS())
+	require.Equal(t, int64(5), norm.S.Int64())
+
+	// The original is left untouched and low S values are unchanged.
+	require.Equal(t, 0, sig.S.Cmp(highS))
+	require.Equal(t, norm.S, norm.Normalize().S)
+	require.True(t, New(big.NewInt(1), halfOrder).IsLowS())
+}
+
+func TestDERZero(t *testing.T) {
+	sig := New(big.NewInt(0), big.NewInt(0x80))
+	require.Equal(t, "3007020100020200800a", hex.EncodeToString(
+		sig.SerializeWithHashType(0x0a),
+	))
+
+	der, hashType, err := SplitHashType(sig.SerializeWithHashType(0x0a))
+	require.NoError(t, err)
+	require.Equal(t, byte(0x0a), hashType)
+
+	parsed, err := Parse(der)
+	require.NoError(t, err)
+	require.Equal(t, 0, parsed.Rx.Sign())
+
+	_, _, err = SplitHashType(nil)
+	require.True(t, errors.Is(err, ErrMissingHashType))
+}
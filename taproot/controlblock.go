@@ -0,0 +1,130 @@
+package taproot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+)
+
+const (
+	// ControlBlockBaseSize is the size of a control block without any
+	// inclusion proof: the leaf version and parity byte followed by the
+	// internal key.
+	ControlBlockBaseSize = 33
+
+	// ControlBlockNodeSize is the size of each hash in the inclusion
+	// proof.
+	ControlBlockNodeSize = 32
+
+	// ControlBlockMaxNodeCount is the maximum depth of a script tree.
+	ControlBlockMaxNodeCount = 128
+)
+
+// ControlBlock is the last witness element of a script path spend. It proves
+// that the leaf script is committed to by the output key.
+type ControlBlock struct {
+	LeafVersion     byte
+	OutputKeyYIsOdd bool
+	InternalKey     *s256point.S256Point
+
+	// InclusionProof holds the sibling hashes on the path from the leaf
+	// to the root, starting with the leaf's sibling.
+	InclusionProof [][]byte
+}
+
+// Serialize returns the encoded control block.
+func (c *ControlBlock) Serialize() []byte {
+	first := c.LeafVersion & LeafVersionMask
+	if c.OutputKeyYIsOdd {
+		first |= 1
+	}
+
+	b := make([]byte, 0, ControlBlockBaseSize+
+		len(c.InclusionProof)*ControlBlockNodeSize)
+	b = append(b, first)
+	b = append(b, c.InternalKey.XOnly()...)
+	for _, h := range c.InclusionProof {
+		b = append(b, h...)
+	}
+
+	return b
+}
+
+// ParseControlBlock decodes a control block, checking its length and that the
+// internal key is a valid x-only key.
+func ParseControlBlock(b []byte) (*ControlBlock, error) {
+	if len(b) < ControlBlockBaseSize ||
+		(len(b)-ControlBlockBaseSize)%ControlBlockNodeSize != 0 {
+
+		return nil, fmt.Errorf("invalid control block size %d", len(b))
+	}
+
+	nodes := (len(b) - ControlBlockBaseSize) / ControlBlockNodeSize
+	if nodes > ControlBlockMaxNodeCount {
+		return nil, fmt.Errorf("control block has %d nodes which "+
+			"exceeds the max of %d", nodes, ControlBlockMaxNodeCount)
+	}
+
+	internalKey, err := s256point.ParseXOnly(b[1:ControlBlockBaseSize])
+	if err != nil {
+		return nil, err
+	}
+
+	proof := make([][]byte, nodes)
+	for i := range proof {
+		start := ControlBlockBaseSize + i*ControlBlockNodeSize
+		proof[i] = append([]byte{}, b[start:start+ControlBlockNodeSize]...)
+	}
+
+	return &ControlBlock{
+		LeafVersion:     b[0] & LeafVersionMask,
+		OutputKeyYIsOdd: b[0]&1 == 1,
+		InternalKey:     internalKey,
+		InclusionProof:  proof,
+	}, nil
+}
+
+// RootHash returns the merkle root implied by the control block for the
+// given leaf script.
+func (c *ControlBlock) RootHash(leafScript script.Script) ([]byte, error) {
+	k, err := Leaf{Version: c.LeafVersion, Script: leafScript}.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, h := range c.InclusionProof {
+		k = BranchHash(k, h)
+	}
+
+	return k, nil
+}
+
+// Verify checks that the control block proves that the leaf script is
+// committed to by the x-only output key.
+func (c *ControlBlock) Verify(outputKey []byte,
+	leafScript script.Script) error {
+
+	root, err := c.RootHash(leafScript)
+	if err != nil {
+		return err
+	}
+
+	q, err := ComputeOutputKey(c.InternalKey, root)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(q.XOnly(), outputKey) {
+		return errors.New("control block does not commit to the " +
+			"output key")
+	}
+
+	if q.HasEvenY() == c.OutputKeyYIsOdd {
+		return errors.New("control block output key parity mismatch")
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package taproot
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+)
+
+const (
+	// BaseLeafVersion is the leaf version of tapscript leaves as defined
+	// by BIP342.
+	BaseLeafVersion byte = 0xc0
+
+	// LeafVersionMask selects the leaf version from the first byte of a
+	// control block. The remaining bit holds the parity of the output key.
+	LeafVersionMask byte = 0xfe
+
+	tagTapTweak  = "TapTweak"
+	tagTapLeaf   = "TapLeaf"
+	tagTapBranch = "TapBranch"
+)
+
+// TweakHash returns the TapTweak hash of the internal key and the merkle
+// root of the script tree. The merkle root is nil for outputs that can only
+// be spent with the key path.
+func TweakHash(internalKey *s256point.S256Point, merkleRoot []byte) []byte {
+	return helpers.TaggedHash(tagTapTweak, internalKey.XOnly(), merkleRoot)
+}
+
+// ComputeOutputKey returns the output key Q = P + t*G where P is the internal
+// key with an even y coordinate and t is its TapTweak hash. The x-only
+// encoding of Q is the witness program of the P2TR output.
+func ComputeOutputKey(internalKey *s256point.S256Point,
+	merkleRoot []byte) (*s256point.S256Point, error) {
+
+	p, err := evenKey(internalKey)
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(big.Int).SetBytes(TweakHash(p, merkleRoot))
+	if t.Cmp(s256point.N) >= 0 {
+		return nil, errors.New("tweak exceeds the curve order")
+	}
+
+	tG, err := s256point.G.Mul(t)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := p.Add(tG)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.IsInfinity() {
+		return nil, errors.New("tweaked key is the point at infinity")
+	}
+
+	return q.(*s256point.S256Point), nil
+}
+
+// evenKey returns the point with the same x coordinate as p and an even y.
+func evenKey(p *s256point.S256Point) (*s256point.S256Point, error) {
+	if p.HasEvenY() {
+		return p, nil
+	}
+
+	return s256point.ParseXOnly(p.XOnly())
+}
+
+// Leaf is a single script in a taproot script tree.
+type Leaf struct {
+	Version byte
+	Script  script.Script
+}
+
+// NewBaseLeaf returns a tapscript leaf with the base leaf version.
+func NewBaseLeaf(s script.Script) Leaf {
+	return Leaf{Version: BaseLeafVersion, Script: s}
+}
+
+// Hash returns the TapLeaf hash of the leaf version and the length prefixed
+// script.
+func (l Leaf) Hash() ([]byte, error) {
+	s, err := l.Script.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.TaggedHash(tagTapLeaf, []byte{l.Version}, s), nil
+}
+
+// BranchHash returns the TapBranch hash of two child hashes. The children
+// are sorted first so the result does not depend on their order.
+func BranchHash(a, b []byte) []byte {
+	if bytesLess(b, a) {
+		a, b = b, a
+	}
+
+	return helpers.TaggedHash(tagTapBranch, a, b)
+}
+
+func bytesLess(a, b []byte) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+
+	return len(a) < len(b)
+}
+
+// TweakPrivateKey returns the private key of the output key that commits to
+// the merkle root, for use when spending with the key path.
+func TweakPrivateKey(priv *privatekey.PrivateKey,
+	merkleRoot []byte) (*privatekey.PrivateKey, error) {
+
+	return priv.TweakXOnly(TweakHash(priv.PubKey, merkleRoot))
+}
@@ -0,0 +1,201 @@
+package taproot
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+func leaf(t *testing.T, version byte, s string) Leaf {
+	sc, err := script.ParseRaw(mustHex(t, s))
+	require.NoError(t, err)
+
+	return Leaf{Version: version, Script: sc}
+}
+
+// TestBIP341Vectors checks the scriptPubKey test vectors from BIP341.
+func TestBIP341Vectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		internalKey   string
+		leaves        []Leaf
+		leafHashes    []string
+		tweak         string
+		outputKey     string
+		controlBlocks []string
+	}{
+		{
+			name:        "key path only",
+			internalKey: "d6889cb081036e0faefa3a35157ad71086b123b2b144b649798b494c300a961d",
+			tweak:       "b86e7be8f39bab32a6f2c0443abbc210f0edac0e2c53d501b36b64437d9c6c70",
+			outputKey:   "53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343",
+		},
+		{
+			name:        "single leaf",
+			internalKey: "187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			leaves: []Leaf{
+				leaf(t, 0xc0, "20d85a959b0290bf19bb89ed43c916be835475d013da4b362117393e25a48229b8ac"),
+			},
+			leafHashes: []string{
+				"5b75adecf53548f3ec6ad7d78383bf84cc57b55a3127c72b9a2481752dd88b21",
+			},
+			tweak:     "cbd8679ba636c1110ea247542cfbd964131a6be84f873f7f3b62a777528ed001",
+			outputKey: "147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3",
+			controlBlocks: []string{
+				"c1187791b6f712a8ea41c8ecdd0ee77fab3e85263b37e1ec18a3651926b3a6cf27",
+			},
+		},
+		{
+			name:        "single leaf even output key",
+			internalKey: "93478e9488f956df2396be2ce6c5cced75f900dfa18e7dabd2428aae78451820",
+			leaves: []Leaf{
+				leaf(t, 0xc0, "20b617298552a72ade070667e86ca63b8f5789a9fe8731ef91202a91c9f3459007ac"),
+			},
+			leafHashes: []string{
+				"c525714a7f49c28aedbbba78c005931a81c234b2f6c99a73e4d06082adc8bf2b",
+			},
+			tweak:     "6af9e28dbf9d6aaf027696e2598a5b3d056f5fd2355a7fd5a37a0e5008132d30",
+			outputKey: "e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e",
+			controlBlocks: []string{
+				"c093478e9488f956df2396be2ce6c5cced75f900dfa18e7dabd2428aae78451820",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			internalKey, err := s256point.ParseXOnly(
+				mustHex(t, test.internalKey),
+			)
+			require.NoError(t, err)
+
+			var (
+				tree *Tree
+				root []byte
+			)
+			if len(test.leaves) > 0 {
+				tree, err = AssembleTree(test.leaves...)
+				require.NoError(t, err)
+				require.Equal(t, len(test.leaves), tree.NumLeaves())
+				root = tree.RootHash()
+			}
+
+			for i, l := range test.leaves {
+				h, err := l.Hash()
+				require.NoError(t, err)
+				require.Equal(t, test.leafHashes[i], hex.EncodeToString(h))
+			}
+
+			require.Equal(t, test.tweak, hex.EncodeToString(
+				TweakHash(internalKey, root),
+			))
+
+			outputKey, err := ComputeOutputKey(internalKey, root)
+			require.NoError(t, err)
+			require.Equal(t, test.outputKey, hex.EncodeToString(
+				outputKey.XOnly(),
+			))
+
+			for i, want := range test.controlBlocks {
+				cb, err := tree.ControlBlock(internalKey, i)
+				require.NoError(t, err)
+				require.Equal(t, want, hex.EncodeToString(cb.Serialize()))
+
+				parsed, err := ParseControlBlock(mustHex(t, want))
+				require.NoError(t, err)
+				require.NoError(t, parsed.Verify(
+					outputKey.XOnly(), tree.Leaf(i).Script,
+				))
+
+				// Flipping the parity bit must break the proof.
+				parsed.OutputKeyYIsOdd = !parsed.OutputKeyYIsOdd
+				require.Error(t, parsed.Verify(
+					outputKey.XOnly(), tree.Leaf(i).Script,
+				))
+			}
+		})
+	}
+}
+
+func TestTreeShape(t *testing.T) {
+	var leaves []Leaf
+	for i := 0; i < 5; i++ {
+		leaves = append(leaves, NewBaseLeaf(script.Script{
+			{Opcode: script.OP_1 + script.Opcode(i)},
+		}))
+	}
+
+	tree, err := AssembleTree(leaves...)
+	require.NoError(t, err)
+	require.Equal(t, 5, tree.NumLeaves())
+
+	priv, err := privatekey.New(big.NewInt(0xc0ffee))
+	require.NoError(t, err)
+
+	outputKey, err := ComputeOutputKey(priv.PubKey, tree.RootHash())
+	require.NoError(t, err)
+
+	// The first four leaves sit at depth three and the carried up fifth
+	// leaf at depth one.
+	depths := []int{3, 3, 3, 3, 1}
+	for i := 0; i < tree.NumLeaves(); i++ {
+		cb, err := tree.ControlBlock(priv.PubKey, i)
+		require.NoError(t, err)
+		require.Len(t, cb.InclusionProof, depths[i])
+		require.Equal(t, leaves[i], tree.Leaf(i))
+
+		parsed, err := ParseControlBlock(cb.Serialize())
+		require.NoError(t, err)
+		require.NoError(t, parsed.Verify(outputKey.XOnly(), leaves[i].Script))
+
+		// The proof must not verify for any other leaf.
+		other := leaves[(i+1)%len(leaves)]
+		require.Error(t, parsed.Verify(outputKey.XOnly(), other.Script))
+	}
+
+	_, err = tree.ControlBlock(priv.PubKey, 5)
+	require.Error(t, err)
+
+	_, err = AssembleTree()
+	require.Error(t, err)
+
+	for _, size := range []int{0, 32, 34, 33 + 32*129} {
+		_, err := ParseControlBlock(make([]byte, size))
+		require.Error(t, err)
+	}
+}
+
+func TestTweakPrivateKey(t *testing.T) {
+	priv, err := privatekey.New(big.NewInt(0xc0ffee))
+	require.NoError(t, err)
+
+	merkleRoot := make([]byte, 32)
+	for _, root := range [][]byte{nil, merkleRoot} {
+		outputKey, err := ComputeOutputKey(priv.PubKey, root)
+		require.NoError(t, err)
+
+		tweaked, err := TweakPrivateKey(priv, root)
+		require.NoError(t, err)
+		require.Equal(t, outputKey.XOnly(), tweaked.PubKey.XOnly())
+
+		msg := make([]byte, 32)
+		sig, err := tweaked.SignSchnorr(msg, make([]byte, 32))
+		require.NoError(t, err)
+
+		ok, err := outputKey.VerifySchnorr(msg, sig)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
@@ -0,0 +1,155 @@
+package taproot
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/s256point"
+)
+
+// Node is a node of a taproot script tree: either a leaf or a branch with
+// two children.
+type Node struct {
+	leaf        *Leaf
+	left, right *Node
+	hash        []byte
+}
+
+// NewLeafNode returns a tree node holding the leaf.
+func NewLeafNode(l Leaf) (*Node, error) {
+	h, err := l.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Node{leaf: &l, hash: h}, nil
+}
+
+// NewBranch returns a tree node with the two given children.
+func NewBranch(left, right *Node) *Node {
+	return &Node{
+		left:  left,
+		right: right,
+		hash:  BranchHash(left.hash, right.hash),
+	}
+}
+
+// Hash returns the TapLeaf hash of a leaf node or the TapBranch hash of a
+// branch.
+func (n *Node) Hash() []byte {
+	return n.hash
+}
+
+// Tree is a taproot script tree along with the inclusion proof of each of
+// its leaves.
+type Tree struct {
+	Root *Node
+
+	leaves []*Node
+	proofs [][][]byte
+}
+
+// NewTree returns the tree with the given root node. Leaves are numbered in
+// depth first order from left to right.
+func NewTree(root *Node) *Tree {
+	t := &Tree{Root: root}
+	t.walk(root, nil)
+
+	return t
+}
+
+// walk records each leaf below n along with its inclusion proof: the hashes
+// of the siblings on the path from the leaf up to the root.
+func (t *Tree) walk(n *Node, path [][]byte) {
+	if n.leaf != nil {
+		proof := make([][]byte, len(path))
+		for i := range path {
+			proof[i] = path[len(path)-1-i]
+		}
+
+		t.leaves = append(t.leaves, n)
+		t.proofs = append(t.proofs, proof)
+
+		return
+	}
+
+	t.walk(n.left, append(append([][]byte{}, path...), n.right.hash))
+	t.walk(n.right, append(append([][]byte{}, path...), n.left.hash))
+}
+
+// AssembleTree builds a balanced tree from the leaves by repeatedly pairing
+// adjacent nodes, carrying an odd node up to the next level.
+func AssembleTree(leaves ...Leaf) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("script tree must have at least one leaf")
+	}
+
+	nodes := make([]*Node, 0, len(leaves))
+	for _, l := range leaves {
+		n, err := NewLeafNode(l)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	for len(nodes) > 1 {
+		var next []*Node
+		for i := 0; i+1 < len(nodes); i += 2 {
+			next = append(next, NewBranch(nodes[i], nodes[i+1]))
+		}
+		if len(nodes)%2 == 1 {
+			next = append(next, nodes[len(nodes)-1])
+		}
+		nodes = next
+	}
+
+	return NewTree(nodes[0]), nil
+}
+
+// RootHash returns the merkle root of the tree.
+func (t *Tree) RootHash() []byte {
+	return t.Root.hash
+}
+
+// NumLeaves returns the number of leaves in the tree.
+func (t *Tree) NumLeaves() int {
+	return len(t.leaves)
+}
+
+// Leaf returns the leaf at the given index.
+func (t *Tree) Leaf(i int) Leaf {
+	return *t.leaves[i].leaf
+}
+
+// ControlBlock returns the control block needed to spend the leaf at the
+// given index from the output committing to this tree and the internal key.
+func (t *Tree) ControlBlock(internalKey *s256point.S256Point,
+	i int) (*ControlBlock, error) {
+
+	if i < 0 || i >= len(t.leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range", i)
+	}
+
+	if len(t.proofs[i]) > ControlBlockMaxNodeCount {
+		return nil, fmt.Errorf("leaf %d is deeper than %d", i,
+			ControlBlockMaxNodeCount)
+	}
+
+	outputKey, err := ComputeOutputKey(internalKey, t.RootHash())
+	if err != nil {
+		return nil, err
+	}
+
+	internal, err := evenKey(internalKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ControlBlock{
+		LeafVersion:     t.leaves[i].leaf.Version,
+		OutputKeyYIsOdd: !outputKey.HasEvenY(),
+		InternalKey:     internal,
+		InclusionProof:  t.proofs[i],
+	}, nil
+}
@@ -0,0 +1,254 @@
+package tx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/script"
+)
+
+const (
+	// SigHashDefault is only valid for taproot signatures and commits to
+	// the same data as SigHashAll.
+	SigHashDefault      uint32 = 0x00
+	SigHashAll          uint32 = 0x01
+	SigHashNone         uint32 = 0x02
+	SigHashSingle       uint32 = 0x03
+	SigHashAnyoneCanPay uint32 = 0x80
+
+	sigHashMask uint32 = 0x1f
+)
+
+// sigHashSingleBug returns the digest that legacy signature hashing commits
+// to when SIGHASH_SINGLE is used on an input without a matching output. It is
+// the little endian encoding of the number one.
+func sigHashSingleBug() []byte {
+	b := make([]byte, 32)
+	b[0] = 0x01
+
+	return b
+}
+
+// SigHash computes the pre-segwit signature hash for the input at inputIndex
+// given the scriptPubKey (or redeem script for P2SH) being spent. Any
+// OP_CODESEPARATORs are removed from the script, but the signature is not as
+// it isn't known yet when signing. Use SigHashForSignature to verify a
+// signature outside of the script engine. The result is the digest that gets
+// signed and can be passed directly to PrivateKey.Sign and S256Point.Verify.
+func (tx *Tx) SigHash(inputIndex int, prevScriptPubKey script.Script,
+	hashType uint32) ([]byte, error) {
+
+	if inputIndex < 0 || inputIndex >= len(tx.TxIns) {
+		return nil, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	baseType := hashType & sigHashMask
+	if baseType == SigHashSingle && inputIndex >= len(tx.TxOuts) {
+		return sigHashSingleBug(), nil
+	}
+
+	scriptCode, err := prevScriptPubKey.RemoveCodeSeparators().SerializeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	txCopy := &Tx{
+		Version:  tx.Version,
+		Locktime: tx.Locktime,
+	}
+
+	for i, in := range tx.TxIns {
+		if hashType&SigHashAnyoneCanPay != 0 && i != inputIndex {
+			continue
+		}
+
+		inCopy := &TxIn{
+			PrevTx:    in.PrevTx,
+			PrevIndex: in.PrevIndex,
+			Sequence:  in.Sequence,
+		}
+
+		if i == inputIndex {
+			inCopy.ScriptSig = scriptCode
+		} else if baseType == SigHashNone || baseType == SigHashSingle {
+			inCopy.Sequence = 0
+		}
+
+		txCopy.TxIns = append(txCopy.TxIns, inCopy)
+	}
+
+	switch baseType {
+	case SigHashNone:
+		txCopy.TxOuts = nil
+
+	case SigHashSingle:
+		for i := 0; i < inputIndex; i++ {
+			txCopy.TxOuts = append(txCopy.TxOuts, &TxOut{
+				Amount: ^uint64(0),
+			})
+		}
+		txCopy.TxOuts = append(txCopy.TxOuts, tx.TxOuts[inputIndex])
+
+	default:
+		txCopy.TxOuts = tx.TxOuts
+	}
+
+	b, err := txCopy.SerializeLegacy()
+	if err != nil {
+		return nil, err
+	}
+
+	b = append(b, uint32Bytes(hashType)...)
+
+	return helpers.DoubleSha256(b), nil
+}
+
+// SigHashForSignature computes the pre-segwit signature hash that sig, a DER
+// signature followed by its hash type byte, commits to. As in consensus, every
+// push of sig is first removed from prevScriptPubKey with FindAndDelete, so
+// the result matches the digest the script engine checks sig against.
+func (tx *Tx) SigHashForSignature(inputIndex int,
+	prevScriptPubKey script.Script, sig []byte) ([]byte, error) {
+
+	if len(sig) == 0 {
+		return nil, errors.New("signature is empty")
+	}
+
+	hashType := uint32(sig[len(sig)-1])
+
+	return tx.SigHash(
+		inputIndex, prevScriptPubKey.FindAndDelete(sig), hashType,
+	)
+}
+
+// SigHashes holds the midstate hashes that are identical for every input of
+// a tx. Computing these once with NewSigHashes or NewTaprootSigHashes and
+// passing them to the SigHashWith methods makes signing all inputs of a tx
+// linear rather than quadratic in the number of inputs. They commit to the
+// tx as it was when they were computed and so must be computed again if the
+// tx changes. The hashes are single SHA256 as used by BIP341; BIP143 commits
+// to the double SHA256 of the same data.
+type SigHashes struct {
+	shaPrevouts  []byte
+	shaSequences []byte
+	shaOutputs   []byte
+
+	// The following commit to the outputs being spent and are only set
+	// by NewTaprootSigHashes.
+	prevOuts         []*TxOut
+	shaAmounts       []byte
+	shaScriptPubKeys []byte
+}
+
+// NewSigHashes computes the signature hash midstate of the tx.
+func NewSigHashes(tx *Tx) (*SigHashes, error) {
+	var prevouts, sequences, outputs bytes.Buffer
+	for _, in := range tx.TxIns {
+		prevouts.Write(reverse(in.PrevTx))
+		prevouts.Write(uint32Bytes(in.PrevIndex))
+		sequences.Write(uint32Bytes(in.Sequence))
+	}
+
+	for _, out := range tx.TxOuts {
+		ser, err := out.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		outputs.Write(ser)
+	}
+
+	return &SigHashes{
+		shaPrevouts:  helpers.Sha256(prevouts.Bytes()),
+		shaSequences: helpers.Sha256(sequences.Bytes()),
+		shaOutputs:   helpers.Sha256(outputs.Bytes()),
+	}, nil
+}
+
+// SegwitSigHash computes the BIP143 signature hash for a segwit v0 input.
+// The scriptCode is the P2PKH script of the key hash for P2WPKH inputs and
+// the witness script for P2WSH inputs. The amount is the value in satoshis
+// of the output being spent. The preimage is made up of:
+// - version: 4 bytes, little endian
+// - hashPrevouts: 32 bytes
+// - hashSequence: 32 bytes
+// - outpoint being spent: 36 bytes
+// - scriptCode: varint length prefixed
+// - amount: 8 bytes, little endian
+// - sequence: 4 bytes, little endian
+// - hashOutputs: 32 bytes
+// - locktime: 4 bytes, little endian
+// - sighash type: 4 bytes, little endian
+//
+// The midstate is computed on every call. Use SegwitSigHashWith when signing
+// many inputs of the same tx.
+func (tx *Tx) SegwitSigHash(inputIndex int, scriptCode script.Script,
+	amount uint64, hashType uint32) ([]byte, error) {
+
+	h, err := NewSigHashes(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.SegwitSigHashWith(h, inputIndex, scriptCode, amount, hashType)
+}
+
+// SegwitSigHashWith is like SegwitSigHash but uses the given midstate, which
+// must have been computed by NewSigHashes for the tx in its current form.
+func (tx *Tx) SegwitSigHashWith(h *SigHashes, inputIndex int,
+	scriptCode script.Script, amount uint64, hashType uint32) ([]byte,
+	error) {
+
+	if inputIndex < 0 || inputIndex >= len(tx.TxIns) {
+		return nil, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	var (
+		zero         = make([]byte, 32)
+		baseType     = hashType & sigHashMask
+		anyoneCanPay = hashType&SigHashAnyoneCanPay != 0
+		in           = tx.TxIns[inputIndex]
+	)
+
+	hashPrevouts := zero
+	if !anyoneCanPay {
+		hashPrevouts = helpers.Sha256(h.shaPrevouts)
+	}
+
+	hashSequence := zero
+	if !anyoneCanPay && baseType != SigHashSingle && baseType != SigHashNone {
+		hashSequence = helpers.Sha256(h.shaSequences)
+	}
+
+	hashOutputs := zero
+	if baseType != SigHashSingle && baseType != SigHashNone {
+		hashOutputs = helpers.Sha256(h.shaOutputs)
+	} else if baseType == SigHashSingle && inputIndex < len(tx.TxOuts) {
+		out, err := tx.TxOuts[inputIndex].Serialize()
+		if err != nil {
+			return nil, err
+		}
+		hashOutputs = helpers.DoubleSha256(out)
+	}
+
+	code, err := scriptCode.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(uint32Bytes(uint32(tx.Version)))
+	b.Write(hashPrevouts)
+	b.Write(hashSequence)
+	b.Write(reverse(in.PrevTx))
+	b.Write(uint32Bytes(in.PrevIndex))
+	b.Write(code)
+	b.Write(uint64Bytes(amount))
+	b.Write(uint32Bytes(in.Sequence))
+	b.Write(hashOutputs)
+	b.Write(uint32Bytes(tx.Locktime))
+	b.Write(uint32Bytes(hashType))
+
+	return helpers.DoubleSha256(b.Bytes()), nil
+}
@@ -0,0 +1,253 @@
+package tx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/varint"
+)
+
+const (
+	tapSighashTag = "TapSighash"
+
+	// sigHashEpoch is the epoch byte prefixed to every taproot signature
+	// message.
+	sigHashEpoch byte = 0x00
+
+	// tapKeyVersion is the key_version committed to in script path
+	// signatures as defined by BIP342.
+	tapKeyVersion byte = 0x00
+
+	// NoCodeSeparator is the code separator position to use when no
+	// OP_CODESEPARATOR has been executed.
+	NoCodeSeparator uint32 = 0xffffffff
+)
+
+// TaprootSigHash computes the BIP341 signature hash for a key path spend of
+// the input at inputIndex. prevOuts must contain the outputs being spent by
+// every input of the tx, in input order. A nil annex means that no annex is
+// present in the witness.
+//
+// The midstate is computed on every call. Use TaprootSigHashWith when signing
+// many inputs of the same tx.
+func (tx *Tx) TaprootSigHash(inputIndex int, prevOuts []*TxOut,
+	hashType uint32, annex []byte) ([]byte, error) {
+
+	h, err := NewTaprootSigHashes(tx, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.TaprootSigHashWith(h, inputIndex, hashType, annex)
+}
+
+// TaprootSigHashWith is like TaprootSigHash but uses the given midstate,
+// which must have been computed by NewTaprootSigHashes for the tx in its
+// current form.
+func (tx *Tx) TaprootSigHashWith(h *SigHashes, inputIndex int,
+	hashType uint32, annex []byte) ([]byte, error) {
+
+	msg, err := tx.taprootSigMsg(
+		h, inputIndex, hashType, annex, nil, NoCodeSeparator,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.TaggedHash(tapSighashTag, msg), nil
+}
+
+// TapscriptSigHash computes the BIP341 signature hash for a script path
+// spend of the input at inputIndex, with the BIP342 extension committing to
+// the tapleaf hash of the executed script and the position of the last
+// executed OP_CODESEPARATOR.
+func (tx *Tx) TapscriptSigHash(inputIndex int, prevOuts []*TxOut,
+	hashType uint32, annex, leafHash []byte,
+	codeSepPos uint32) ([]byte, error) {
+
+	h, err := NewTaprootSigHashes(tx, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.TapscriptSigHashWith(
+		h, inputIndex, hashType, annex, leafHash, codeSepPos,
+	)
+}
+
+// TapscriptSigHashWith is like TapscriptSigHash but uses the given midstate,
+// which must have been computed by NewTaprootSigHashes for the tx in its
+// current form.
+func (tx *Tx) TapscriptSigHashWith(h *SigHashes, inputIndex int,
+	hashType uint32, annex, leafHash []byte,
+	codeSepPos uint32) ([]byte, error) {
+
+	if len(leafHash) != 32 {
+		return nil, errors.New("tapleaf hash must be 32 bytes")
+	}
+
+	msg, err := tx.taprootSigMsg(
+		h, inputIndex, hashType, annex, leafHash, codeSepPos,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.TaggedHash(tapSighashTag, msg), nil
+}
+
+// NewTaprootSigHashes computes the signature hash midstate of the tx
+// including the hashes of the amounts and scripts of prevOuts, which must
+// contain the outputs being spent by every input of the tx, in input order.
+func NewTaprootSigHashes(tx *Tx, prevOuts []*TxOut) (*SigHashes, error) {
+	if len(prevOuts) != len(tx.TxIns) {
+		return nil, fmt.Errorf("expected %d previous outputs, got %d",
+			len(tx.TxIns), len(prevOuts))
+	}
+
+	h, err := NewSigHashes(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var amounts, scriptPubKeys bytes.Buffer
+	for _, out := range prevOuts {
+		amounts.Write(uint64Bytes(out.Amount))
+
+		spk, err := serializeScript(out.ScriptPubKey)
+		if err != nil {
+			return nil, err
+		}
+		scriptPubKeys.Write(spk)
+	}
+
+	h.prevOuts = prevOuts
+	h.shaAmounts = helpers.Sha256(amounts.Bytes())
+	h.shaScriptPubKeys = helpers.Sha256(scriptPubKeys.Bytes())
+
+	return h, nil
+}
+
+func validTaprootHashType(hashType uint32) bool {
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay,
+		SigHashNone | SigHashAnyoneCanPay,
+		SigHashSingle | SigHashAnyoneCanPay:
+
+		return true
+	}
+
+	return false
+}
+
+/*
+- epoch: 1 byte (0x00)
+- hash type: 1 byte
+- version: 4 bytes, little endian
+- locktime: 4 bytes, little endian
+- if not ANYONECANPAY: sha_prevouts, sha_amounts, sha_scriptpubkeys,
+  sha_sequences: 32 bytes each
+- if not NONE or SINGLE: sha_outputs: 32 bytes
+- spend type: 1 byte (ext_flag * 2 + annex_present)
+- if ANYONECANPAY: outpoint (36), amount (8), scriptPubKey (varint prefixed),
+  sequence (4). Otherwise: input index (4)
+- if annex present: sha_annex: 32 bytes
+- if SINGLE: sha_single_output: 32 bytes
+- if script path: tapleaf hash (32), key version (1), codesep position (4)
+*/
+func (tx *Tx) taprootSigMsg(h *SigHashes, inputIndex int,
+	hashType uint32, annex, leafHash []byte,
+	codeSepPos uint32) ([]byte, error) {
+
+	if inputIndex < 0 || inputIndex >= len(tx.TxIns) {
+		return nil, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	if len(h.prevOuts) != len(tx.TxIns) {
+		return nil, errors.New("sighash midstate does not commit to " +
+			"the outputs being spent")
+	}
+
+	if !validTaprootHashType(hashType) {
+		return nil, fmt.Errorf("invalid taproot sighash type 0x%x",
+			hashType)
+	}
+
+	var (
+		baseType     = hashType & 0x03
+		anyoneCanPay = hashType&SigHashAnyoneCanPay != 0
+		in           = tx.TxIns[inputIndex]
+	)
+
+	if baseType == SigHashSingle && inputIndex >= len(tx.TxOuts) {
+		return nil, errors.New("SIGHASH_SINGLE used without a " +
+			"corresponding output")
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(sigHashEpoch)
+	b.WriteByte(byte(hashType))
+	b.Write(uint32Bytes(uint32(tx.Version)))
+	b.Write(uint32Bytes(tx.Locktime))
+
+	if !anyoneCanPay {
+		b.Write(h.shaPrevouts)
+		b.Write(h.shaAmounts)
+		b.Write(h.shaScriptPubKeys)
+		b.Write(h.shaSequences)
+	}
+
+	if baseType != SigHashNone && baseType != SigHashSingle {
+		b.Write(h.shaOutputs)
+	}
+
+	var spendType byte
+	if leafHash != nil {
+		spendType |= 0x02
+	}
+	if annex != nil {
+		spendType |= 0x01
+	}
+	b.WriteByte(spendType)
+
+	if anyoneCanPay {
+		prevOut, err := h.prevOuts[inputIndex].Serialize()
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(reverse(in.PrevTx))
+		b.Write(uint32Bytes(in.PrevIndex))
+		b.Write(prevOut)
+		b.Write(uint32Bytes(in.Sequence))
+	} else {
+		b.Write(uint32Bytes(uint32(inputIndex)))
+	}
+
+	if annex != nil {
+		length, err := varint.Encode(uint64(len(annex)))
+		if err != nil {
+			return nil, err
+		}
+		b.Write(helpers.Sha256(append(length, annex...)))
+	}
+
+	if baseType == SigHashSingle {
+		out, err := tx.TxOuts[inputIndex].Serialize()
+		if err != nil {
+			return nil, err
+		}
+		b.Write(helpers.Sha256(out))
+	}
+
+	if leafHash != nil {
+		b.Write(leafHash)
+		b.WriteByte(tapKeyVersion)
+		b.Write(uint32Bytes(codeSepPos))
+	}
+
+	return b.Bytes(), nil
+}
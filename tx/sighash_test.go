@@ -0,0 +1,462 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/ellemouton/btc/privatekey"
+	"github.com/ellemouton/btc/s256point"
+	"github.com/ellemouton/btc/script"
+	"github.com/ellemouton/btc/signature"
+	"github.com/stretchr/testify/require"
+)
+
+func parseScript(t *testing.T, s string) script.Script {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	res, err := script.Parse(b)
+	require.NoError(t, err)
+
+	return res
+}
+
+func rawScript(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestSigHash(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	prevScriptPubKey := parseScript(t, "1976a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+
+	z, err := tx.SigHash(0, prevScriptPubKey, SigHashAll)
+	require.NoError(t, err)
+	require.Equal(t, "27e0c5994dec7824e56dec6b2fcb342eb7cdb0d0957c2fce9882f715e85d81a6", hex.EncodeToString(z))
+
+	// The signature in the scriptSig must verify against the digest.
+	sig, err := signature.ParseFromString("3045022100ed81ff192e75a3fd2304004dcadb746fa5e24c5031ccfcf21320b0277457c98f02207a986d955c6e0cb35d446a89d3f56100f4d7f67801c31967743a9c8e10615bed")
+	require.NoError(t, err)
+
+	p, err := s256point.ParseFromString("0349fc4e631e3624a545de3f89f5d8684c7b8138bd94bdd531d2e213bf016b278a")
+	require.NoError(t, err)
+
+	valid, err := (&s256point.S256Point{Point: p}).Verify(z, sig)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestSigHashVectors(t *testing.T) {
+	// The sighash.json vectors of Bitcoin Core. Each is a tx, the script
+	// being spent, the input index, the hash type and the expected digest
+	// in reverse byte order.
+	b, err := ioutil.ReadFile("testdata/sighash.json")
+	require.NoError(t, err)
+
+	var vectors [][]interface{}
+	require.NoError(t, json.Unmarshal(b, &vectors))
+
+	for i, v := range vectors {
+		// Skip the header.
+		if len(v) == 1 {
+			continue
+		}
+
+		tx, err := ParseString(v[0].(string))
+		require.NoError(t, err, i)
+
+		prevScript, err := script.ParseRaw(rawScript(t, v[1].(string)))
+		require.NoError(t, err, i)
+
+		var (
+			inputIndex = int(v[2].(float64))
+			hashType   = uint32(int32(v[3].(float64)))
+		)
+
+		z, err := tx.SigHash(inputIndex, prevScript, hashType)
+		require.NoError(t, err, i)
+		require.Equal(t, v[4].(string), hex.EncodeToString(reverse(z)), i)
+	}
+}
+
+func TestSigHashSignAndVerify(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	privKey, err := privatekey.New(big.NewInt(8675309))
+	require.NoError(t, err)
+
+	prevScriptPubKey := parseScript(t, "1976a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+
+	for _, hashType := range []uint32{
+		SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay,
+	} {
+		z, err := tx.SigHash(0, prevScriptPubKey, hashType)
+		require.NoError(t, err)
+
+		sig, err := privKey.Sign(z)
+		require.NoError(t, err)
+
+		valid, err := privKey.PubKey.Verify(z, sig)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+}
+
+func TestSigHashTypes(t *testing.T) {
+	spk := parseScript(t, "1976a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+	spkRaw := rawScript(t, "76a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+
+	newTx := func() *Tx {
+		return &Tx{
+			Version: 1,
+			TxIns: []*TxIn{
+				{PrevTx: make([]byte, 32), PrevIndex: 0, Sequence: 0xffffffff},
+				{PrevTx: make([]byte, 32), PrevIndex: 1, Sequence: 0xffffffff},
+			},
+			TxOuts: []*TxOut{
+				{Amount: 1000, ScriptPubKey: spkRaw},
+				{Amount: 2000, ScriptPubKey: spkRaw},
+			},
+		}
+	}
+
+	digest := func(tx *Tx, idx int, hashType uint32) string {
+		z, err := tx.SigHash(idx, spk, hashType)
+		require.NoError(t, err)
+		return hex.EncodeToString(z)
+	}
+
+	base := newTx()
+
+	// Changing the other input's sequence only affects SIGHASH_ALL.
+	seqChanged := newTx()
+	seqChanged.TxIns[1].Sequence = 0
+	require.NotEqual(t, digest(base, 0, SigHashAll), digest(seqChanged, 0, SigHashAll))
+	require.Equal(t, digest(base, 0, SigHashNone), digest(seqChanged, 0, SigHashNone))
+	require.Equal(t, digest(base, 0, SigHashSingle), digest(seqChanged, 0, SigHashSingle))
+
+	// ANYONECANPAY does not commit to the other inputs at all.
+	inChanged := newTx()
+	inChanged.TxIns[1].PrevIndex = 7
+	require.NotEqual(t, digest(base, 0, SigHashAll), digest(inChanged, 0, SigHashAll))
+	require.Equal(t, digest(base, 0, SigHashAll|SigHashAnyoneCanPay), digest(inChanged, 0, SigHashAll|SigHashAnyoneCanPay))
+
+	// NONE commits to no outputs, SINGLE only to the matching one.
+	outChanged := newTx()
+	outChanged.TxOuts[1].Amount = 3000
+	require.NotEqual(t, digest(base, 0, SigHashAll), digest(outChanged, 0, SigHashAll))
+	require.Equal(t, digest(base, 0, SigHashNone), digest(outChanged, 0, SigHashNone))
+	require.Equal(t, digest(base, 0, SigHashSingle), digest(outChanged, 0, SigHashSingle))
+	require.NotEqual(t, digest(base, 1, SigHashSingle), digest(outChanged, 1, SigHashSingle))
+
+	// SIGHASH_SINGLE without a matching output signs the number one.
+	base.TxOuts = base.TxOuts[:1]
+	require.Equal(t, "0100000000000000000000000000000000000000000000000000000000000000", digest(base, 1, SigHashSingle))
+
+	// The digest is a fresh copy, so changing it affects no later result.
+	z, err := base.SigHash(1, spk, SigHashSingle)
+	require.NoError(t, err)
+	z[0] = 0xff
+	require.Equal(t, "0100000000000000000000000000000000000000000000000000000000000000", digest(base, 1, SigHashSingle))
+
+	_, err = base.SigHash(2, spk, SigHashAll)
+	require.Error(t, err)
+}
+
+func TestSigHashForSignature(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	// A signature with SIGHASH_ALL that is also pushed by the script, which
+	// is <sig> OP_DROP followed by the P2PKH script.
+	sig := append(make([]byte, 70), byte(SigHashAll))
+	p2pkh := parseScript(t, "1976a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+	withSig := append(script.Script{script.PushData(sig), {Opcode: script.OP_DROP}}, p2pkh...)
+	withoutSig := append(script.Script{{Opcode: script.OP_DROP}}, p2pkh...)
+
+	z, err := tx.SigHashForSignature(0, withSig, sig)
+	require.NoError(t, err)
+
+	want, err := tx.SigHash(0, withoutSig, SigHashAll)
+	require.NoError(t, err)
+	require.Equal(t, want, z)
+
+	unchanged, err := tx.SigHash(0, withSig, SigHashAll)
+	require.NoError(t, err)
+	require.NotEqual(t, unchanged, z)
+
+	_, err = tx.SigHashForSignature(0, withSig, nil)
+	require.Error(t, err)
+}
+
+func TestSigHashCodeSeparator(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	// OP_CODESEPARATOR followed by the P2PKH script.
+	withSep := parseScript(t, "1aab76a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+	without := parseScript(t, "1976a914a802fc56c704ce87c42d7c92eb75e7896bdc41ae88ac")
+
+	z1, err := tx.SigHash(0, withSep, SigHashAll)
+	require.NoError(t, err)
+
+	z2, err := tx.SigHash(0, without, SigHashAll)
+	require.NoError(t, err)
+
+	require.Equal(t, z2, z1)
+}
+
+func TestSegwitSigHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		tx         string
+		index      int
+		scriptCode string
+		amount     uint64
+		expect     string
+	}{
+		{
+			name:       "native P2WPKH",
+			tx:         segwitTx,
+			index:      1,
+			scriptCode: "1976a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac",
+			amount:     600000000,
+			expect:     "c37af31116d1b27caf68aae9e3ac82f1477929014d5b917657d0eb49478cb670",
+		},
+		{
+			name:       "P2SH-P2WPKH",
+			tx:         "0100000001db6b1b20aa0fd7b23880be2ecbd4a98130974cf4748fb66092ac4d3ceb1a54770100000000feffffff02b8b4eb0b000000001976a914a457b684d7f0d539a46a45bbc043f35b59d0d96388ac0008af2f000000001976a914fd270b1ee6abcaea97fea7ad0402e8bd8ad6d77c88ac92040000",
+			index:      0,
+			scriptCode: "1976a91479091972186c449eb1ded22b78e40d009bdf008988ac",
+			amount:     1000000000,
+			expect:     "64f3b0f4dd2bb3aa1ce8566d220cc74dda9df97d8490cc81d89d735c92e59fb6",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx, err := ParseString(test.tx)
+			require.NoError(t, err)
+
+			z, err := tx.SegwitSigHash(test.index, parseScript(t, test.scriptCode), test.amount, SigHashAll)
+			require.NoError(t, err)
+			require.Equal(t, test.expect, hex.EncodeToString(z))
+		})
+	}
+}
+
+func TestSegwitSigHashVerify(t *testing.T) {
+	tx, err := ParseString(segwitTx)
+	require.NoError(t, err)
+
+	scriptCode := parseScript(t, "1976a9141d0f172a0ecb48aee1be1f2687d2963ae33f71a188ac")
+
+	witness := tx.TxIns[1].Witness
+	der, hashType := witness[0][:len(witness[0])-1], witness[0][len(witness[0])-1]
+
+	z, err := tx.SegwitSigHash(1, scriptCode, 600000000, uint32(hashType))
+	require.NoError(t, err)
+
+	sig, err := signature.Parse(der)
+	require.NoError(t, err)
+
+	p, err := s256point.Parse(witness[1])
+	require.NoError(t, err)
+
+	valid, err := (&s256point.S256Point{Point: p}).Verify(z, sig)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// A precomputed midstate must give the same result for every hash
+	// type.
+	h, err := NewSigHashes(tx)
+	require.NoError(t, err)
+
+	for _, hashType := range []uint32{
+		SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay, SigHashSingle | SigHashAnyoneCanPay,
+	} {
+		z1, err := tx.SegwitSigHashWith(h, 0, scriptCode, 1, hashType)
+		require.NoError(t, err)
+
+		z2, err := tx.SegwitSigHash(0, scriptCode, 1, hashType)
+		require.NoError(t, err)
+		require.Equal(t, z2, z1)
+	}
+
+	// Changing the tx after a sighash has been computed changes the
+	// next one.
+	tx.TxOuts[0].Amount++
+	z2, err := tx.SegwitSigHash(1, scriptCode, 600000000, uint32(hashType))
+	require.NoError(t, err)
+	require.NotEqual(t, z, z2)
+}
+
+func TestTaprootSigHash(t *testing.T) {
+	p2tr := rawScript(t, "5120"+"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+
+	newTx := func() (*Tx, []*TxOut) {
+		tx := &Tx{
+			Version: 2,
+			TxIns: []*TxIn{
+				{PrevTx: make([]byte, 32), PrevIndex: 0, Sequence: 0xffffffff},
+				{PrevTx: make([]byte, 32), PrevIndex: 1, Sequence: 0xfffffffd},
+			},
+			TxOuts: []*TxOut{
+				{Amount: 1000, ScriptPubKey: p2tr},
+			},
+			Segwit: true,
+		}
+
+		prevOuts := []*TxOut{
+			{Amount: 5000, ScriptPubKey: p2tr},
+			{Amount: 6000, ScriptPubKey: p2tr},
+		}
+
+		return tx, prevOuts
+	}
+
+	digest := func(tx *Tx, prevOuts []*TxOut, idx int, hashType uint32, annex []byte) string {
+		z, err := tx.TaprootSigHash(idx, prevOuts, hashType, annex)
+		require.NoError(t, err)
+		require.Len(t, z, 32)
+		return hex.EncodeToString(z)
+	}
+
+	tx, prevOuts := newTx()
+
+	// SIGHASH_DEFAULT commits to the same data as SIGHASH_ALL but the
+	// hash type itself is part of the message.
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, nil), digest(tx, prevOuts, 0, SigHashAll, nil))
+
+	// The annex is committed to.
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, nil), digest(tx, prevOuts, 0, SigHashDefault, []byte{0x50}))
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, []byte{0x50}), digest(tx, prevOuts, 0, SigHashDefault, []byte{0x50, 0x01}))
+
+	// All spent amounts are committed to unless ANYONECANPAY is used.
+	tx2, prevOuts2 := newTx()
+	prevOuts2[1].Amount = 1
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, nil), digest(tx2, prevOuts2, 0, SigHashDefault, nil))
+	require.Equal(t, digest(tx, prevOuts, 0, SigHashAll|SigHashAnyoneCanPay, nil), digest(tx2, prevOuts2, 0, SigHashAll|SigHashAnyoneCanPay, nil))
+
+	// Later calls on the same tx use the outputs they are given.
+	before := digest(tx, prevOuts, 0, SigHashDefault, nil)
+	prevOuts[1].Amount++
+	require.NotEqual(t, before, digest(tx, prevOuts, 0, SigHashDefault, nil))
+	prevOuts[1].Amount--
+
+	// NONE does not commit to the outputs.
+	tx3, prevOuts3 := newTx()
+	tx3.TxOuts[0].Amount = 1
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, nil), digest(tx3, prevOuts3, 0, SigHashDefault, nil))
+	require.Equal(t, digest(tx, prevOuts, 0, SigHashNone, nil), digest(tx3, prevOuts3, 0, SigHashNone, nil))
+
+	// Script path spends commit to the leaf and code separator position.
+	leaf := make([]byte, 32)
+	z1, err := tx.TapscriptSigHash(0, prevOuts, SigHashDefault, nil, leaf, NoCodeSeparator)
+	require.NoError(t, err)
+	z2, err := tx.TapscriptSigHash(0, prevOuts, SigHashDefault, nil, leaf, 3)
+	require.NoError(t, err)
+	require.NotEqual(t, z1, z2)
+	require.NotEqual(t, digest(tx, prevOuts, 0, SigHashDefault, nil), hex.EncodeToString(z1))
+
+	// Invalid uses.
+	_, err = tx.TaprootSigHash(0, prevOuts, 0x04, nil)
+	require.Error(t, err)
+
+	_, err = tx.TaprootSigHash(1, prevOuts, SigHashSingle, nil)
+	require.Error(t, err)
+
+	_, err = tx.TaprootSigHash(0, prevOuts[:1], SigHashDefault, nil)
+	require.Error(t, err)
+
+	_, err = tx.TapscriptSigHash(0, prevOuts, SigHashDefault, nil, nil, NoCodeSeparator)
+	require.Error(t, err)
+	// A midstate without the spent outputs can't be used for taproot.
+	h, err := NewSigHashes(tx)
+	require.NoError(t, err)
+	_, err = tx.TaprootSigHashWith(h, 0, SigHashDefault, nil)
+	require.Error(t, err)
+}
+
+func TestTaprootSigHashVectors(t *testing.T) {
+	// The keyPathSpending vectors of BIP341.
+	tx, err := ParseString("02000000097de20cbff686da83a54981d2b9bab3586f4ca7e48f57f5b55963115f3b334e9c010000000000000000d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd990000000000fffffffff8e1f583384333689228c5d28eac13366be082dc57441760d957275419a418420000000000fffffffff0689180aa63b30cb162a73c6d2a38b7eeda2a83ece74310fda0843ad604853b0100000000feffffffaa5202bdf6d8ccd2ee0f0202afbbb7461d9264a25e5bfd3c5a52ee1239e0ba6c0000000000feffffff956149bdc66faa968eb2be2d2faa29718acbfe3941215893a2a3446d32acd050000000000000000000e664b9773b88c09c32cb70a2a3e4da0ced63b7ba3b22f848531bbb1d5d5f4c94010000000000000000e9aa6b8e6c9de67619e6a3924ae25696bb7b694bb677a632a74ef7eadfd4eabf0000000000ffffffffa778eb6a263dc090464cd125c466b5a99667720b1c110468831d058aa1b82af10100000000ffffffff0200ca9a3b000000001976a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac807840cb0000000020ac9a87f5594be208f8532db38cff670c450ed2fea8fcdefcc9a663f78bab962b0065cd1d")
+	require.NoError(t, err)
+
+	utxos := []struct {
+		scriptPubKey string
+		amount       uint64
+	}{
+		{"512053a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343", 420000000},
+		{"5120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3", 462000000},
+		{"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac", 294000000},
+		{"5120e4d810fd50586274face62b8a807eb9719cef49c04177cc6b76a9a4251d5450e", 504000000},
+		{"512091b64d5324723a985170e4dc5a0f84c041804f2cd12660fa5dec09fc21783605", 630000000},
+		{"00147dd65592d0ab2fe0d0257d571abf032cd9db93dc", 378000000},
+		{"512075169f4001aa68f15bbed28b218df1d0a62cbbcf1188c6665110c293c907b831", 672000000},
+		{"5120712447206d7a5238acc7ff53fbe94a3b64539ad291c7cdbc490b7577e4b17df5", 546000000},
+		{"512077e30a5522dd9f894c3f8b8bd4c4b2cf82ca7da8a3ea6a239655c39c050ab220", 588000000},
+	}
+
+	var prevOuts []*TxOut
+	for _, utxo := range utxos {
+		prevOuts = append(prevOuts, &TxOut{
+			Amount:       utxo.amount,
+			ScriptPubKey: rawScript(t, utxo.scriptPubKey),
+		})
+	}
+
+	h, err := NewTaprootSigHashes(tx, prevOuts)
+	require.NoError(t, err)
+	require.Equal(t, "58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde6", hex.EncodeToString(h.shaAmounts))
+	require.Equal(t, "a2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc5", hex.EncodeToString(h.shaOutputs))
+	require.Equal(t, "e3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f", hex.EncodeToString(h.shaPrevouts))
+	require.Equal(t, "23ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e21", hex.EncodeToString(h.shaScriptPubKeys))
+	require.Equal(t, "18959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957e", hex.EncodeToString(h.shaSequences))
+
+	tests := []struct {
+		index    int
+		hashType uint32
+
+		// sigMsg is only given for some of the inputs.
+		sigMsg  string
+		sigHash string
+	}{
+		{0, SigHashSingle, "0003020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957e0000000000d0418f0e9a36245b9a50ec87f8bf5be5bcae434337b87139c3a5b1f56e33cba0", "2514a6272f85cfa0f45eb907fcb0d121b808ed37c6ea160a5a9046ed5526d555"},
+		{1, SigHashSingle | SigHashAnyoneCanPay, "0083020000000065cd1d00d7b7cab57b1393ace2d064f4d4a2cb8af6def61273e127517d44759b6dafdd9900000000808f891b00000000225120147c9c57132f6e7ecddba9800bb0c4449251c92a1e60371ee77557b6620f3ea3ffffffffffcef8fb4ca7efc5433f591ecfc57391811ce1e186a3793024def5c884cba51d", "325a644af47e8a5a2591cda0ab0723978537318f10e6a63d4eed783b96a71a4d"},
+		{3, SigHashAll, "0001020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957ea2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc50003000000", "bf013ea93474aa67815b1b6cc441d23b64fa310911d991e713cd34c7f5d46669"},
+		{4, SigHashDefault, "0000020000000065cd1de3b33bb4ef3a52ad1fffb555c0d82828eb22737036eaeb02a235d82b909c4c3f58a6964a4f5f8f0b642ded0a8a553be7622a719da71d1f5befcefcdee8e0fde623ad0f61ad2bca5ba6a7693f50fce988e17c3780bf2b1e720cfbb38fbdd52e2118959c7221ab5ce9e26c3cd67b22c24f8baa54bac281d8e6b05e400e6c3a957ea2e6dab7c1f0dcd297c8d61647fd17d821541ea69c3cc37dcbad7f90d4eb4bc50004000000", "4f900a0bae3f1446fd48490c2958b5a023228f01661cda3496a11da502a7f7ef"},
+		{6, SigHashNone, "", "15f25c298eb5cdc7eb1d638dd2d45c97c4c59dcaec6679cfc16ad84f30876b85"},
+		{7, SigHashNone | SigHashAnyoneCanPay, "", "cd292de50313804dabe4685e83f923d2969577191a3e1d2882220dca88cbeb10"},
+		{8, SigHashAll | SigHashAnyoneCanPay, "", "cccb739eca6c13a8a89e6e5cd317ffe55669bbda23f2fd37b0f18755e008edd2"},
+	}
+
+	for _, test := range tests {
+		if test.sigMsg != "" {
+			msg, err := tx.taprootSigMsg(
+				h, test.index, test.hashType, nil, nil,
+				NoCodeSeparator,
+			)
+			require.NoError(t, err)
+			require.Equal(t, test.sigMsg, hex.EncodeToString(msg))
+		}
+
+		z, err := tx.TaprootSigHashWith(h, test.index, test.hashType, nil)
+		require.NoError(t, err)
+		require.Equal(t, test.sigHash, hex.EncodeToString(z), test.index)
+
+		z, err = tx.TaprootSigHash(test.index, prevOuts, test.hashType, nil)
+		require.NoError(t, err)
+		require.Equal(t, test.sigHash, hex.EncodeToString(z), test.index)
+	}
+}
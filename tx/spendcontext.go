@@ -0,0 +1,72 @@
+package tx
+
+import "github.com/ellemouton/btc/script"
+
+// SpendContext is a tx together with the outputs spent by its inputs, which
+// is what the script engine needs to check taproot signatures. It implements
+// script.TaprootTxContext and computes the signature hash midstate once for
+// all inputs, so the tx must not change while the context is in use.
+type SpendContext struct {
+	tx     *Tx
+	hashes *SigHashes
+}
+
+// NewSpendContext returns the context of tx spending prevOuts, which must
+// contain the outputs being spent by every input of the tx, in input order.
+func NewSpendContext(tx *Tx, prevOuts []*TxOut) (*SpendContext, error) {
+	h, err := NewTaprootSigHashes(tx, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpendContext{tx: tx, hashes: h}, nil
+}
+
+// SigHash computes the pre-segwit signature hash of the input.
+func (c *SpendContext) SigHash(inputIndex int, subScript script.Script,
+	hashType uint32) ([]byte, error) {
+
+	return c.tx.SigHash(inputIndex, subScript, hashType)
+}
+
+// SegwitSigHash computes the BIP143 signature hash of the input.
+func (c *SpendContext) SegwitSigHash(inputIndex int, scriptCode script.Script,
+	amount uint64, hashType uint32) ([]byte, error) {
+
+	return c.tx.SegwitSigHashWith(
+		c.hashes, inputIndex, scriptCode, amount, hashType,
+	)
+}
+
+// TaprootSigHash computes the BIP341 signature hash of a key path spend of
+// the input.
+func (c *SpendContext) TaprootSigHash(inputIndex int, hashType uint32,
+	annex []byte) ([]byte, error) {
+
+	return c.tx.TaprootSigHashWith(c.hashes, inputIndex, hashType, annex)
+}
+
+// TapscriptSigHash computes the BIP342 signature hash of a script path spend
+// of the input.
+func (c *SpendContext) TapscriptSigHash(inputIndex int, hashType uint32,
+	annex, leafHash []byte, codeSepPos uint32) ([]byte, error) {
+
+	return c.tx.TapscriptSigHashWith(
+		c.hashes, inputIndex, hashType, annex, leafHash, codeSepPos,
+	)
+}
+
+// GetVersion returns the version of the tx.
+func (c *SpendContext) GetVersion() int64 {
+	return c.tx.GetVersion()
+}
+
+// GetLocktime returns the locktime of the tx.
+func (c *SpendContext) GetLocktime() uint32 {
+	return c.tx.GetLocktime()
+}
+
+// GetSequence returns the sequence of the input.
+func (c *SpendContext) GetSequence(inputIndex int) uint32 {
+	return c.tx.GetSequence(inputIndex)
+}
@@ -1,17 +1,79 @@
 package tx
 
-import "encoding/hex"
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/ellemouton/btc/helpers"
+	"github.com/ellemouton/btc/varint"
+)
+
+const (
+	segwitMarker byte = 0x00
+	segwitFlag   byte = 0x01
+
+	witnessScaleFactor = 4
+
+	// minTxInSize is the size of an input with an empty scriptSig: the
+	// outpoint, a one byte script length and the sequence.
+	minTxInSize = 32 + 4 + 1 + 4
+
+	// minTxOutSize is the size of an output with an empty scriptPubKey:
+	// the amount and a one byte script length.
+	minTxOutSize = 8 + 1
+)
 
 type Tx struct {
-	Version int64
-	// Inputs
-	// Outputs
-	// Locktime
+	// Version is signed as in Bitcoin Core, which reads and writes it as
+	// an int32. Versions outside the int32 range are truncated when
+	// serialized.
+	Version   int64
+	TxIns     []*TxIn
+	TxOuts    []*TxOut
+	Locktime  uint32
 	IsTestnet bool
+
+	// Segwit indicates that the tx should be serialized with the BIP144
+	// marker, flag and witness data.
+	Segwit bool
+}
+
+func (tx *Tx) GetVersion() int64 {
+	return tx.Version
+}
+
+func (tx *Tx) GetLocktime() uint32 {
+	return tx.Locktime
+}
+
+func (tx *Tx) GetSequence(inputIndex int) uint32 {
+	return tx.TxIns[inputIndex].Sequence
 }
 
+// Hash returns the double-SHA256 of the stripped (legacy) serialization of
+// the transaction in the reversed (little-endian) byte order used to display
+// txids.
 func (tx *Tx) Hash() ([]byte, error) {
-	return nil, nil
+	b, err := tx.SerializeLegacy()
+	if err != nil {
+		return nil, err
+	}
+
+	return reverse(helpers.DoubleSha256(b)), nil
+}
+
+// WitnessHash returns the wtxid of the transaction. This commits to the
+// witness data and is equal to Hash for non-segwit transactions.
+func (tx *Tx) WitnessHash() ([]byte, error) {
+	b, err := tx.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	return reverse(helpers.DoubleSha256(b)), nil
 }
 
 func (tx *Tx) ID() (string, error) {
@@ -23,12 +85,220 @@ func (tx *Tx) ID() (string, error) {
 	return hex.EncodeToString(h), nil
 }
 
+func (tx *Tx) WitnessID() (string, error) {
+	h, err := tx.WitnessHash()
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h), nil
+}
+
+// BaseSize is the size in bytes of the transaction without witness data.
+func (tx *Tx) BaseSize() (int, error) {
+	b, err := tx.SerializeLegacy()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// TotalSize is the size in bytes of the transaction including witness data.
+func (tx *Tx) TotalSize() (int, error) {
+	b, err := tx.Serialize()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// Weight returns the BIP141 weight of the transaction:
+// base size * 3 + total size.
+func (tx *Tx) Weight() (int, error) {
+	base, err := tx.BaseSize()
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := tx.TotalSize()
+	if err != nil {
+		return 0, err
+	}
+
+	return base*(witnessScaleFactor-1) + total, nil
+}
+
+// VSize returns the virtual size of the transaction which is its weight
+// divided by 4, rounded up.
+func (tx *Tx) VSize() (int, error) {
+	w, err := tx.Weight()
+	if err != nil {
+		return 0, err
+	}
+
+	return (w + witnessScaleFactor - 1) / witnessScaleFactor, nil
+}
+
+// Serialize serializes the transaction. If the tx is marked as Segwit then
+// the BIP144 encoding including witness data is used.
 func (tx *Tx) Serialize() ([]byte, error) {
-	return nil, nil
+	return tx.serialize(tx.Segwit)
+}
+
+// SerializeLegacy serializes the transaction without the marker, flag and
+// witness data.
+func (tx *Tx) SerializeLegacy() ([]byte, error) {
+	return tx.serialize(false)
+}
+
+/*
+- version: 4 bytes, little endian
+- if segwit: marker (0x00) and flag (0x01)
+- number of inputs: varint
+- inputs
+- number of outputs: varint
+- outputs
+- if segwit: witness stack for each input
+- locktime: 4 bytes, little endian
+*/
+func (tx *Tx) serialize(withWitness bool) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.Write(uint32Bytes(uint32(tx.Version)))
+
+	if withWitness {
+		b.Write([]byte{segwitMarker, segwitFlag})
+	}
+
+	numIns, err := varint.Encode(uint64(len(tx.TxIns)))
+	if err != nil {
+		return nil, err
+	}
+	b.Write(numIns)
+
+	for _, in := range tx.TxIns {
+		ser, err := in.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		b.Write(ser)
+	}
+
+	numOuts, err := varint.Encode(uint64(len(tx.TxOuts)))
+	if err != nil {
+		return nil, err
+	}
+	b.Write(numOuts)
+
+	for _, out := range tx.TxOuts {
+		ser, err := out.Serialize()
+		if err != nil {
+			return nil, err
+		}
+		b.Write(ser)
+	}
+
+	if withWitness {
+		for _, in := range tx.TxIns {
+			ser, err := in.serializeWitness()
+			if err != nil {
+				return nil, err
+			}
+			b.Write(ser)
+		}
+	}
+
+	b.Write(uint32Bytes(tx.Locktime))
+
+	return b.Bytes(), nil
 }
 
 func Parse(b []byte) (*Tx, error) {
-	return nil, nil
+	r := bytes.NewReader(b)
+
+	version, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// A zero byte where the input count is expected can only be the
+	// segwit marker since a tx without inputs is invalid.
+	var segwit bool
+	if r.Len() >= 2 && b[4] == segwitMarker {
+		if b[5] != segwitFlag {
+			return nil, errors.New("unknown segwit flag")
+		}
+		segwit = true
+
+		if _, err := r.Seek(2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+
+	numIns, err := varint.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each input is at least 41 bytes so anything claiming more inputs
+	// than could fit in the remaining data is malformed.
+	if numIns > uint64(r.Len()/minTxInSize) {
+		return nil, errors.New("input count exceeds tx length")
+	}
+
+	ins := make([]*TxIn, 0, numIns)
+	for i := uint64(0); i < numIns; i++ {
+		in, err := parseTxIn(r)
+		if err != nil {
+			return nil, err
+		}
+		ins = append(ins, in)
+	}
+
+	numOuts, err := varint.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if numOuts > uint64(r.Len()/minTxOutSize) {
+		return nil, errors.New("output count exceeds tx length")
+	}
+
+	outs := make([]*TxOut, 0, numOuts)
+	for i := uint64(0); i < numOuts; i++ {
+		out, err := parseTxOut(r)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, out)
+	}
+
+	if segwit {
+		for _, in := range ins {
+			if err := in.parseWitness(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	locktime, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("unexpected trailing bytes after locktime")
+	}
+
+	return &Tx{
+		Version:  int64(int32(version)),
+		TxIns:    ins,
+		TxOuts:   outs,
+		Locktime: locktime,
+		Segwit:   segwit,
+	}, nil
 }
 
 func ParseString(s string) (*Tx, error) {
@@ -39,3 +309,73 @@ func ParseString(s string) (*Tx, error) {
 
 	return Parse(b)
 }
+
+func readUint32(r io.Reader) (uint32, error) {
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func readUint64(r io.Reader) (uint64, error) {
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// readScript reads a varint length prefixed script from r and returns the
+// raw script bytes. The length is checked against the remaining data before
+// anything is allocated since it comes straight from untrusted input.
+func readScript(r *bytes.Reader) ([]byte, error) {
+	length, err := varint.ReadFrom(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if length > uint64(r.Len()) {
+		return nil, errors.New("script length exceeds tx length")
+	}
+
+	raw := make([]byte, length)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// serializeScript returns the raw script prefixed with its length as a
+// varint.
+func serializeScript(raw []byte) ([]byte, error) {
+	length, err := varint.Encode(uint64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(length, raw...), nil
+}
+
+func uint32Bytes(i uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, i)
+	return b
+}
+
+func uint64Bytes(i uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, i)
+	return b
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[i] = b[len(b)-1-i]
+	}
+	return r
+}
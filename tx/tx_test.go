@@ -0,0 +1,188 @@
+package tx
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/ellemouton/btc/script"
+	"github.com/stretchr/testify/require"
+)
+
+const legacyTx = "0100000001813f79011acb80925dfe69b3def355fe914bd1d96a3f5f71bf8303c6a989c7d1000000006b483045022100ed81ff192e75a3fd2304004dcadb746fa5e24c5031ccfcf21320b0277457c98f02207a986d955c6e0cb35d446a89d3f56100f4d7f67801c31967743a9c8e10615bed01210349fc4e631e3624a545de3f89f5d8684c7b8138bd94bdd531d2e213bf016b278afeffffff02a135ef01000000001976a914bc3b654dca7e56b04dca18f2566cdaf02e8d9ada88ac99c39800000000001976a9141c4bc762dd5423e332166702cb75f40df79fea1288ac19430600"
+
+func TestParse(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), tx.Version)
+	require.Equal(t, uint32(410393), tx.Locktime)
+
+	require.Len(t, tx.TxIns, 1)
+	require.Equal(t, "d1c789a9c60383bf715f3f6ad9d14b91fe55f3deb369fe5d9280cb1a01793f81", hex.EncodeToString(tx.TxIns[0].PrevTx))
+	require.Equal(t, uint32(0), tx.TxIns[0].PrevIndex)
+	require.Equal(t, uint32(0xfffffffe), tx.TxIns[0].Sequence)
+
+	scriptSig, err := script.ParseRaw(tx.TxIns[0].ScriptSig)
+	require.NoError(t, err)
+	require.Len(t, scriptSig, 2)
+
+	require.Len(t, tx.TxOuts, 2)
+	require.Equal(t, uint64(32454049), tx.TxOuts[0].Amount)
+	require.Equal(t, uint64(10011545), tx.TxOuts[1].Amount)
+
+	require.Equal(t, "76a9141c4bc762dd5423e332166702cb75f40df79fea1288ac", hex.EncodeToString(tx.TxOuts[1].ScriptPubKey))
+}
+
+func TestSerialize(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	b, err := tx.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, legacyTx, hex.EncodeToString(b))
+}
+
+func TestNegativeVersion(t *testing.T) {
+	// A version of 0xffffffff is -1, as Bitcoin Core reads it as an
+	// int32, and must serialize back to the same bytes.
+	raw := "ffffffff" + legacyTx[8:]
+
+	tx, err := ParseString(raw)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), tx.Version)
+
+	b, err := tx.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, raw, hex.EncodeToString(b))
+}
+
+func TestSerializeMalformedScripts(t *testing.T) {
+	// A coinbase tx whose scriptSig is an OP_PUSHDATA1 that claims 5
+	// bytes but has none, and whose output script is a truncated push.
+	// Consensus doesn't require either script to parse, so the tx must
+	// still round trip byte for byte.
+	raw := "01000000010000000000000000000000000000000000000000000000000000000000000000ffffffff024c05ffffffff0100f2052a01000000024d01" + "00000000"
+
+	tx, err := ParseString(raw)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x4c, 0x05}, tx.TxIns[0].ScriptSig)
+
+	_, err = script.ParseRaw(tx.TxIns[0].ScriptSig)
+	require.Error(t, err)
+
+	b, err := tx.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, raw, hex.EncodeToString(b))
+}
+
+func TestID(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	id, err := tx.ID()
+	require.NoError(t, err)
+	require.Equal(t, "452c629d67e41baec3ac6f04fe744b4b9617f8f859c63b3002f8684e7a4fee03", id)
+}
+
+func TestParseErrors(t *testing.T) {
+	b, err := hex.DecodeString(legacyTx)
+	require.NoError(t, err)
+
+	_, err = Parse(b[:len(b)-2])
+	require.Error(t, err)
+
+	_, err = Parse(append(b, 0x00))
+	require.Error(t, err)
+
+	// Lengths and counts that claim more data than the tx holds must be
+	// rejected without allocating for them.
+	tests := []string{
+		// A scriptSig length of 0xffffffffffffffff.
+		"0100000001" + hex.EncodeToString(make([]byte, 36)) +
+			"ffffffffffffffffff",
+		// An input count of 0xffffffff.
+		"01000000feffffffff" + hex.EncodeToString(make([]byte, 41)),
+		// One empty input followed by an output count of 0xffffffff.
+		"0100000001" + hex.EncodeToString(make([]byte, 41)) +
+			"feffffffff" + hex.EncodeToString(make([]byte, 9)),
+		// A segwit tx whose only input has 0xffffffff witness items.
+		"010000000001" + "01" + hex.EncodeToString(make([]byte, 41)) +
+			"00" + "feffffffff" + "00000000",
+	}
+
+	for _, test := range tests {
+		_, err := ParseString(test)
+		require.Error(t, err, test)
+	}
+}
+
+// Signed native P2WPKH example from BIP143.
+const segwitTx = "01000000000102fff7f7881a8099afa6940d42d1e7f6362bec38171ea3edf433541db4e4ad969f00000000494830450221008b9d1dc26ba6a9cb62127b02742fa9d754cd3bebf337f7a55d114c8e5cdd30be022040529b194ba3f9281a99f2b1c0a19c0489bc22ede944ccf4ecbab4cc618ef3ed01eeffffffef51e1b804cc89d182d279655c3aa89e815b1b309fe287d9b2b55d57b90ec68a0100000000ffffffff02202cb206000000001976a9148280b37df378db99f66f85c95a783a76ac7a6d5988ac9093510d000000001976a9143bde42dbee7e4dbe6a21b2d50ce2f0167faa815988ac000247304402203609e17b84f6a7d30c80bfa610b5b4542f32a8a0d5447a12fb1366d7f01cc44a0220573a954c4518331561406f90300e8f3358f51928d43c212a8caed02de67eebee0121025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee635711000000"
+
+func TestSegwit(t *testing.T) {
+	tx, err := ParseString(segwitTx)
+	require.NoError(t, err)
+
+	require.True(t, tx.Segwit)
+	require.Len(t, tx.TxIns, 2)
+	require.Len(t, tx.TxIns[0].Witness, 0)
+	require.Len(t, tx.TxIns[1].Witness, 2)
+	require.Equal(t, "025476c2e83188368da1ff3e292e7acafcdb3566bb0ad253f62fc70f07aeee6357", hex.EncodeToString(tx.TxIns[1].Witness[1]))
+	require.Equal(t, uint32(17), tx.Locktime)
+
+	b, err := tx.Serialize()
+	require.NoError(t, err)
+	require.Equal(t, segwitTx, hex.EncodeToString(b))
+
+	id, err := tx.ID()
+	require.NoError(t, err)
+	require.Equal(t, "e8151a2af31c368a35053ddd4bdb285a8595c769a3ad83e0fa02314a602d4609", id)
+
+	wid, err := tx.WitnessID()
+	require.NoError(t, err)
+	require.Equal(t, "c36c38370907df2324d9ce9d149d191192f338b37665a82e78e76a12c909b762", wid)
+
+	base, err := tx.BaseSize()
+	require.NoError(t, err)
+	require.Equal(t, 233, base)
+
+	total, err := tx.TotalSize()
+	require.NoError(t, err)
+	require.Equal(t, 343, total)
+
+	weight, err := tx.Weight()
+	require.NoError(t, err)
+	require.Equal(t, 1042, weight)
+
+	vsize, err := tx.VSize()
+	require.NoError(t, err)
+	require.Equal(t, 261, vsize)
+
+	// The stripped serialization parses as a legacy tx with the same txid.
+	stripped, err := tx.SerializeLegacy()
+	require.NoError(t, err)
+
+	legacy, err := Parse(stripped)
+	require.NoError(t, err)
+	require.False(t, legacy.Segwit)
+
+	legacyID, err := legacy.ID()
+	require.NoError(t, err)
+	require.Equal(t, id, legacyID)
+}
+
+func TestLegacySizes(t *testing.T) {
+	tx, err := ParseString(legacyTx)
+	require.NoError(t, err)
+
+	id, err := tx.ID()
+	require.NoError(t, err)
+
+	wid, err := tx.WitnessID()
+	require.NoError(t, err)
+	require.Equal(t, id, wid)
+
+	weight, err := tx.Weight()
+	require.NoError(t, err)
+	require.Equal(t, len(legacyTx)/2*4, weight)
+}
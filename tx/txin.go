@@ -0,0 +1,137 @@
+package tx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/ellemouton/btc/varint"
+)
+
+type TxIn struct {
+	// PrevTx is the txid of the transaction being spent in the usual
+	// display (big endian) byte order.
+	PrevTx    []byte
+	PrevIndex uint32
+
+	// ScriptSig holds the raw script without its length prefix. Consensus
+	// allows any bytes here, not only well formed scripts, so they are
+	// kept as they are and only parsed with script.ParseRaw when the
+	// script is executed or classified.
+	ScriptSig []byte
+	Sequence  uint32
+
+	// Witness is the stack of witness items for the input. It is only
+	// serialized if the owning tx is a segwit tx.
+	Witness [][]byte
+}
+
+/*
+- previous tx id: 32 bytes, little endian
+- previous tx index: 4 bytes, little endian
+- script sig: varint length prefixed
+- sequence: 4 bytes, little endian
+*/
+func (in *TxIn) Serialize() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.Write(reverse(in.PrevTx))
+	b.Write(uint32Bytes(in.PrevIndex))
+
+	scriptSig, err := serializeScript(in.ScriptSig)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(scriptSig)
+
+	b.Write(uint32Bytes(in.Sequence))
+
+	return b.Bytes(), nil
+}
+
+func parseTxIn(r *bytes.Reader) (*TxIn, error) {
+	prevTx := make([]byte, 32)
+	if _, err := io.ReadFull(r, prevTx); err != nil {
+		return nil, err
+	}
+
+	prevIndex, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptSig, err := readScript(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, err := readUint32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxIn{
+		PrevTx:    reverse(prevTx),
+		PrevIndex: prevIndex,
+		ScriptSig: scriptSig,
+		Sequence:  sequence,
+	}, nil
+}
+
+/*
+- number of witness items: varint
+- each item: varint length prefixed bytes
+*/
+func (in *TxIn) serializeWitness() ([]byte, error) {
+	var b bytes.Buffer
+
+	num, err := varint.Encode(uint64(len(in.Witness)))
+	if err != nil {
+		return nil, err
+	}
+	b.Write(num)
+
+	for _, item := range in.Witness {
+		length, err := varint.Encode(uint64(len(item)))
+		if err != nil {
+			return nil, err
+		}
+		b.Write(length)
+		b.Write(item)
+	}
+
+	return b.Bytes(), nil
+}
+
+func (in *TxIn) parseWitness(r *bytes.Reader) error {
+	num, err := varint.ReadFrom(r)
+	if err != nil {
+		return err
+	}
+
+	if num > uint64(r.Len()) {
+		return errors.New("witness item count exceeds tx length")
+	}
+
+	witness := make([][]byte, 0, num)
+	for i := uint64(0); i < num; i++ {
+		length, err := varint.ReadFrom(r)
+		if err != nil {
+			return err
+		}
+
+		if length > uint64(r.Len()) {
+			return errors.New("witness item length exceeds tx length")
+		}
+
+		item := make([]byte, length)
+		if _, err := io.ReadFull(r, item); err != nil {
+			return err
+		}
+		witness = append(witness, item)
+	}
+
+	in.Witness = witness
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package tx
+
+import "bytes"
+
+type TxOut struct {
+	// Amount is the value of the output in satoshis.
+	Amount uint64
+
+	// ScriptPubKey holds the raw script without its length prefix. Like
+	// TxIn.ScriptSig it need not be a well formed script.
+	ScriptPubKey []byte
+}
+
+/*
+- amount: 8 bytes, little endian
+- script pubkey: varint length prefixed
+*/
+func (out *TxOut) Serialize() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.Write(uint64Bytes(out.Amount))
+
+	scriptPubKey, err := serializeScript(out.ScriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(scriptPubKey)
+
+	return b.Bytes(), nil
+}
+
+func parseTxOut(r *bytes.Reader) (*TxOut, error) {
+	amount, err := readUint64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	scriptPubKey, err := readScript(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TxOut{
+		Amount:       amount,
+		ScriptPubKey: scriptPubKey,
+	}, nil
+}
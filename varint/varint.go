@@ -3,6 +3,7 @@ package varint
 import (
 	"encoding/binary"
 	"errors"
+	"io"
 )
 
 func Read(b []byte) uint64 {
@@ -24,6 +25,34 @@ func Read(b []byte) uint64 {
 	return uint64(i)
 }
 
+// ReadFrom reads a varint from r, consuming only the bytes that make up the
+// encoded integer.
+func ReadFrom(r io.Reader) (uint64, error) {
+	prefix := make([]byte, 1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return 0, err
+	}
+
+	var size int
+	switch prefix[0] {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix[0]), nil
+	}
+
+	b := make([]byte, 8)
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b), nil
+}
+
 func Encode(i uint64) ([]byte, error) {
 	if i < 0xfd {
 		return []byte{byte(int(i))}, nil